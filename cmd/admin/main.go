@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"server-go/common"
 	"server-go/database"
 	"server-go/database/schemas"
+	"server-go/modules"
+	"server-go/modules/github"
+	"server-go/routes"
 	"strconv"
 
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/api/cmdroute"
 	"github.com/uptrace/bun"
 )
 
@@ -17,11 +24,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	common.InitCache()
 	database.InitDB()
 	if err := database.UpdateDB(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	if err := modules.SyncRelinkedGithubSponsors(database.RelinkedGithubAccounts); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
 	switch os.Args[1] {
 	case "backfill-reputation":
@@ -34,6 +46,40 @@ func main() {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+	case "reweight-reputation":
+		batchSize, err := parseBatchSize(os.Args[2:])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := reweightVotes(batchSize); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := backfillReputation(batchSize); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "check-vote-consistency":
+		args := os.Args[2:]
+		repair := len(args) > 0 && args[0] == "--repair"
+		if repair {
+			args = args[1:]
+		}
+		batchSize, err := parseBatchSize(args)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := checkVoteConsistency(batchSize, repair); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "reconcile-github-sponsors":
+		if err := reconcileGithubSponsors(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	case "add-manual-opt-out":
 		if len(os.Args) < 3 {
 			fmt.Println("missing discord id")
@@ -43,7 +89,7 @@ func main() {
 		if len(os.Args) > 3 {
 			reason = os.Args[3]
 		}
-		if err := addManualOptOut(os.Args[2], reason); err != nil {
+		if err := modules.AddManualOptOut(os.Args[2], reason); err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
@@ -52,10 +98,17 @@ func main() {
 			fmt.Println("missing discord id")
 			os.Exit(1)
 		}
-		if err := removeManualOptOut(os.Args[2]); err != nil {
+		if err := modules.DeleteManualOptOut(os.Args[2]); err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+	case "register-commands":
+		client := api.NewClient("Bot " + common.Config.BotToken)
+		if err := cmdroute.OverwriteCommands(client, routes.Commands); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("registered %d commands\n", len(routes.Commands))
 	default:
 		printUsage()
 		os.Exit(1)
@@ -67,8 +120,12 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  backfill-reputation [batch-size]  Recalculate users.reputation from review_votes")
+	fmt.Println("  reweight-reputation [batch-size]  Recalculate vote weights, then backfill reputation")
+	fmt.Println("  check-vote-consistency [--repair] [batch-size]  Report (and fix) scores and reputation that drifted from review_votes")
+	fmt.Println("  reconcile-github-sponsors  Sync donor flags with the active github sponsors")
 	fmt.Println("  add-manual-opt-out <discord-id> [reason]")
 	fmt.Println("  remove-manual-opt-out <discord-id>")
+	fmt.Println("  register-commands  Overwrite the slash commands of the bot with the ones /interactions serves")
 }
 
 func parseBatchSize(args []string) (int, error) {
@@ -83,6 +140,30 @@ func parseBatchSize(args []string) (int, error) {
 	return batchSize, nil
 }
 
+// expectedReputationSQL sums the weighted votes on the active reviews of the users in ?, this is the source of
+// truth users.reputation is kept in sync with
+const expectedReputationSQL = `
+	SELECT
+		u2.id,
+		COALESCE(SUM(CASE WHEN rv.id IS NULL THEN 0 WHEN rv.is_upvote THEN rv.weight ELSE -rv.weight END), 0) AS reputation
+	FROM users AS u2
+	LEFT JOIN reviews AS r ON r.reviewer_id = u2.id AND r.deleted_at IS NULL
+	LEFT JOIN review_votes AS rv ON rv.review_id = r.id
+	WHERE u2.id IN (?)
+	GROUP BY u2.id
+`
+
+// expectedScoreSQL counts the votes on the reviews in ?, every vote changes reviews.score by one
+const expectedScoreSQL = `
+	SELECT
+		r2.id,
+		COALESCE(SUM(CASE WHEN rv.id IS NULL THEN 0 WHEN rv.is_upvote THEN 1 ELSE -1 END), 0) AS score
+	FROM reviews AS r2
+	LEFT JOIN review_votes AS rv ON rv.review_id = r2.id
+	WHERE r2.id IN (?)
+	GROUP BY r2.id
+`
+
 func backfillReputation(batchSize int) error {
 	var lastID int32
 	totalUpdated := 0
@@ -107,16 +188,7 @@ func backfillReputation(batchSize int) error {
 		res, err := database.DB.NewRaw(`
 			UPDATE users AS u
 			SET reputation = calculated.reputation
-			FROM (
-				SELECT
-					u2.id,
-					COALESCE(SUM(CASE WHEN rv.id IS NULL THEN 0 WHEN rv.is_upvote THEN 1 ELSE -1 END), 0) AS reputation
-				FROM users AS u2
-				LEFT JOIN reviews AS r ON r.reviewer_id = u2.id
-				LEFT JOIN review_votes AS rv ON rv.review_id = r.id
-				WHERE u2.id IN (?)
-				GROUP BY u2.id
-			) AS calculated
+			FROM (`+expectedReputationSQL+`) AS calculated
 			WHERE u.id = calculated.id
 		`, bun.In(userIDs)).Exec(context.Background())
 		if err != nil {
@@ -130,22 +202,233 @@ func backfillReputation(batchSize int) error {
 	}
 }
 
-func addManualOptOut(discordID string, reason string) error {
-	_, err := database.DB.NewInsert().
-		Model(&schemas.ManualOptOut{
-			DiscordID: discordID,
-			Reason:    reason,
-		}).
-		On("CONFLICT (discord_id) DO UPDATE").
-		Set("reason = EXCLUDED.reason").
-		Exec(context.Background())
-	return err
+type scoreDrift struct {
+	ID       int32 `bun:"id"`
+	Actual   int   `bun:"actual"`
+	Expected int   `bun:"expected"`
+}
+
+// checkVoteConsistency compares reviews.score and users.reputation with review_votes and optionally repairs the rows
+// that drifted
+func checkVoteConsistency(batchSize int, repair bool) error {
+	reviewDrift, err := checkReviewScores(batchSize, repair)
+	if err != nil {
+		return err
+	}
+
+	userDrift, err := checkUserReputation(batchSize, repair)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("done, %d reviews and %d users out of sync", reviewDrift, userDrift)
+	if repair {
+		fmt.Print(", repaired")
+	}
+	fmt.Println()
+	return nil
+}
+
+func checkReviewScores(batchSize int, repair bool) (int, error) {
+	var lastID int32
+	totalDrift := 0
+
+	for {
+		var reviewIDs []int32
+		err := database.DB.NewSelect().
+			Model((*schemas.UserReview)(nil)).
+			Column("id").
+			WhereAllWithDeleted().
+			Where("id > ?", lastID).
+			OrderExpr("id ASC").
+			Limit(batchSize).
+			Scan(context.Background(), &reviewIDs)
+		if err != nil {
+			return 0, err
+		}
+		if len(reviewIDs) == 0 {
+			return totalDrift, nil
+		}
+
+		var drift []scoreDrift
+		err = database.DB.NewRaw(`
+			SELECT r.id, r.score AS actual, calculated.score AS expected
+			FROM reviews AS r
+			JOIN (`+expectedScoreSQL+`) AS calculated ON calculated.id = r.id
+			WHERE r.score <> calculated.score
+			ORDER BY r.id
+		`, bun.In(reviewIDs)).Scan(context.Background(), &drift)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, d := range drift {
+			fmt.Printf("review %d: score %d, expected %d\n", d.ID, d.Actual, d.Expected)
+			if !repair {
+				continue
+			}
+
+			_, err = database.DB.NewRaw(`
+				UPDATE reviews AS r
+				SET score = calculated.score
+				FROM (`+expectedScoreSQL+`) AS calculated
+				WHERE r.id = calculated.id
+			`, bun.In([]int32{d.ID})).Exec(context.Background())
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		totalDrift += len(drift)
+		lastID = reviewIDs[len(reviewIDs)-1]
+	}
+}
+
+func checkUserReputation(batchSize int, repair bool) (int, error) {
+	var lastID int32
+	totalDrift := 0
+
+	for {
+		var userIDs []int32
+		err := database.DB.NewSelect().
+			Model((*schemas.URUser)(nil)).
+			Column("id").
+			Where("id > ?", lastID).
+			OrderExpr("id ASC").
+			Limit(batchSize).
+			Scan(context.Background(), &userIDs)
+		if err != nil {
+			return 0, err
+		}
+		if len(userIDs) == 0 {
+			return totalDrift, nil
+		}
+
+		var drift []scoreDrift
+		err = database.DB.NewRaw(`
+			SELECT u.id, u.reputation AS actual, calculated.reputation AS expected
+			FROM users AS u
+			JOIN (`+expectedReputationSQL+`) AS calculated ON calculated.id = u.id
+			WHERE u.reputation <> calculated.reputation
+			ORDER BY u.id
+		`, bun.In(userIDs)).Scan(context.Background(), &drift)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, d := range drift {
+			fmt.Printf("user %d: reputation %d, expected %d\n", d.ID, d.Actual, d.Expected)
+			if !repair {
+				continue
+			}
+
+			// recompute inside the update so votes cast since the check are not lost
+			_, err = database.DB.NewRaw(`
+				UPDATE users AS u
+				SET reputation = calculated.reputation
+				FROM (`+expectedReputationSQL+`) AS calculated
+				WHERE u.id = calculated.id
+			`, bun.In([]int32{d.ID})).Exec(context.Background())
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		totalDrift += len(drift)
+		lastID = userIDs[len(userIDs)-1]
+	}
+}
+
+// reweightVotes recalculates the weight of every vote from the current state of the voter and review author
+func reweightVotes(batchSize int) error {
+	var lastID int32
+	totalUpdated := 0
+
+	for {
+		var votes []struct {
+			ID       int32 `bun:"id"`
+			Weight   int   `bun:"weight"`
+			VoterID  int32 `bun:"voter_id"`
+			AuthorID int32 `bun:"author_id"`
+		}
+		err := database.DB.NewSelect().
+			TableExpr("review_votes AS rv").
+			ColumnExpr("rv.id, rv.weight, rv.voter_id, r.reviewer_id AS author_id").
+			Join("JOIN reviews AS r ON r.id = rv.review_id").
+			Where("rv.id > ?", lastID).
+			OrderExpr("rv.id ASC").
+			Limit(batchSize).
+			Scan(context.Background(), &votes)
+		if err != nil {
+			return err
+		}
+		if len(votes) == 0 {
+			fmt.Printf("done, reweighted %d votes\n", totalUpdated)
+			return nil
+		}
+
+		userIDs := []int32{}
+		for _, vote := range votes {
+			userIDs = append(userIDs, vote.VoterID, vote.AuthorID)
+		}
+
+		var users []schemas.URUser
+		err = database.DB.NewSelect().
+			Model(&users).
+			Column("id", "discord_id", "ip_hash", "reputation").
+			Where("id IN (?)", bun.In(userIDs)).
+			Scan(context.Background())
+		if err != nil {
+			return err
+		}
+
+		usersByID := map[int32]*schemas.URUser{}
+		for i := range users {
+			usersByID[users[i].ID] = &users[i]
+		}
+
+		for _, vote := range votes {
+			voter, author := usersByID[vote.VoterID], usersByID[vote.AuthorID]
+			if voter == nil || author == nil {
+				continue
+			}
+
+			weight := modules.VoteWeight(voter, author)
+			if weight == vote.Weight {
+				continue
+			}
+
+			_, err = database.DB.NewUpdate().
+				Model((*schemas.ReviewVote)(nil)).
+				Set("weight = ?", weight).
+				Where("id = ?", vote.ID).
+				Exec(context.Background())
+			if err != nil {
+				return err
+			}
+			totalUpdated++
+		}
+
+		lastID = votes[len(votes)-1].ID
+		fmt.Printf("reweighted %d votes, last id %d\n", totalUpdated, lastID)
+	}
 }
 
-func removeManualOptOut(discordID string) error {
-	_, err := database.DB.NewDelete().
-		Model((*schemas.ManualOptOut)(nil)).
-		Where("discord_id = ?", discordID).
-		Exec(context.Background())
-	return err
+func reconcileGithubSponsors() error {
+	if common.Config.GithubSponsorsToken == "" {
+		return errors.New("github_sponsors_token is not set")
+	}
+
+	sponsorships, err := github.GetActiveSponsorships(common.Config.GithubSponsorsToken)
+	if err != nil {
+		return err
+	}
+
+	activated, deactivated, err := modules.ReconcileGithubSponsors(sponsorships)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("done, %d active sponsors, %d sponsorships ended\n", activated, deactivated)
+	return nil
 }
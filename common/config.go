@@ -21,11 +21,14 @@ type Client struct {
 type ConfigStr struct {
 	DB                     *ConfigDB `json:"db"`
 	GithubWebhookSecret    string    `json:"github_webhook_secret"`
+	GithubSponsorsToken    string    `json:"github_sponsors_token"` // token of the sponsored account, used to reconcile sponsors
 	Origin                 string    `json:"origin"`
 	Port                   string    `json:"port"`
 	BotToken               string    `json:"bot_token"`
+	DiscordPublicKey       string    `json:"discord_public_key"` // public key of the application, verifies /interactions
 	ReportWebhook          string    `json:"report_webhook"`
 	JunkReportWebhook      string    `json:"junk_report_webhook"`
+	TwitterReportWebhook   string    `json:"twitter_report_webhook"` // falls back to report_webhook
 	AppealWebhook          string    `json:"appeal_webhook"`
 	AdminToken             string    `json:"admin_token"`
 	BotIntegrationToken    string    `json:"bot_integration_token"`
@@ -41,6 +44,11 @@ type ConfigStr struct {
 	ProfaneWordList        []string  `json:"profane_word_list"`
 	LightProfaneWordList   []string  `json:"light_profane_word_list"`
 	BanWordList            []string  `json:"ban_word_list"`
+	ReviewRetentionDays    int       `json:"review_retention_days"`
+	EventsBackend          string    `json:"events_backend"`       // "postgres" shares review events between instances
+	TranslationProvider    string    `json:"translation_provider"` // "google" (default), "libretranslate" or "none"
+	LibreTranslateURL      string    `json:"libretranslate_url"`
+	LibreTranslateAPIKey   string    `json:"libretranslate_api_key"`
 }
 
 var LightProfanityDetector *goaway.ProfanityDetector
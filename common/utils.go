@@ -10,11 +10,14 @@ import (
 	"strconv"
 )
 
-var PublicKeyString string = "a6953fb61ec1e9107fae66ff1c56437c322f561e2f1578b0f52dbcb3d9eda694"
-
+// VerifySignature checks the signature discord sends interactions with against discord_public_key, nothing is
+// valid when it isn't configured
 func VerifySignature(signatureString string, message []byte) bool {
 	signature, _ := hex.DecodeString(signatureString)
-	publicKey, _ := hex.DecodeString(PublicKeyString)
+	publicKey, err := hex.DecodeString(Config.DiscordPublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
 	return ed25519.Verify(publicKey, message, signature)
 }
 
@@ -81,7 +84,7 @@ func GetIntQueryOrDefault(r *http.Request, key string, defaultValue int) int {
 	}
 	intValue, err := strconv.Atoi(value)
 	if err != nil {
-		return intValue
+		return defaultValue
 	}
-	return defaultValue
+	return intValue
 }
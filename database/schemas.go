@@ -23,12 +23,19 @@ func CreateReviewDBSchemas() error {
 		(*schemas.ActionLog)(nil),
 		(*schemas.ReviewDBAppeal)(nil),
 		(*schemas.UserReview)(nil),
-		(*schemas.UserBadge)(nil),
+		(*schemas.BadgeDefinition)(nil),
+		(*schemas.BadgeAssignment)(nil),
 		(*schemas.ReviewDBBanLog)(nil),
 		(*schemas.Notification)(nil),
 		(*schemas.Oauth2Token)(nil),
+		(*schemas.GithubSponsor)(nil),
 		(*schemas.ReviewVote)(nil),
 		(*schemas.ManualOptOut)(nil),
+		(*schemas.ReviewRevision)(nil),
+		(*schemas.ReputationEvent)(nil),
+		(*schemas.UserWebhook)(nil),
+		(*schemas.WebhookDelivery)(nil),
+		(*schemas.WebhookJob)(nil),
 	}
 
 	for _, model := range models {
@@ -43,7 +50,7 @@ func CreateReviewDBSchemas() error {
 func CreateStupidityDBSchemas() error {
 	models := []any{
 		(*schemas.StupitStat)(nil),
-		(*schemas.UserInfo)(nil),
+		(*schemas.RatingOptIn)(nil),
 	}
 
 	for _, model := range models {
@@ -56,12 +63,9 @@ func CreateStupidityDBSchemas() error {
 
 func CreateTwitterReviewDBSchemas() error {
 	models := []any{
-		(*schemas.TwitterUser)(nil),
-		(*schemas.TwitterUserReview)(nil),
 		(*schemas.TwitterUserBadge)(nil),
-		(*schemas.ReviewDBTwitterBanLog)(nil),
+		(*schemas.TwitterReviewReport)(nil),
 	}
-	// soon
 
 	for _, model := range models {
 		if _, err := DB.NewCreateTable().IfNotExists().Model(model).Exec(context.Background()); err != nil {
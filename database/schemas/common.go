@@ -14,10 +14,25 @@ type Oauth2Token struct {
 	AccessToken  string    `bun:"access_token" json:"accessToken"`
 	RefreshToken string    `bun:"refresh_token" json:"refreshToken"`
 	Expiry       time.Time `bun:"expiry" json:"expiry"`
-	Provider     string    `bun:"provider" json:"provider"`
+	Provider     string    `bun:"provider,unique:oauth2_tokens_provider_account_unique" json:"provider"`
 
 	// this is probably not right place to put this but its better than creating another table
 	Username   string `bun:"username" json:"username"`
 	Avatar     string `bun:"avatar" json:"avatar"`
-	ProviderId string `bun:"provider_id" json:"providerId"`
+	ProviderId string `bun:"provider_id,unique:oauth2_tokens_provider_account_unique" json:"providerId"`
+
+	Public    bool      `bun:"public,notnull" json:"public"` // shown on the user's profile
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+type GithubSponsor struct {
+	bun.BaseModel `bun:"table:github_sponsors"`
+
+	NodeID            string    `bun:"node_id,pk" json:"nodeID"`
+	Login             string    `bun:"login" json:"login"`
+	TierName          string    `bun:"tier_name" json:"tierName"`
+	MonthlyPriceCents int       `bun:"monthly_price_cents" json:"monthlyPriceCents"`
+	Active            bool      `bun:"active,notnull" json:"active"`
+	DonorUserID       int32     `bun:"donor_user_id,nullzero" json:"donorUserID,omitempty"` // user whose donor flag was granted because of this sponsorship
+	UpdatedAt         time.Time `bun:"updated_at,nullzero,notnull,default:current_timestamp" json:"updatedAt"`
 }
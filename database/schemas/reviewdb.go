@@ -1,6 +1,7 @@
 package schemas
 
 import (
+	"slices"
 	"time"
 
 	"github.com/uptrace/bun"
@@ -13,13 +14,22 @@ const (
 	UserTypeModerator = 2
 )
 
+// identity providers a ReviewDB account can belong to. For accounts that aren't on discord the discord_id
+// column holds the account id on their platform.
+const (
+	PlatformDiscord = "discord"
+	PlatformTwitter = "twitter"
+)
+
 type URUser struct {
 	bun.BaseModel `bun:"table:users"`
 
 	ID                int32         `bun:"id,pk,autoincrement" json:"ID"`
 	DiscordID         string        `bun:"discord_id,type:numeric" json:"discordID"`
+	Platform          string        `bun:"platform,notnull,default:'discord'" json:"platform"`
 	Token             string        `bun:"token" json:"-"`
 	Username          string        `bun:"username" json:"username"`
+	DisplayName       string        `bun:"display_name,nullzero" json:"displayName,omitempty"`
 	Type              int32         `bun:"column:type" json:"-"`
 	AvatarURL         string        `bun:"avatar_url" json:"profilePhoto"`
 	ClientMods        []string      `bun:"client_mods,array" json:"clientMods"`
@@ -36,6 +46,10 @@ type URUser struct {
 	LastOnline        time.Time     `bun:"last_online" json:"-"`
 	Reputation        int           `bun:"reputation,default:0" json:"reputation"`
 
+	// restrictions the user has set on who can review their profile
+	ReviewMinAccountAgeDays int  `bun:"review_min_account_age_days,default:0" json:"-"`
+	ReviewMinReputation     *int `bun:"review_min_reputation" json:"-"`
+
 	BanID int32 `bun:"ban_id" json:"-"`
 
 	BanInfo *ReviewDBBanLog `bun:"rel:has-one,join:ban_id=id" json:"banInfo"`
@@ -46,6 +60,7 @@ type ReviewDBUserFull struct {
 
 	ID                int32         `bun:"id,pk,autoincrement" json:"id"`
 	DiscordID         string        `bun:"discord_id,type:numeric" json:"discord_id"`
+	Platform          string        `bun:"platform,notnull,default:'discord'" json:"platform"`
 	Token             string        `bun:"token" json:"-"`
 	Username          string        `bun:"username" json:"username"`
 	Type              int32         `bun:"column:type" json:"type"`
@@ -113,6 +128,7 @@ type ReviewDBBanLog struct {
 	ReviewID        int32     `bun:"review_id" json:"reviewID"`
 	ReviewContent   string    `bun:"review_content" json:"reviewContent"`
 	AdminDiscordID  *string   `bun:"admin_discord_id,type:numeric" json:"-"`
+	Reason          string    `bun:"reason,nullzero" json:"reason,omitempty"`
 	BanEndDate      time.Time `bun:"ban_end_date" json:"banEndDate"`
 	Timestamp       time.Time `bun:"timestamp,default:current_timestamp" json:"-"`
 	ReviewTimestamp time.Time `bun:"review_timestamp" json:"reviewTimestamp"`
@@ -186,11 +202,36 @@ type UserReview struct {
 	RepliesTo    int32     `bun:"replies_to,nullzero" json:"-"`
 	Score        int       `bun:"score,default:0" json:"score"`
 	Reputation   *int      `bun:"-" json:"reputation,omitempty"`
+	EditedAtStr  time.Time `bun:"edited_at,nullzero" json:"-"`
+	EditedAt     int64     `bun:"-" json:"editedAt,omitempty"`
+	Edited       bool      `bun:"-" json:"edited"`
+	DeletedAt    time.Time `bun:"deleted_at,soft_delete,nullzero" json:"-"`
+	DeletedBy    int32     `bun:"deleted_by,nullzero" json:"-"`
+	Pinned       bool      `bun:"pinned,default:false" json:"pinned"`
+	Hidden       bool      `bun:"hidden,default:false" json:"hidden"`
+	HiddenReason string    `bun:"hidden_reason,nullzero" json:"hiddenReason,omitempty"`
+	Platform     string    `bun:"platform,notnull,default:'discord'" json:"-"` // platform of the reviewed profile, always the reviewer's platform
+	// english translation of the comment, saved when a moderator needs one. TranslatedFrom is set once the
+	// comment was translated, TranslatedComment stays empty when it already is english.
+	TranslatedComment string `bun:"translated_comment,nullzero" json:"translatedComment,omitempty"`
+	TranslatedFrom    string `bun:"translated_from,nullzero" json:"translatedFrom,omitempty"`
 
 	User    *URUser      `bun:"rel:belongs-to,join:reviewer_id=id" json:"-"`
 	Replies []UserReview `bun:"-" json:"replies"`
 }
 
+// ReviewRevision is a previous version of a review's comment, saved whenever the reviewer edits it
+type ReviewRevision struct {
+	bun.BaseModel `bun:"table:review_revisions"`
+
+	ID           int32     `bun:"id,pk,autoincrement" json:"id"`
+	ReviewID     int32     `bun:"review_id" json:"reviewID"`
+	Comment      string    `bun:"comment" json:"comment"`
+	TimestampStr time.Time `bun:"timestamp" json:"-"`
+	Timestamp    int64     `bun:"-" json:"timestamp"`
+	ReplacedAt   time.Time `bun:"replaced_at,default:current_timestamp" json:"replacedAt"`
+}
+
 type ReviewVote struct {
 	bun.BaseModel `bun:"table:review_votes"`
 
@@ -198,6 +239,19 @@ type ReviewVote struct {
 	ReviewID int32 `bun:"review_id,unique:vote_unique"`
 	VoterID  int32 `bun:"voter_id,unique:vote_unique"`
 	IsUpvote bool  `bun:"is_upvote"`
+	Weight   int   `bun:"weight,notnull"` // how much reputation the vote is worth to the review author, see modules.VoteWeight
+}
+
+// ReputationEvent records a change of a user's reputation caused by a vote on one of their reviews
+type ReputationEvent struct {
+	bun.BaseModel `bun:"table:reputation_events"`
+
+	ID        int32     `bun:"id,pk,autoincrement"`
+	UserID    int32     `bun:"user_id"`
+	VoterID   int32     `bun:"voter_id"`
+	ReviewID  int32     `bun:"review_id"`
+	Delta     int       `bun:"delta"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
 }
 
 type UserReviewBasic struct {
@@ -210,8 +264,14 @@ type UserReviewBasic struct {
 	TimestampStr time.Time `bun:"timestamp,default:current_timestamp" json:"-"`
 	Timestamp    int64     `bun:"-" json:"timestamp"`
 	ReviewerID   int32     `bun:"reviewer_id" json:"reviewer_id"`
+	DeletedAt    time.Time `bun:"deleted_at,soft_delete,nullzero" json:"-"`
+	// saved by modules.TranslateReview when the review is reported
+	TranslatedComment string `bun:"translated_comment,nullzero" json:"translatedComment,omitempty"`
+	TranslatedFrom    string `bun:"translated_from,nullzero" json:"translatedFrom,omitempty"`
 }
 
+// UserBadge is a badge as shown to clients. The user_badges table is only kept around for the migration to
+// badge_definitions and badge_assignments.
 type UserBadge struct {
 	bun.BaseModel `bun:"table:user_badges"`
 
@@ -224,10 +284,122 @@ type UserBadge struct {
 	Description     string `bun:"description" json:"description"`
 }
 
+type BadgeDefinition struct {
+	bun.BaseModel `bun:"table:badge_definitions"`
+
+	ID          int32     `bun:"id,pk,autoincrement" json:"id"`
+	SystemKey   string    `bun:"system_key,nullzero,unique" json:"systemKey,omitempty"` // set for badges derived from user type and flags
+	Name        string    `bun:"name,notnull" json:"name"`
+	Icon        string    `bun:"icon_url" json:"icon"`
+	RedirectURL string    `bun:"redirect_url" json:"redirectURL"`
+	Type        int32     `bun:"type,notnull" json:"type"`
+	Description string    `bun:"description" json:"description"`
+	Priority    int       `bun:"priority,notnull" json:"priority"` // higher priority badges are shown first
+	Hidden      bool      `bun:"hidden,notnull" json:"hidden"`
+	CreatedAt   time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (badge *BadgeDefinition) ToUserBadge(targetDiscordID string) UserBadge {
+	return UserBadge{
+		TargetDiscordID: targetDiscordID,
+		Name:            badge.Name,
+		Icon:            badge.Icon,
+		RedirectURL:     badge.RedirectURL,
+		Type:            badge.Type,
+		Description:     badge.Description,
+	}
+}
+
+type BadgeAssignment struct {
+	bun.BaseModel `bun:"table:badge_assignments"`
+
+	ID              int32      `bun:"id,pk,autoincrement" json:"id"`
+	BadgeID         int32      `bun:"badge_id,notnull,unique:badge_assignment_unique" json:"badgeID"`
+	TargetDiscordID string     `bun:"target_discord_id,type:numeric,notnull,unique:badge_assignment_unique" json:"discordID"`
+	ExpiresAt       *time.Time `bun:"expires_at" json:"expiresAt"` // nil for badges that never expire
+	CreatedAt       time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// UserWebhook is an url events of a user's profile are posted to
+type UserWebhook struct {
+	bun.BaseModel `bun:"table:user_webhooks"`
+
+	ID        int32     `bun:"id,pk,autoincrement" json:"id"`
+	UserID    int32     `bun:"user_id,notnull" json:"-"`
+	URL       string    `bun:"url,notnull" json:"url"`
+	Secret    string    `bun:"secret,notnull" json:"-"`
+	Events    []string  `bun:"events,array" json:"events"` // every event when empty
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+type WebhookDelivery struct {
+	bun.BaseModel `bun:"table:webhook_deliveries"`
+
+	ID             int64      `bun:"id,pk,autoincrement" json:"id"`
+	WebhookID      int32      `bun:"webhook_id,notnull" json:"webhookId"`
+	Event          string     `bun:"event,notnull" json:"event"`
+	Payload        string     `bun:"payload,notnull" json:"payload"` // the body that is signed and sent
+	Status         string     `bun:"status,notnull,default:'pending'" json:"status"`
+	Attempts       int        `bun:"attempts,notnull,default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `bun:"next_attempt_at,nullzero,notnull,default:current_timestamp" json:"nextAttemptAt"`
+	LastStatusCode int        `bun:"last_status_code,nullzero" json:"lastStatusCode,omitempty"`
+	LastError      string     `bun:"last_error,nullzero" json:"lastError,omitempty"`
+	CreatedAt      time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	DeliveredAt    *time.Time `bun:"delivered_at" json:"deliveredAt"`
+}
+
+const (
+	WebhookJobPending = "pending"
+	WebhookJobSent    = "sent"
+	WebhookJobDead    = "dead" // gave up, an admin can retry it
+)
+
+// WebhookJob is a discord webhook waiting in the outbox to be sent to a staff channel
+type WebhookJob struct {
+	bun.BaseModel `bun:"table:webhook_jobs"`
+
+	ID            int64      `bun:"id,pk,autoincrement" json:"id"`
+	Kind          string     `bun:"kind,notnull" json:"kind"`
+	Target        string     `bun:"target,nullzero" json:"target"`
+	Payload       string     `bun:"payload,notnull" json:"payload"`
+	Status        string     `bun:"status,notnull,default:'pending'" json:"status"`
+	Attempts      int        `bun:"attempts,notnull,default:0" json:"attempts"`
+	NextAttemptAt time.Time  `bun:"next_attempt_at,nullzero,notnull,default:current_timestamp" json:"nextAttemptAt"`
+	LastError     string     `bun:"last_error,nullzero" json:"lastError,omitempty"`
+	CreatedAt     time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	SentAt        *time.Time `bun:"sent_at" json:"sentAt"`
+}
+
 func (user *URUser) IsAdmin() bool {
 	return user.Type == 1
 }
 
+// Capability is a moderation action that needs a staff account
+type Capability int
+
+const (
+	CapabilityModerateReviews Capability = iota // delete and restore any review
+	CapabilityBanUsers                          // ban, unban and warn users
+	CapabilityHandleAppeals
+	CapabilityManageBadges
+	CapabilityManageOptOuts
+)
+
+var staffCapabilities = map[int32][]Capability{
+	UserTypeAdmin:     {CapabilityModerateReviews, CapabilityBanUsers, CapabilityHandleAppeals, CapabilityManageBadges, CapabilityManageOptOuts},
+	UserTypeModerator: {CapabilityModerateReviews, CapabilityBanUsers, CapabilityHandleAppeals},
+}
+
+func (user *URUser) Can(capability Capability) bool {
+	return slices.Contains(staffCapabilities[user.Type], capability)
+}
+
 func (user *URUser) IsBanned() bool {
 	if user.Type == -1 {
 		return true
@@ -1,22 +1,27 @@
 package schemas
 
 import (
+	"time"
+
 	"github.com/uptrace/bun"
 )
 
+// StupitStat is one vote on a metric of a profile, every user has at most one vote per profile and metric
 type StupitStat struct {
 	bun.BaseModel `bun:"table:stupidity_reviews"`
 
 	ID                int32  `bun:"id,pk,autoincrement"`
-	ReviewedDiscordID int64  `bun:"reviewed_discord_id,type:numeric"`
+	ReviewedDiscordID int64  `bun:"reviewed_discord_id,type:numeric,unique:stupidity_vote_unique"`
 	StupidityValue    int32  `bun:"stupidity_value"`
-	ReviewerDiscordID string `bun:"reviewer_discord_id,type:numeric"`
+	ReviewerDiscordID string `bun:"reviewer_discord_id,type:numeric,unique:stupidity_vote_unique"`
+	Metric            string `bun:"metric,notnull,default:'stupidity',unique:stupidity_vote_unique"`
 }
 
-type UserInfo struct {
-	bun.BaseModel `bun:"table:stupidity_users"`
+// RatingOptIn records that a user allows being rated on an opt-in metric
+type RatingOptIn struct {
+	bun.BaseModel `bun:"table:rating_opt_ins"`
 
-	ID        int32  `bun:"id,pk,autoincrement"`
-	DiscordID string `bun:"discord_id,type:numeric"`
-	Token     string `bun:"token"`
+	DiscordID string    `bun:"discord_id,pk,type:numeric" json:"-"`
+	Metric    string    `bun:"metric,pk" json:"metric"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"createdAt"`
 }
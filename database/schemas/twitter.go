@@ -6,65 +6,29 @@ import (
 	"github.com/uptrace/bun"
 )
 
-type TwitterUser struct {
-	bun.BaseModel `bun:"table:reviewdb_twitter.users"`
-
-	ID           int32       `bun:"id,pk,autoincrement" json:"id"`
-	TwitterID    string      `bun:"twitter_id,type:numeric" json:"twitterId"`
-	Token        string      `bun:"token" json:"-"`
-	Username     string      `bun:"username" json:"username"`
-	DisplayName  string      `bun:"display_name" json:"displayName"`
-	Type         int32       `bun:"column:type" json:"-"`
-	AvatarURL    string      `bun:"avatar_url" json:"avatarURL"`
-	WarningCount int32       `bun:"warning_count" json:"warningCount"`
-	Badges       []UserBadge `bun:"-" json:"badges"`
-	OptedOut     bool        `bun:"opted_out" json:"-"`
-	IpHash       string      `bun:"ip_hash" json:"-"`
-	RefreshToken string      `bun:"refresh_token" json:"-"`
-	ExpiresAt    time.Time   `bun:"expires_at" json:"-"`
-
-	BanID int32 `bun:"ban_id" json:"-"`
-
-	BanInfo *ReviewDBBanLog `bun:"rel:has-one,join:ban_id=id" json:"banInfo"`
-}
-
-type ReviewDBTwitterBanLog struct {
-	bun.BaseModel `bun:"table:reviewdb_twitter.user_bans"`
-
-	ID              int32     `bun:"id,pk,autoincrement" json:"id"`
-	TwitterID       string    `bun:"twitter_id,type:numeric" json:"twitterId"`
-	ReviewID        int32     `bun:"review_id" json:"reviewId"`
-	ReviewContent   string    `bun:"review_content" json:"reviewContent"`
-	AdminDiscordID  *string   `bun:"admin_discord_id,type:numeric" json:"-"`
-	BanEndDate      time.Time `bun:"ban_end_date" json:"banEndDate"`
-	Timestamp       time.Time `bun:"timestamp,default:current_timestamp" json:"-"`
-	ReviewTimestamp time.Time `bun:"review_timestamp" json:"reviewTimestamp"`
-}
-
-type TwitterUserReview struct {
-	bun.BaseModel `bun:"table:reviewdb_twitter.reviews"`
-
-	ID           int32         `bun:"id,pk,autoincrement" json:"id"`
-	ProfileID    string        `bun:"profile_id,type:numeric" json:"-"`
-	Sender       TwitterSender `bun:"-" json:"sender"`
-	Comment      string        `bun:"comment" json:"comment"`
-	Type         int32         `bun:"type" json:"type"` // 0 = normal review , 1 = system review
-	TimestampStr time.Time     `bun:"timestamp,default:current_timestamp" json:"-"`
-	Timestamp    int64         `bun:"-" json:"timestamp"`
-
-	User       *TwitterUser `bun:"rel:belongs-to,join:reviewer_id=twitter_id" json:"-"`
-	ReviewerID string       `bun:"reviewer_id,type:numeric" json:"-"`
+// TwitterReview is a review with PlatformTwitter in the shape ReviewDB Twitter clients expect
+type TwitterReview struct {
+	ID        int32           `json:"id"`
+	Sender    TwitterSender   `json:"sender"`
+	Comment   string          `json:"comment"`
+	Type      int32           `json:"type"` // 0 = normal review , 1 = system review
+	Timestamp int64           `json:"timestamp"`
+	Score     int             `json:"score"`
+	Edited    bool            `json:"edited"`
+	EditedAt  int64           `json:"editedAt,omitempty"`
+	Replies   []TwitterReview `json:"replies"`
 }
 
 type TwitterSender struct {
-	ID          int32              `json:"id"`
-	TwitterID   string             `json:"twitterId"`
-	Username    string             `json:"username"`
-	DisplayName string             `json:"displayName"`
-	AvatarURL   string             `json:"avatarURL"`
-	Badges      []TwitterUserBadge `json:"badges"`
+	ID          int32       `json:"id"`
+	TwitterID   string      `json:"twitterId"`
+	Username    string      `json:"username"`
+	DisplayName string      `json:"displayName"`
+	AvatarURL   string      `json:"avatarURL"`
+	Badges      []UserBadge `json:"badges"`
 }
 
+// TwitterUserBadge is a badge given by hand to a twitter account, system badges come from the shared badge index
 type TwitterUserBadge struct {
 	bun.BaseModel `bun:"table:reviewdb_twitter.user_badges"`
 
@@ -77,24 +41,29 @@ type TwitterUserBadge struct {
 	Description     string `bun:"description" json:"description"`
 }
 
+func (b TwitterUserBadge) ToUserBadge() UserBadge {
+	return UserBadge{
+		TargetDiscordID: b.TargetTwitterID,
+		Name:            b.Name,
+		Icon:            b.Icon,
+		RedirectURL:     b.RedirectURL,
+		Type:            b.Type,
+		Description:     b.Description,
+	}
+}
+
+// TwitterReviewReport is a report of a review with PlatformTwitter, kept apart from ReviewDB reports
 type TwitterReviewReport struct {
-	bun.BaseModel `bun:"table:reports"`
+	bun.BaseModel `bun:"table:reviewdb_twitter.reports"`
 
 	ID         int32     `bun:"id,pk,autoincrement"`
-	ReviewID   int32     `bun:"review_id"`
-	ReporterID string    `bun:"reporter_id,type:numeric"`
+	ReviewID   int32     `bun:"review_id,notnull"`
+	ReporterID int32     `bun:"reporter_id,notnull"`
 	Timestamp  time.Time `bun:"timestamp,default:current_timestamp"`
 }
 
 type TwitterRequestData struct {
 	Comment   string `json:"comment"`
 	ProfileID string `json:"profileId"`
+	RepliesTo int32  `json:"repliesTo"`
 }
-
-func (t *TwitterUser) IsAdmin() bool {
-	return t.Type == 1
-}
-
-func (t *TwitterUser) IsBanned() bool {
-	return t.Type == -1 || t.BanInfo != nil
-}
\ No newline at end of file
@@ -1,6 +1,14 @@
 package database
 
-import "context"
+import (
+	"context"
+	"log"
+	"slices"
+)
+
+// RelinkedGithubAccounts lists the github accounts that UpdateDB took away from one user because a newer link to
+// another user exists, their sponsor donor flags have to be synced again with modules.SyncRelinkedGithubSponsors
+var RelinkedGithubAccounts []string
 
 func UpdateDB() error {
 	_, err := DB.NewRaw(
@@ -17,5 +25,571 @@ func UpdateDB() error {
 			created_at timestamptz NOT NULL DEFAULT now()
 		)
 	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`ALTER TABLE reviews ADD COLUMN IF NOT EXISTS edited_at timestamptz`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS review_revisions (
+			id serial PRIMARY KEY,
+			review_id integer NOT NULL,
+			comment text,
+			timestamp timestamptz,
+			replaced_at timestamptz NOT NULL DEFAULT now()
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS review_revisions_review_id_idx ON review_revisions (review_id)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		ALTER TABLE reviews
+			ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+			ADD COLUMN IF NOT EXISTS deleted_by integer
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS reviews_deleted_at_idx ON reviews (deleted_at) WHERE deleted_at IS NOT NULL`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		ALTER TABLE reviews
+			ADD COLUMN IF NOT EXISTS pinned boolean NOT NULL DEFAULT false,
+			ADD COLUMN IF NOT EXISTS hidden boolean NOT NULL DEFAULT false,
+			ADD COLUMN IF NOT EXISTS hidden_reason text
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS review_min_account_age_days integer NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS review_min_reputation integer
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`ALTER TABLE review_votes ADD COLUMN IF NOT EXISTS weight integer NOT NULL DEFAULT 1`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS reputation_events (
+			id serial PRIMARY KEY,
+			user_id integer NOT NULL,
+			voter_id integer NOT NULL,
+			review_id integer NOT NULL,
+			delta integer NOT NULL,
+			created_at timestamptz NOT NULL DEFAULT now()
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS reputation_events_user_id_created_at_idx ON reputation_events (user_id, created_at)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS reputation_events_created_at_idx ON reputation_events (created_at)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS badge_definitions (
+			id serial PRIMARY KEY,
+			system_key text UNIQUE,
+			name text NOT NULL,
+			icon_url text,
+			redirect_url text,
+			type integer NOT NULL DEFAULT 0,
+			description text,
+			priority integer NOT NULL DEFAULT 0,
+			hidden boolean NOT NULL DEFAULT false,
+			created_at timestamptz NOT NULL DEFAULT now()
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS badge_assignments (
+			id serial PRIMARY KEY,
+			badge_id integer NOT NULL REFERENCES badge_definitions (id) ON DELETE CASCADE,
+			target_discord_id numeric NOT NULL,
+			expires_at timestamptz,
+			created_at timestamptz NOT NULL DEFAULT now(),
+			CONSTRAINT badge_assignment_unique UNIQUE (badge_id, target_discord_id)
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS badge_assignments_target_discord_id_idx ON badge_assignments (target_discord_id)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// moderator and donor badges have no icon yet, they stay hidden until an admin gives them one
+	_, err = DB.NewRaw(`
+		INSERT INTO badge_definitions (system_key, name, icon_url, redirect_url, description, priority, hidden) VALUES
+			('admin', 'Admin', 'https://cdn.discordapp.com/emojis/1040004306100826122.gif?size=128', 'https://www.youtube.com/watch?v=dQw4w9WgXcQ', 'This user is an admin of ReviewDB.', 100, false),
+			('moderator', 'Moderator', '', '', 'This user is a moderator of ReviewDB.', 90, true),
+			('banned', 'Banned', 'https://cdn.discordapp.com/emojis/399233923898540053.gif?size=128', 'https://www.youtube.com/watch?v=dQw4w9WgXcQ', 'This user is banned from ReviewDB.', 80, false),
+			('donor', 'Donor', '', '', 'This user supports ReviewDB.', 10, true)
+		ON CONFLICT (system_key) DO NOTHING
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// every distinct badge in user_badges becomes one definition, its rows become assignments.
+	// Runs once, as soon as there is any non system definition the legacy table is ignored.
+	_, err = DB.NewRaw(`
+		DO $$
+		BEGIN
+			IF to_regclass('user_badges') IS NOT NULL
+				AND NOT EXISTS (SELECT 1 FROM badge_definitions WHERE system_key IS NULL) THEN
+
+				INSERT INTO badge_definitions (name, icon_url, redirect_url, type, description)
+				SELECT DISTINCT COALESCE(name, ''), icon_url, redirect_url, COALESCE(type, 0), description FROM user_badges;
+
+				INSERT INTO badge_assignments (badge_id, target_discord_id)
+				SELECT DISTINCT d.id, ub.target_discord_id
+				FROM user_badges AS ub
+				JOIN badge_definitions AS d ON d.system_key IS NULL
+					AND d.name = COALESCE(ub.name, '')
+					AND d.icon_url IS NOT DISTINCT FROM ub.icon_url
+					AND d.redirect_url IS NOT DISTINCT FROM ub.redirect_url
+					AND d.type = COALESCE(ub.type, 0)
+					AND d.description IS NOT DISTINCT FROM ub.description
+				WHERE ub.target_discord_id IS NOT NULL
+				ON CONFLICT DO NOTHING;
+			END IF;
+		END
+		$$
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS github_sponsors (
+			node_id text PRIMARY KEY,
+			login text,
+			tier_name text,
+			monthly_price_cents integer,
+			active boolean NOT NULL DEFAULT false,
+			donor_user_id integer,
+			updated_at timestamptz NOT NULL DEFAULT now()
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		ALTER TABLE oauth2_tokens
+			ADD COLUMN IF NOT EXISTS public boolean NOT NULL DEFAULT false,
+			ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now()
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// an account could be linked more than once before, only the most recent link is kept
+	var removedLinks []struct {
+		ID         int32  `bun:"id"`
+		UserID     int32  `bun:"user_id"`
+		Provider   string `bun:"provider"`
+		ProviderID string `bun:"provider_id"`
+		KeptUserID int32  `bun:"kept_user_id"`
+	}
+	err = DB.NewRaw(`
+		DELETE FROM oauth2_tokens AS t
+		USING oauth2_tokens AS newest
+		WHERE t.provider = newest.provider AND t.provider_id = newest.provider_id AND t.id < newest.id
+			AND newest.id = (SELECT max(id) FROM oauth2_tokens WHERE provider = t.provider AND provider_id = t.provider_id)
+		RETURNING t.id, t.user_id, t.provider, t.provider_id, newest.user_id AS kept_user_id
+	`).Scan(context.Background(), &removedLinks)
+	if err != nil {
+		return err
+	}
+	for _, link := range removedLinks {
+		log.Printf("removed duplicate %s link %d of account %s from user %d, it stays linked to user %d",
+			link.Provider, link.ID, link.ProviderID, link.UserID, link.KeptUserID)
+		if link.Provider == "github" && link.UserID != link.KeptUserID {
+			RelinkedGithubAccounts = append(RelinkedGithubAccounts, link.ProviderID)
+		}
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE UNIQUE INDEX IF NOT EXISTS oauth2_tokens_provider_account_unique ON oauth2_tokens (provider, provider_id)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS oauth2_tokens_user_id_idx ON oauth2_tokens (user_id)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS platform text NOT NULL DEFAULT 'discord',
+			ADD COLUMN IF NOT EXISTS display_name text
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`ALTER TABLE reviews ADD COLUMN IF NOT EXISTS platform text NOT NULL DEFAULT 'discord'`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS users_platform_discord_id_idx ON users (platform, discord_id)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// ReviewDB Twitter users and reviews move into the shared tables. Runs once, as soon as there is any
+	// twitter user the legacy tables are ignored.
+	_, err = DB.NewRaw(`
+		DO $$
+		BEGIN
+			IF to_regclass('reviewdb_twitter.users') IS NOT NULL
+				AND NOT EXISTS (SELECT 1 FROM users WHERE platform = 'twitter') THEN
+
+				INSERT INTO users (discord_id, platform, token, username, display_name, avatar_url, type, warning_count,
+					opted_out, ip_hash, refresh_token, access_token_expiry, ban_id, flags, client_mods)
+				SELECT twitter_id, 'twitter', token, username, display_name, avatar_url, COALESCE(type, 0),
+					COALESCE(warning_count, 0), COALESCE(opted_out, false), ip_hash, refresh_token, expires_at, ban_id,
+					0, ARRAY['reviewdb-twitter']
+				FROM reviewdb_twitter.users;
+
+				IF to_regclass('reviewdb_twitter.reviews') IS NOT NULL THEN
+					INSERT INTO reviews (profile_id, reviewer_id, comment, type, timestamp, platform)
+					SELECT tr.profile_id, u.id, tr.comment, COALESCE(tr.type, 0), tr.timestamp, 'twitter'
+					FROM reviewdb_twitter.reviews AS tr
+					JOIN users AS u ON u.platform = 'twitter' AND u.discord_id = tr.reviewer_id;
+				END IF;
+			END IF;
+		END
+		$$
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`CREATE SCHEMA IF NOT EXISTS reviewdb_twitter`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// an older reviewdb_twitter.reports keyed reporters by twitter account id, it is set aside and moved over below
+	_, err = DB.NewRaw(`
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = 'reviewdb_twitter'
+				AND table_name = 'reports' AND column_name = 'reporter_id' AND data_type <> 'integer') THEN
+
+				ALTER TABLE reviewdb_twitter.reports RENAME TO legacy_reports;
+				DROP INDEX IF EXISTS reviewdb_twitter.twitter_reports_reporter_id_idx;
+			END IF;
+		END
+		$$
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS reviewdb_twitter.reports (
+			id serial PRIMARY KEY,
+			review_id integer NOT NULL,
+			reporter_id integer NOT NULL,
+			timestamp timestamptz DEFAULT now()
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS twitter_reports_reporter_id_idx ON reviewdb_twitter.reports (reporter_id, timestamp)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err = moveLegacyTwitterReports(); err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`ALTER TABLE stupidity_reviews ADD COLUMN IF NOT EXISTS metric text NOT NULL DEFAULT 'stupidity'`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// concurrent votes could insert a user's vote twice before, only the most recent one is kept
+	_, err = DB.NewRaw(`
+		DELETE FROM stupidity_reviews AS s
+		USING stupidity_reviews AS newer
+		WHERE s.metric = newer.metric AND s.reviewed_discord_id = newer.reviewed_discord_id
+			AND s.reviewer_discord_id = newer.reviewer_discord_id AND s.id < newer.id
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE UNIQUE INDEX IF NOT EXISTS stupidity_vote_unique
+		ON stupidity_reviews (reviewed_discord_id, reviewer_discord_id, metric)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS rating_opt_ins (
+			discord_id numeric NOT NULL,
+			metric text NOT NULL,
+			created_at timestamptz NOT NULL DEFAULT now(),
+			PRIMARY KEY (discord_id, metric)
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// StupidityDB accounts become ReviewDB accounts. Users without one keep their hashed StupidityDB token, which
+	// token lookups still accept, users with one log in again and get their ReviewDB token.
+	_, err = DB.NewRaw(`
+		DO $$
+		BEGIN
+			IF to_regclass('stupidity_users') IS NOT NULL THEN
+				INSERT INTO users (discord_id, platform, token, username, avatar_url, type, warning_count, opted_out,
+					flags, client_mods)
+				SELECT DISTINCT ON (s.discord_id) s.discord_id, 'discord', s.token, s.discord_id::text, '', 0, 0, false,
+					0, ARRAY['stupiditydb']
+				FROM stupidity_users AS s
+				WHERE NOT EXISTS (SELECT 1 FROM users AS u WHERE u.platform = 'discord' AND u.discord_id = s.discord_id)
+				ORDER BY s.discord_id, s.id DESC;
+
+				UPDATE users SET client_mods = array_append(client_mods, 'stupiditydb')
+				WHERE platform = 'discord' AND NOT ('stupiditydb' = ANY(client_mods))
+					AND discord_id IN (SELECT discord_id FROM stupidity_users);
+
+				ALTER TABLE stupidity_users RENAME TO stupidity_users_migrated;
+			END IF;
+		END
+		$$
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS user_webhooks (
+			id serial PRIMARY KEY,
+			user_id integer NOT NULL,
+			url text NOT NULL,
+			secret text NOT NULL,
+			events text[],
+			created_at timestamptz NOT NULL DEFAULT now()
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id bigserial PRIMARY KEY,
+			webhook_id integer NOT NULL,
+			event text NOT NULL,
+			payload text NOT NULL,
+			status text NOT NULL DEFAULT 'pending',
+			attempts integer NOT NULL DEFAULT 0,
+			next_attempt_at timestamptz NOT NULL DEFAULT now(),
+			last_status_code integer,
+			last_error text,
+			created_at timestamptz NOT NULL DEFAULT now(),
+			delivered_at timestamptz
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS user_webhooks_user_id_idx ON user_webhooks (user_id)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id)`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// the worker only looks at pending deliveries
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending'`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		CREATE TABLE IF NOT EXISTS webhook_jobs (
+			id bigserial PRIMARY KEY,
+			kind text NOT NULL,
+			target text,
+			payload text NOT NULL,
+			status text NOT NULL DEFAULT 'pending',
+			attempts integer NOT NULL DEFAULT 0,
+			next_attempt_at timestamptz NOT NULL DEFAULT now(),
+			last_error text,
+			created_at timestamptz NOT NULL DEFAULT now(),
+			sent_at timestamptz
+		)
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(
+		`CREATE INDEX IF NOT EXISTS webhook_jobs_pending_idx ON webhook_jobs (next_attempt_at) WHERE status = 'pending'`,
+	).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`
+		ALTER TABLE reviews
+			ADD COLUMN IF NOT EXISTS translated_comment text,
+			ADD COLUMN IF NOT EXISTS translated_from text
+	`).Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NewRaw(`ALTER TABLE user_bans ADD COLUMN IF NOT EXISTS reason text`).Exec(context.Background())
 	return err
 }
+
+// moveLegacyTwitterReports moves reports made by ReviewDB Twitter before its users and reviews moved into the
+// shared tables. Those reports point at reviewdb_twitter.reviews and name the reporter by twitter account id, they
+// were kept in reviewdb_twitter.legacy_reports or mixed into the shared reports table. Rows whose review or reporter
+// can't be found are left where they are.
+func moveLegacyTwitterReports() error {
+	var hasLegacyReviews bool
+	err := DB.NewRaw(`SELECT to_regclass('reviewdb_twitter.reviews') IS NOT NULL`).Scan(context.Background(), &hasLegacyReviews)
+	if err != nil || !hasLegacyReviews {
+		return err
+	}
+
+	sources := []struct {
+		schema, table string
+		// reports of discord users are in the shared table too, their reporter_id is a user id
+		filter string
+	}{
+		{schema: "reviewdb_twitter", table: "legacy_reports"},
+		{schema: "public", table: "reports", filter: "AND NOT EXISTS (SELECT 1 FROM users WHERE id = old.reporter_id)"},
+	}
+
+	for _, source := range sources {
+		var columns []string
+		err = DB.NewRaw(
+			`SELECT column_name FROM information_schema.columns WHERE table_schema = ? AND table_name = ?`,
+			source.schema, source.table,
+		).Scan(context.Background(), &columns)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		timestamp := "now()"
+		if slices.Contains(columns, "timestamp") {
+			timestamp = "COALESCE(old.timestamp, now())"
+		}
+
+		res, err := DB.NewRaw(`
+			WITH migrated_reviews AS (
+				SELECT DISTINCT ON (tr.id) tr.id AS legacy_id, r.id AS review_id
+				FROM reviewdb_twitter.reviews AS tr
+				JOIN users AS u ON u.platform = 'twitter' AND u.discord_id = tr.reviewer_id
+				JOIN reviews AS r ON r.platform = 'twitter' AND r.reviewer_id = u.id
+					AND r.profile_id = tr.profile_id AND r.timestamp = tr.timestamp
+				ORDER BY tr.id, r.id
+			), moved AS (
+				DELETE FROM ` + source.schema + "." + source.table + ` AS old
+				USING migrated_reviews, users AS reporter
+				WHERE old.review_id = migrated_reviews.legacy_id
+					AND reporter.platform = 'twitter' AND reporter.discord_id = old.reporter_id ` + source.filter + `
+				RETURNING migrated_reviews.review_id, reporter.id AS reporter_id, ` + timestamp + ` AS timestamp
+			)
+			INSERT INTO reviewdb_twitter.reports (review_id, reporter_id, timestamp)
+			SELECT review_id, reporter_id, timestamp FROM moved
+		`).Exec(context.Background())
+		if err != nil {
+			return err
+		}
+
+		if moved, _ := res.RowsAffected(); moved > 0 {
+			log.Printf("moved %d twitter reports from %s to reviewdb_twitter.reports", moved, source.schema+"."+source.table)
+		}
+	}
+	return nil
+}
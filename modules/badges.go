@@ -0,0 +1,393 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"server-go/common"
+	"server-go/database"
+	"server-go/database/schemas"
+	"server-go/modules/bitmask"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// system badges are never assigned by hand, their holders are derived from user type and flags
+const (
+	SystemBadgeAdmin     = "admin"
+	SystemBadgeModerator = "moderator"
+	SystemBadgeBanned    = "banned"
+	SystemBadgeDonor     = "donor"
+)
+
+const badgesChangedChannel = "badges_changed"
+
+var ErrBadgeNotFound = NotFoundError("badge_not_found", "Badge not found")
+
+// systemBadgeHolder decides who holds a system badge. These are the same checks permissions use, so a user
+// shown as Admin or Banned is treated as one everywhere.
+var systemBadgeHolder = map[string]func(user *schemas.URUser) bool{
+	SystemBadgeAdmin: (*schemas.URUser).IsAdmin,
+	SystemBadgeModerator: func(user *schemas.URUser) bool {
+		return user.Type == schemas.UserTypeModerator || bitmask.CheckFlag(user.Flags, bitmask.UserModerator)
+	},
+	SystemBadgeBanned: (*schemas.URUser).IsBanned,
+	SystemBadgeDonor: func(user *schemas.URUser) bool {
+		return bitmask.CheckFlag(user.Flags, bitmask.UserDonor)
+	},
+}
+
+// badgeIndex holds every visible badge, as a list and keyed by discord id, ordered by badge priority.
+// Accounts on other platforms only hold system badges, keyed by platform and then account id.
+type badgeIndex struct {
+	all        []schemas.UserBadge
+	byUser     map[string][]schemas.UserBadge
+	byPlatform map[string]map[string][]schemas.UserBadge
+}
+
+var badgeIndexLock sync.Mutex
+
+// InvalidateBadgeCache drops the badge index on this and, through a postgres notification, every other instance.
+// It has to be called whenever badges, user types, flags or bans change.
+func InvalidateBadgeCache() {
+	common.Cache.Delete("badges")
+
+	if err := pgdriver.Notify(context.Background(), database.DB, badgesChangedChannel, ""); err != nil {
+		fmt.Println("failed to notify badge change:", err)
+	}
+}
+
+// StartBadgeCacheListener drops the local badge index whenever another instance changes badges.
+// The cache expiry still applies in case a notification is missed.
+func StartBadgeCacheListener() {
+	listener := pgdriver.NewListener(database.DB)
+	defer listener.Close()
+
+	if err := listener.Listen(context.Background(), badgesChangedChannel); err != nil {
+		fmt.Println("failed to listen for badge changes:", err)
+		return
+	}
+
+	for range listener.Channel() {
+		common.Cache.Delete("badges")
+	}
+}
+
+// loadSystemBadgeCandidates loads every user that could hold a system badge
+func loadSystemBadgeCandidates() ([]schemas.URUser, error) {
+	users := []schemas.URUser{}
+	err := database.DB.NewSelect().
+		Model(&users).
+		Column("ur_user.id", "ur_user.discord_id", "ur_user.platform", "ur_user.type", "ur_user.flags", "ur_user.ban_id").
+		Relation("BanInfo").
+		Where("ur_user.type != 0 OR ur_user.flags != 0 OR ur_user.ban_id IS NOT NULL").
+		Scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range users {
+		if users[i].BanInfo != nil && users[i].BanInfo.BanEndDate.Before(time.Now()) {
+			users[i].BanInfo = nil
+		}
+	}
+
+	return users, nil
+}
+
+// loadBadges resolves every visible badge to its holders
+func loadBadges() (*badgeIndex, error) {
+	definitions := []schemas.BadgeDefinition{}
+	err := database.DB.NewSelect().
+		Model(&definitions).
+		Where("hidden = false").
+		Order("priority DESC", "id ASC").
+		Scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := []schemas.BadgeAssignment{}
+	err = database.DB.NewSelect().
+		Model(&assignments).
+		Where("expires_at IS NULL OR expires_at > now()").
+		Order("id ASC").
+		Scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := loadSystemBadgeCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	holders := map[int32][]string{}
+	for _, assignment := range assignments {
+		holders[assignment.BadgeID] = append(holders[assignment.BadgeID], assignment.TargetDiscordID)
+	}
+
+	index := &badgeIndex{
+		all:        []schemas.UserBadge{},
+		byUser:     map[string][]schemas.UserBadge{},
+		byPlatform: map[string]map[string][]schemas.UserBadge{},
+	}
+	for _, definition := range definitions {
+		discordIDs := holders[definition.ID]
+		if isHolder, ok := systemBadgeHolder[definition.SystemKey]; ok {
+			discordIDs = nil
+			for i := range candidates {
+				if !isHolder(&candidates[i]) {
+					continue
+				}
+
+				if candidates[i].Platform == schemas.PlatformDiscord {
+					discordIDs = append(discordIDs, candidates[i].DiscordID)
+					continue
+				}
+
+				platformBadges := index.byPlatform[candidates[i].Platform]
+				if platformBadges == nil {
+					platformBadges = map[string][]schemas.UserBadge{}
+					index.byPlatform[candidates[i].Platform] = platformBadges
+				}
+				platformBadges[candidates[i].DiscordID] = append(platformBadges[candidates[i].DiscordID], definition.ToUserBadge(candidates[i].DiscordID))
+			}
+		}
+
+		for _, discordID := range discordIDs {
+			badge := definition.ToUserBadge(discordID)
+			index.all = append(index.all, badge)
+			index.byUser[discordID] = append(index.byUser[discordID], badge)
+		}
+	}
+
+	return index, nil
+}
+
+func getBadgeIndex() (*badgeIndex, error) {
+	if cached, found := common.Cache.Get("badges"); found {
+		return cached.(*badgeIndex), nil
+	}
+
+	// only one request rebuilds the index, the others wait for it
+	badgeIndexLock.Lock()
+	defer badgeIndexLock.Unlock()
+
+	if cached, found := common.Cache.Get("badges"); found {
+		return cached.(*badgeIndex), nil
+	}
+
+	index, err := loadBadges()
+	if err != nil {
+		return nil, err
+	}
+
+	common.Cache.Set("badges", index, cache.DefaultExpiration)
+	return index, nil
+}
+
+// GetBadgesOfUser returns the user's badges ordered by priority, the slice is shared and must not be modified
+func GetBadgesOfUser(discordid string) []schemas.UserBadge {
+	index, err := getBadgeIndex()
+	if err != nil || index.byUser[discordid] == nil {
+		return []schemas.UserBadge{}
+	}
+	return index.byUser[discordid]
+}
+
+// GetPlatformBadgesOfUser returns the badges of an account on any identity provider, the slice is shared and must
+// not be modified
+func GetPlatformBadgesOfUser(platform string, platformID string) []schemas.UserBadge {
+	if platform == "" || platform == schemas.PlatformDiscord {
+		return GetBadgesOfUser(platformID)
+	}
+
+	index, err := getBadgeIndex()
+	if err != nil || index.byPlatform[platform][platformID] == nil {
+		return []schemas.UserBadge{}
+	}
+	return index.byPlatform[platform][platformID]
+}
+
+func GetAllBadges() ([]schemas.UserBadge, error) {
+	index, err := getBadgeIndex()
+	if err != nil {
+		return []schemas.UserBadge{}, err
+	}
+	return index.all, nil
+}
+
+func GetBadgesMap() (map[string][]schemas.UserBadge, error) {
+	index, err := getBadgeIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.byUser, nil
+}
+
+func GetBadgeDefinitions() (definitions []schemas.BadgeDefinition, err error) {
+	definitions = []schemas.BadgeDefinition{}
+	err = database.DB.NewSelect().
+		Model(&definitions).
+		Order("priority DESC", "id ASC").
+		Scan(context.Background())
+	return
+}
+
+func GetBadgeDefinition(id int32) (*schemas.BadgeDefinition, error) {
+	definition := &schemas.BadgeDefinition{}
+	err := database.DB.NewSelect().Model(definition).Where("id = ?", id).Scan(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBadgeNotFound
+	}
+	return definition, err
+}
+
+func validateBadgeDefinition(definition *schemas.BadgeDefinition) error {
+	definition.Name = strings.TrimSpace(definition.Name)
+	if definition.Name == "" || len(definition.Name) > 64 {
+		return ValidationError("invalid_badge_name", "Badge name must be between 1 and 64 characters")
+	}
+
+	if definition.Icon != "" && !strings.HasPrefix(definition.Icon, "https://") {
+		return ValidationError("invalid_badge_icon", "Badge icon must be an https url")
+	}
+
+	if definition.Icon == "" && !definition.Hidden {
+		return ValidationError("missing_badge_icon", "Visible badges need an icon")
+	}
+
+	return nil
+}
+
+// CreateBadgeDefinition creates a badge that can be assigned to users, system badges can't be created
+func CreateBadgeDefinition(definition *schemas.BadgeDefinition) error {
+	if err := validateBadgeDefinition(definition); err != nil {
+		return err
+	}
+
+	definition.ID = 0
+	definition.SystemKey = ""
+	_, err := database.DB.NewInsert().Model(definition).Exec(context.Background())
+	InvalidateBadgeCache()
+	return err
+}
+
+func UpdateBadgeDefinition(definition *schemas.BadgeDefinition) error {
+	if err := validateBadgeDefinition(definition); err != nil {
+		return err
+	}
+
+	res, err := database.DB.NewUpdate().
+		Model(definition).
+		Column("name", "icon_url", "redirect_url", "type", "description", "priority", "hidden").
+		WherePK().
+		Returning("*").
+		Exec(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrBadgeNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if updated, _ := res.RowsAffected(); updated == 0 {
+		return ErrBadgeNotFound
+	}
+
+	InvalidateBadgeCache()
+	return nil
+}
+
+// DeleteBadgeDefinition deletes a badge along with all of its assignments
+func DeleteBadgeDefinition(id int32) error {
+	definition, err := GetBadgeDefinition(id)
+	if err != nil {
+		return err
+	}
+
+	if definition.SystemKey != "" {
+		return ValidationError("delete_system_badge", "System badges can't be deleted, hide them instead")
+	}
+
+	err = database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().Model((*schemas.BadgeAssignment)(nil)).Where("badge_id = ?", id).Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().Model(definition).WherePK().Exec(ctx)
+		return err
+	})
+	InvalidateBadgeCache()
+	return err
+}
+
+// GetBadgeAssignments returns every assignment of the badge, including expired ones
+func GetBadgeAssignments(badgeID int32) (assignments []schemas.BadgeAssignment, err error) {
+	if _, err = GetBadgeDefinition(badgeID); err != nil {
+		return
+	}
+
+	assignments = []schemas.BadgeAssignment{}
+	err = database.DB.NewSelect().
+		Model(&assignments).
+		Where("badge_id = ?", badgeID).
+		Order("id ASC").
+		Scan(context.Background())
+	return
+}
+
+// AssignBadge gives the badge to a user until expiresAt, or forever if it is nil.
+// Assigning a badge the user already has only updates the expiry.
+func AssignBadge(badgeID int32, discordID string, expiresAt *time.Time) error {
+	definition, err := GetBadgeDefinition(badgeID)
+	if err != nil {
+		return err
+	}
+
+	if definition.SystemKey != "" {
+		return ValidationError("assign_system_badge", "System badges are assigned automatically")
+	}
+
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return ValidationError("invalid_badge_expiry", "Expiry must be in the future")
+	}
+
+	_, err = database.DB.NewInsert().
+		Model(&schemas.BadgeAssignment{
+			BadgeID:         badgeID,
+			TargetDiscordID: discordID,
+			ExpiresAt:       expiresAt,
+		}).
+		On("CONFLICT (badge_id, target_discord_id) DO UPDATE").
+		Set("expires_at = EXCLUDED.expires_at").
+		Exec(context.Background())
+	InvalidateBadgeCache()
+	return err
+}
+
+func UnassignBadge(badgeID int32, discordID string) error {
+	res, err := database.DB.NewDelete().
+		Model((*schemas.BadgeAssignment)(nil)).
+		Where("badge_id = ? AND target_discord_id = ?", badgeID, discordID).
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := res.RowsAffected(); deleted == 0 {
+		return NotFoundError("badge_not_assigned", "User doesn't have this badge")
+	}
+
+	InvalidateBadgeCache()
+	return nil
+}
@@ -0,0 +1,139 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"server-go/database"
+	"server-go/database/schemas"
+)
+
+const (
+	ConnectionProviderGithub  = "github"
+	ConnectionProviderTwitter = "reviewdb_twitter" // a ReviewDB Twitter account, provider id is the twitter id
+)
+
+var (
+	ErrConnectionNotFound      = NotFoundError("connection_not_found", "Connection not found")
+	ErrConnectionLinkedToOther = ConflictError("connection_linked_to_other", "This account is already linked to another user")
+)
+
+// Connection is a linked account as shown to its owner, tokens are never exposed
+type Connection struct {
+	ID         int32  `json:"id"`
+	Provider   string `json:"provider"`
+	ProviderID string `json:"providerID"`
+	Username   string `json:"username"`
+	Avatar     string `json:"avatar"`
+	Public     bool   `json:"public"`
+	CreatedAt  int64  `json:"createdAt"`
+}
+
+// PublicConnection is a linked account shown on the user's profile
+type PublicConnection struct {
+	Provider   string `json:"provider"`
+	ProviderID string `json:"providerID"`
+	Username   string `json:"username"`
+	Avatar     string `json:"avatar"`
+}
+
+func GetConnections(user *schemas.URUser) ([]Connection, error) {
+	tokens := []schemas.Oauth2Token{}
+	err := database.DB.NewSelect().
+		Model(&tokens).
+		Where("user_id = ?", user.ID).
+		Order("id ASC").
+		Scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	connections := make([]Connection, len(tokens))
+	for i, token := range tokens {
+		connections[i] = Connection{
+			ID:         token.Id,
+			Provider:   token.Provider,
+			ProviderID: token.ProviderId,
+			Username:   token.Username,
+			Avatar:     token.Avatar,
+			Public:     token.Public,
+			CreatedAt:  token.CreatedAt.Unix(),
+		}
+	}
+	return connections, nil
+}
+
+func GetPublicConnections(userID int32) ([]PublicConnection, error) {
+	connections := []PublicConnection{}
+	err := database.DB.NewSelect().
+		Model((*schemas.Oauth2Token)(nil)).
+		Column("provider", "provider_id", "username", "avatar").
+		Where("user_id = ? AND public = true", userID).
+		Order("id ASC").
+		Scan(context.Background(), &connections)
+	return connections, err
+}
+
+// AddConnection links an external account to a user. Linking an account again refreshes its tokens,
+// an account can only be linked to one user.
+func AddConnection(token *schemas.Oauth2Token) error {
+	res, err := database.DB.NewInsert().
+		Model(token).
+		On("CONFLICT (provider, provider_id) DO UPDATE").
+		Set("access_token = EXCLUDED.access_token").
+		Set("refresh_token = EXCLUDED.refresh_token").
+		Set("expiry = EXCLUDED.expiry").
+		Set("username = EXCLUDED.username").
+		Set("avatar = EXCLUDED.avatar").
+		Where("oauth2_token.user_id = EXCLUDED.user_id").
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrConnectionLinkedToOther
+	}
+	return nil
+}
+
+func DeleteConnection(user *schemas.URUser, connectionID int32) error {
+	token := &schemas.Oauth2Token{}
+	err := database.DB.NewDelete().
+		Model(token).
+		Where("id = ? AND user_id = ?", connectionID, user.ID).
+		Returning("provider, provider_id").
+		Scan(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConnectionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// sponsors lose the donor status they got through this link
+	if token.Provider == ConnectionProviderGithub {
+		err = syncGithubSponsorDonor(token.ProviderId)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+	}
+	return err
+}
+
+func SetConnectionPublic(user *schemas.URUser, connectionID int32, public bool) error {
+	res, err := database.DB.NewUpdate().
+		Model((*schemas.Oauth2Token)(nil)).
+		Set("public = ?", public).
+		Where("id = ? AND user_id = ?", connectionID, user.ID).
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrConnectionNotFound
+	}
+	return nil
+}
@@ -1,14 +1,17 @@
 package discord
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"regexp"
 	"server-go/common"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/state"
@@ -88,18 +91,60 @@ func GetUser(token string) (user *discord.User, err error) {
 	return nil, err
 }
 
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+var ErrWebhookNotConfigured = errors.New("webhook url is not configured")
+
+// WebhookError is returned by SendWebhook when discord doesn't accept a webhook
+type WebhookError struct {
+	StatusCode int
+	RetryAfter time.Duration // how long discord wants us to wait, only set for 429s
+	Body       string
+}
+
+func (err *WebhookError) Error() string {
+	return fmt.Sprintf("discord answered webhook with %d: %s", err.StatusCode, err.Body)
+}
+
+// Temporary tells if sending the same webhook again later can succeed
+func (err *WebhookError) Temporary() bool {
+	return err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= 500
+}
+
+// SendWebhook posts to a webhook right away, modules queue webhooks with modules.QueueDiscordWebhook instead
 func SendWebhook(url string, data WebhookData) error {
+	if url == "" {
+		return ErrWebhookNotConfigured
+	}
+
 	body, err := json.Marshal(data)
-	var resp *http.Response
+	if err != nil {
+		return err
+	}
 
-	resp, err = http.Post(url, "application/json", strings.NewReader(string(body)))
-	_, err = io.ReadAll(resp.Body)
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-	return err
-}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
 
-func SendLoggerWebhook(data WebhookData) error {
-	return SendWebhook(common.Config.LoggerWebhook, data)
+	webhookErr := &WebhookError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var rateLimit struct {
+			RetryAfter float64 `json:"retry_after"` // seconds
+		}
+		if json.Unmarshal(respBody, &rateLimit) == nil && rateLimit.RetryAfter > 0 {
+			webhookErr.RetryAfter = time.Duration(rateLimit.RetryAfter * float64(time.Second))
+		} else if seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+			webhookErr.RetryAfter = time.Duration(seconds * float64(time.Second))
+		}
+	}
+	return webhookErr
 }
 
 func RefreshToken(token string) (*oauth2.Token, error) {
@@ -0,0 +1,62 @@
+package discord
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendWebhookReadsRetryAfter(t *testing.T) {
+	for name, tc := range map[string]struct {
+		header string
+		body   string
+		want   time.Duration
+	}{
+		"body":   {body: `{"message":"You are being rate limited.","retry_after":1.5,"global":false}`, want: 1500 * time.Millisecond},
+		"header": {header: "2", body: "rate limited", want: 2 * time.Second},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tc.header != "" {
+				w.Header().Set("Retry-After", tc.header)
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(tc.body))
+		}))
+
+		var webhookErr *WebhookError
+		err := SendWebhook(server.URL, WebhookData{Content: "hi"})
+		if !errors.As(err, &webhookErr) || webhookErr.RetryAfter != tc.want || !webhookErr.Temporary() {
+			t.Errorf("%s: SendWebhook = %v, want a temporary error with retry after %v", name, err, tc.want)
+		}
+		server.Close()
+	}
+}
+
+func TestSendWebhookErrors(t *testing.T) {
+	if err := SendWebhook("", WebhookData{}); !errors.Is(err, ErrWebhookNotConfigured) {
+		t.Errorf("SendWebhook without url = %v", err)
+	}
+
+	for status, temporary := range map[int]bool{http.StatusNotFound: false, http.StatusBadGateway: true} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+
+		var webhookErr *WebhookError
+		err := SendWebhook(server.URL, WebhookData{})
+		if !errors.As(err, &webhookErr) || webhookErr.StatusCode != status || webhookErr.Temporary() != temporary {
+			t.Errorf("SendWebhook with %d = %v, want temporary %v", status, err, temporary)
+		}
+		server.Close()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	if err := SendWebhook(server.URL, WebhookData{}); err != nil {
+		t.Errorf("SendWebhook with 204 = %v", err)
+	}
+}
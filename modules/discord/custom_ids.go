@@ -0,0 +1,134 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"server-go/database/schemas"
+	"strconv"
+	"strings"
+)
+
+// discord doesn't accept longer custom ids
+const maxCustomIDLength = 100
+
+var ErrInvalidCustomID = errors.New("invalid custom id")
+
+type CustomIDArg int
+
+const (
+	ArgID         CustomIDArg = iota // a positive database id, like a review or appeal id
+	ArgOptionalID                    // a database id that is 0 when there is none
+	ArgAccountID                     // the id of an account on its platform
+)
+
+// ComponentAction is what a message component does. Its custom id is the action's name followed by the
+// arguments, separated by colons. Components acting on accounts of other platforms than discord are prefixed
+// with the platform, like "twitter:ban_select:<account id>:<review id>".
+type ComponentAction struct {
+	Name string
+	Args []CustomIDArg
+}
+
+var (
+	ActionDeleteReview  = ComponentAction{"delete_review", []CustomIDArg{ArgID}}
+	ActionRestoreReview = ComponentAction{"restore_review", []CustomIDArg{ArgID}}
+	// buttons that ask for a ban duration, the select menus they answer with ban the user once one is picked
+	ActionBanSelect          = ComponentAction{"ban_select", []CustomIDArg{ArgAccountID, ArgOptionalID}}
+	ActionBanUser            = ComponentAction{"ban_user", []CustomIDArg{ArgAccountID, ArgOptionalID}}
+	ActionSelectDeleteAndBan = ComponentAction{"select_delete_and_ban", []CustomIDArg{ArgID, ArgAccountID}}
+	ActionDeleteAndBan       = ComponentAction{"delete_and_ban", []CustomIDArg{ArgAccountID, ArgID}}
+	ActionAcceptAppeal       = ComponentAction{"accept_appeal", []CustomIDArg{ArgID}}
+	// the button opens a modal, submitting the modal denies the appeal
+	ActionTextDenyAppeal = ComponentAction{"text_deny_appeal", []CustomIDArg{ArgID}}
+	ActionDenyAppeal     = ComponentAction{"deny_appeal", []CustomIDArg{ArgID}}
+)
+
+// ComponentActions are the actions ParseCustomID knows
+var ComponentActions = map[string]ComponentAction{}
+
+func init() {
+	for _, action := range []ComponentAction{
+		ActionDeleteReview, ActionRestoreReview, ActionBanSelect, ActionBanUser, ActionSelectDeleteAndBan,
+		ActionDeleteAndBan, ActionAcceptAppeal, ActionTextDenyAppeal, ActionDenyAppeal,
+	} {
+		ComponentActions[action.Name] = action
+	}
+}
+
+// CustomID is a parsed custom id, its arguments are valid for their action
+type CustomID struct {
+	Action   ComponentAction
+	Platform string
+	args     []string
+}
+
+// CustomID encodes a custom id for the action. The arguments are not checked, interactions with a custom id
+// that doesn't fit its action are rejected by ParseCustomID.
+func (action ComponentAction) CustomID(platform string, args ...any) string {
+	parts := make([]string, 0, len(args)+2)
+	if platform != schemas.PlatformDiscord {
+		parts = append(parts, platform)
+	}
+	parts = append(parts, action.Name)
+	for _, arg := range args {
+		parts = append(parts, fmt.Sprint(arg))
+	}
+	return strings.Join(parts, ":")
+}
+
+func ParseCustomID(raw string) (id CustomID, err error) {
+	if len(raw) > maxCustomIDLength {
+		return id, ErrInvalidCustomID
+	}
+
+	parts := strings.Split(raw, ":")
+	id.Platform = schemas.PlatformDiscord
+	if parts[0] == schemas.PlatformTwitter {
+		id.Platform = parts[0]
+		parts = parts[1:]
+	}
+	if len(parts) == 0 {
+		return id, ErrInvalidCustomID
+	}
+
+	action, ok := ComponentActions[parts[0]]
+	if !ok || len(parts)-1 != len(action.Args) {
+		return id, ErrInvalidCustomID
+	}
+
+	for i, kind := range action.Args {
+		if !validArg(kind, parts[i+1]) {
+			return id, ErrInvalidCustomID
+		}
+	}
+
+	id.Action = action
+	id.args = parts[1:]
+	return id, nil
+}
+
+func validArg(kind CustomIDArg, arg string) bool {
+	switch kind {
+	case ArgID:
+		id, err := strconv.ParseInt(arg, 10, 32)
+		return err == nil && id > 0
+	case ArgOptionalID:
+		id, err := strconv.ParseInt(arg, 10, 32)
+		return err == nil && id >= 0
+	case ArgAccountID:
+		id, err := strconv.ParseUint(arg, 10, 64)
+		return err == nil && id > 0
+	}
+	return false
+}
+
+// ID is the database id argument at index i
+func (id CustomID) ID(i int) int32 {
+	parsed, _ := strconv.ParseInt(id.args[i], 10, 32)
+	return int32(parsed)
+}
+
+// AccountID is the account id argument at index i
+func (id CustomID) AccountID(i int) string {
+	return id.args[i]
+}
@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"server-go/database/schemas"
+)
+
+func TestCustomIDRoundTrip(t *testing.T) {
+	for raw, want := range map[string]struct {
+		platform string
+		action   ComponentAction
+	}{
+		ActionDeleteReview.CustomID(schemas.PlatformDiscord, 12):                            {schemas.PlatformDiscord, ActionDeleteReview},
+		ActionBanSelect.CustomID(schemas.PlatformTwitter, "1234567890", 0):                  {schemas.PlatformTwitter, ActionBanSelect},
+		ActionSelectDeleteAndBan.CustomID(schemas.PlatformDiscord, 5, "343383572805058560"): {schemas.PlatformDiscord, ActionSelectDeleteAndBan},
+	} {
+		id, err := ParseCustomID(raw)
+		if err != nil || id.Platform != want.platform || id.Action.Name != want.action.Name {
+			t.Errorf("ParseCustomID(%q) = %+v, %v", raw, id, err)
+		}
+	}
+}
+
+func TestParseCustomIDReadsPostedComponents(t *testing.T) {
+	// components of messages that were sent before custom ids were encoded by ComponentAction
+	id, err := ParseCustomID("twitter:select_delete_and_ban:42:1234567890")
+	if err != nil || id.Platform != schemas.PlatformTwitter || id.ID(0) != 42 || id.AccountID(1) != "1234567890" {
+		t.Fatalf("ParseCustomID = %+v, %v", id, err)
+	}
+
+	id, err = ParseCustomID("ban_select:343383572805058560:0")
+	if err != nil || id.Platform != schemas.PlatformDiscord || id.AccountID(0) != "343383572805058560" || id.ID(1) != 0 {
+		t.Fatalf("ParseCustomID = %+v, %v", id, err)
+	}
+}
+
+func TestParseCustomIDRejectsInvalidIDs(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"twitter",
+		"twitter:",
+		"unknown_action:1",
+		"delete_review",
+		"delete_review:1:2",
+		"delete_review:0",
+		"delete_review:-1",
+		"delete_review:abc",
+		"delete_review:99999999999",
+		"ban_select:abc:0",
+		"ban_select:123:-1",
+		"ban_user:0:1",
+		"delete_review:" + strings.Repeat("1", 100),
+	} {
+		if id, err := ParseCustomID(raw); err == nil {
+			t.Errorf("ParseCustomID(%q) = %+v, want an error", raw, id)
+		}
+	}
+}
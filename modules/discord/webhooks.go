@@ -1,20 +1,23 @@
 package discord
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
 	"server-go/common"
 	"server-go/database/schemas"
 	"server-go/modules/moderation"
+	"server-go/modules/translation"
 	"strconv"
+	"time"
 
 	"github.com/diamondburned/arikawa/v3/discord"
 )
 
-func SendUserBannedWebhook(reviewer *schemas.URUser, review *schemas.UserReview) {
-	SendLoggerWebhook(WebhookData{
+// the reviewed user is shown as "?" when discord takes longer to look them up
+const userLookupTimeout = 5 * time.Second
+
+func UserBannedWebhook(reviewer *schemas.URUser, review *schemas.UserReview) WebhookData {
+	return WebhookData{
 		Username: "ReviewDB",
 		Content:  "User <@" + reviewer.DiscordID + "> has been banned for 1 week for trying to post a profane review",
 		Embeds: []discord.Embed{
@@ -35,29 +38,24 @@ func SendUserBannedWebhook(reviewer *schemas.URUser, review *schemas.UserReview)
 				},
 			},
 		},
-	})
+	}
 }
 
-func SendReportWebhook(reporter *schemas.URUser, review *schemas.UserReview, reportedUser *schemas.URUser) error {
+// ReportWebhook builds the message staff moderates a report with. It rates the review, so it is only called
+// from the webhook worker. flagged tells if the review looks bad enough for the report channel.
+func ReportWebhook(reporter *schemas.URUser, review *schemas.UserReview, reportedUser *schemas.URUser, revisions []schemas.ReviewRevision, translated translation.Result) (webhookData WebhookData, flagged bool) {
 
 	reviewedUsername := "?"
-	if reviewedUser, err := ArikawaState.User(discord.UserID(review.ProfileID)); err == nil {
+	ctx, cancel := context.WithTimeout(context.Background(), userLookupTimeout)
+	defer cancel()
+	if reviewedUser, err := ArikawaState.WithContext(ctx).User(discord.UserID(review.ProfileID)); err == nil {
 		reviewedUsername = reviewedUser.Tag()
 	}
 
 	sourceLang := ""
-	translatedContent := ""
-	if res, err := http.Get("https://translate.googleapis.com/translate_a/single?client=gtx&sl=auto&tl=en&dt=t&dj=1&source=input&q=" + url.QueryEscape(review.Comment)); err == nil {
-		var trans common.Translate
-		if err = json.NewDecoder(res.Body).Decode(&trans); err == nil {
-			if trans.Src != "en" && trans.Confidence > 0.3 {
-				sourceLang = " (" + trans.Src + ")"
-				translatedContent = ""
-				for _, sentence := range trans.Sentences {
-					translatedContent += sentence.Trans + "\n"
-				}
-			}
-		}
+	translatedContent := translated.Text
+	if translated.Translated() {
+		sourceLang = " (" + translated.SourceLang + ")"
 	}
 
 	var commentSuffix string
@@ -78,11 +76,10 @@ func SendReportWebhook(reporter *schemas.URUser, review *schemas.UserReview, rep
 		}
 	} else {
 		println(err.Error())
-		commentSuffix = fmt.Sprintf(" (Rating: Error)")
+		commentSuffix = " (Rating: Error)"
 	}
 
-
-	webhookData := WebhookData{
+	webhookData = WebhookData{
 		Username: "ReviewDB",
 		Content:  "Reported Review",
 		Components: []WebhookComponent{
@@ -93,7 +90,7 @@ func SendReportWebhook(reporter *schemas.URUser, review *schemas.UserReview, rep
 						Type:     2,
 						Label:    "Delete Review",
 						Style:    4,
-						CustomID: fmt.Sprintf("delete_review:%d", review.ID),
+						CustomID: ActionDeleteReview.CustomID(review.Platform, review.ID),
 						Emoji: discord.ComponentEmoji{
 							Name: "🗑️",
 						},
@@ -102,7 +99,7 @@ func SendReportWebhook(reporter *schemas.URUser, review *schemas.UserReview, rep
 						Type:     2,
 						Label:    "Ban User",
 						Style:    4,
-						CustomID: fmt.Sprintf("ban_select:%s:%d", reportedUser.DiscordID, review.ID), //string(reportedUser.DiscordID)
+						CustomID: ActionBanSelect.CustomID(review.Platform, reportedUser.DiscordID, review.ID),
 						Emoji: discord.ComponentEmoji{
 							Name:     "banned",
 							ID:       590237837299941382,
@@ -113,7 +110,7 @@ func SendReportWebhook(reporter *schemas.URUser, review *schemas.UserReview, rep
 						Type:     2,
 						Label:    "Delete Review and Ban User",
 						Style:    4,
-						CustomID: fmt.Sprintf("select_delete_and_ban:%d:%s", review.ID, string(reportedUser.DiscordID)),
+						CustomID: ActionSelectDeleteAndBan.CustomID(review.Platform, review.ID, reportedUser.DiscordID),
 						Emoji: discord.ComponentEmoji{
 							Name:     "banned",
 							ID:       590237837299941382,
@@ -132,7 +129,7 @@ func SendReportWebhook(reporter *schemas.URUser, review *schemas.UserReview, rep
 					},
 					{
 						Name:  "**Content**",
-						Value: fmt.Sprint(review.Comment,commentSuffix),
+						Value: fmt.Sprint(review.Comment, commentSuffix),
 					},
 					{
 						Name:  "**Translated Content" + sourceLang + "**",
@@ -163,77 +160,101 @@ func SendReportWebhook(reporter *schemas.URUser, review *schemas.UserReview, rep
 		webhookData.Embeds[0].Fields = append(fields, embed.Fields[3:]...)
 	}
 
+	if len(revisions) > 0 {
+		webhookData.Embeds[0].Fields = append(webhookData.Embeds[0].Fields, previousVersionsField(revisions))
+	}
+
 	if reportedUser.DiscordID != reporter.DiscordID {
 		webhookData.Components[0].Components = append(webhookData.Components[0].Components, WebhookComponent{
 			Type:     2,
 			Label:    "Ban Reporter",
 			Style:    4,
-			CustomID: fmt.Sprintf("ban_select:" + reporter.DiscordID + ":" + "0"),
+			CustomID: ActionBanSelect.CustomID(reporter.Platform, reporter.DiscordID, 0),
 			Emoji: discord.ComponentEmoji{
 				Name:     "banned",
 				ID:       590237837299941382,
 				Animated: true,
 			},
 		})
-	}	
+	}
 
+	return webhookData, commentSuffix != ""
+}
 
-	if commentSuffix != "" {
-		err = SendWebhook(common.Config.ReportWebhook, webhookData)
-	} else {
-		err = SendWebhook(common.Config.JunkReportWebhook, webhookData)
+// discord rejects embeds with longer field values
+const maxEmbedFieldLength = 1024
+
+// revisions shown in reports are shortened to this many characters so three of them fit in a field
+const maxRevisionLength = 300
+
+// previousVersionsField shows what a review said before it was edited, newest first
+func previousVersionsField(revisions []schemas.ReviewRevision) discord.EmbedField {
+	previousVersions := ""
+	for i := len(revisions) - 1; i >= 0 && len(revisions)-i <= 3; i-- {
+		previousVersions += fmt.Sprintf("<t:%d:f> %s\n", revisions[i].TimestampStr.Unix(), truncate(revisions[i].Comment, maxRevisionLength))
+	}
+
+	return discord.EmbedField{
+		Name:  fmt.Sprintf("**Previous Versions (%d)**", len(revisions)),
+		Value: truncate(previousVersions, maxEmbedFieldLength),
 	}
-	
-	return err
 }
 
-func SendAppealWebhook(appeal *schemas.ReviewDBAppeal, user *schemas.URUser) {
-	SendWebhook(common.Config.AppealWebhook,
-		WebhookData{
-			Username: "ReviewDB Appeals",
-			Embeds: []discord.Embed{
-				{
-					Title: "Appeal Form",
-					Fields: []discord.EmbedField{
-						{
-							Name:  "User",
-							Value: common.FormatUser(user.Username, user.ID, user.DiscordID),
-						},
-						{
-							Name:  "Reason to appeal",
-							Value: appeal.AppealText,
-						},
-						{
-							Name:  "Review Content",
-							Value: user.BanInfo.ReviewContent,
-						},
+// truncate shortens text to at most length characters
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length-1]) + "…"
+}
+
+func AppealWebhook(appeal *schemas.ReviewDBAppeal, user *schemas.URUser) WebhookData {
+	return WebhookData{
+		Username: "ReviewDB Appeals",
+		Embeds: []discord.Embed{
+			{
+				Title: "Appeal Form",
+				Fields: []discord.EmbedField{
+					{
+						Name:  "User",
+						Value: common.FormatUser(user.Username, user.ID, user.DiscordID),
+					},
+					{
+						Name:  "Reason to appeal",
+						Value: appeal.AppealText,
+					},
+					{
+						Name:  "Review Content",
+						Value: user.BanInfo.ReviewContent,
 					},
 				},
 			},
-			Components: []WebhookComponent{
-				{
-					Type: 1,
-					Components: []WebhookComponent{
-						{
-							Type:     2,
-							Label:    "Accept",
-							Style:    3,
-							CustomID: fmt.Sprintf("accept_appeal:%d", appeal.ID),
-							Emoji: discord.ComponentEmoji{
-								Name: "✅",
-							},
+		},
+		Components: []WebhookComponent{
+			{
+				Type: 1,
+				Components: []WebhookComponent{
+					{
+						Type:     2,
+						Label:    "Accept",
+						Style:    3,
+						CustomID: ActionAcceptAppeal.CustomID(schemas.PlatformDiscord, appeal.ID),
+						Emoji: discord.ComponentEmoji{
+							Name: "✅",
 						},
-						{
-							Type:     2,
-							Label:    "Deny",
-							Style:    4,
-							CustomID: fmt.Sprintf("text_deny_appeal:%d", appeal.ID),
-							Emoji: discord.ComponentEmoji{
-								Name: "❌",
-							},
+					},
+					{
+						Type:     2,
+						Label:    "Deny",
+						Style:    4,
+						CustomID: ActionTextDenyAppeal.CustomID(schemas.PlatformDiscord, appeal.ID),
+						Emoji: discord.ComponentEmoji{
+							Name: "❌",
 						},
 					},
 				},
 			},
-		})
+		},
+	}
 }
@@ -0,0 +1,33 @@
+package discord
+
+import (
+	"server-go/database/schemas"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestPreviousVersionsFieldFitsInEmbed(t *testing.T) {
+	revisions := []schemas.ReviewRevision{}
+	for i := 0; i < 5; i++ {
+		revisions = append(revisions, schemas.ReviewRevision{
+			Comment:      strings.Repeat("ä", 1000),
+			TimestampStr: time.Unix(1700000000+int64(i), 0),
+		})
+	}
+
+	field := previousVersionsField(revisions)
+	if length := utf8.RuneCountInString(field.Value); length > maxEmbedFieldLength {
+		t.Errorf("previous versions are %d characters long, discord allows %d", length, maxEmbedFieldLength)
+	}
+	if !utf8.ValidString(field.Value) {
+		t.Error("previous versions were cut inside a character")
+	}
+	if !strings.HasPrefix(field.Value, "<t:1700000004:f>") || strings.Count(field.Value, "<t:") != 3 {
+		t.Errorf("want the three newest revisions, newest first, got %q", field.Value)
+	}
+	if field.Name != "**Previous Versions (5)**" {
+		t.Errorf("name = %q", field.Name)
+	}
+}
@@ -0,0 +1,38 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"server-go/database"
+	"testing"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// emptyDB is a database without rows, every query finds nothing and every statement changes nothing
+type emptyDB struct{}
+
+func (emptyDB) Connect(context.Context) (driver.Conn, error) { return emptyDB{}, nil }
+func (emptyDB) Driver() driver.Driver                        { return nil }
+func (emptyDB) Prepare(query string) (driver.Stmt, error)    { return emptyDB{}, nil }
+func (emptyDB) Close() error                                 { return nil }
+func (emptyDB) Begin() (driver.Tx, error)                    { return emptyDB{}, nil }
+func (emptyDB) Commit() error                                { return nil }
+func (emptyDB) Rollback() error                              { return nil }
+func (emptyDB) NumInput() int                                { return -1 }
+func (emptyDB) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (emptyDB) Query(args []driver.Value) (driver.Rows, error) { return emptyDB{}, nil }
+func (emptyDB) Columns() []string                              { return nil }
+func (emptyDB) Next(dest []driver.Value) error                 { return io.EOF }
+
+// useEmptyDB points database.DB at an emptyDB for the duration of the test
+func useEmptyDB(t *testing.T) {
+	previous := database.DB
+	database.DB = bun.NewDB(sql.OpenDB(emptyDB{}), pgdialect.New())
+	t.Cleanup(func() { database.DB = previous })
+}
@@ -0,0 +1,76 @@
+package modules
+
+import "errors"
+
+// ErrorKind tells routes which status code an error should be answered with
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindBanned
+	KindNotFound
+	KindConflict
+	KindRateLimited
+)
+
+// Error is an error that is meant to be shown to the user. Code is a stable snake_case identifier that clients
+// can use to localize Message.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func ValidationError(code string, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+func UnauthorizedError(code string, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+func ForbiddenError(code string, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func BannedError(code string, message string) *Error {
+	return &Error{Kind: KindBanned, Code: code, Message: message}
+}
+
+func NotFoundError(code string, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func ConflictError(code string, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func RateLimitedError(code string, message string) *Error {
+	return &Error{Kind: KindRateLimited, Code: code, Message: message}
+}
+
+var (
+	ErrUnauthorized   = UnauthorizedError("unauthorized", "Unauthorized")
+	ErrInvalidToken   = UnauthorizedError("invalid_token", "Invalid Token")
+	ErrBanned         = BannedError("banned", "You have been banned from ReviewDB")
+	ErrNotAdmin       = ForbiddenError("not_admin", "You are not allowed to use this route")
+	ErrInvalidReview  = NotFoundError("invalid_review_id", "Invalid Review ID")
+	ErrInvalidRequest = ValidationError("invalid_request", "Invalid Request")
+	ErrUserNotFound   = NotFoundError("user_not_found", "User not found")
+)
+
+// AsError returns the typed error in err's chain, nil for unexpected errors
+func AsError(err error) *Error {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed
+	}
+	return nil
+}
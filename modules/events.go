@@ -0,0 +1,221 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"server-go/database"
+	"server-go/database/schemas"
+	"sync"
+
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+type EventType string
+
+const (
+	EventReviewAdded   EventType = "review.added"
+	EventReviewReplied EventType = "review.replied"
+	EventReviewUpdated EventType = "review.updated"
+	EventReviewDeleted EventType = "review.deleted"
+	EventReviewVoted   EventType = "review.voted"
+	EventNotification  EventType = "notification"
+)
+
+// postgres limits NOTIFY payloads to 8000 bytes so events only carry ids, clients fetch the rest
+const eventsChannel = "reviewdb_events"
+
+// subscriptions buffer this many events, a subscriber that falls further behind misses events
+const eventBufferSize = 32
+
+type Event struct {
+	Type         EventType             `json:"type"`
+	Topic        string                `json:"topic"`
+	ReviewID     int32                 `json:"reviewId,omitempty"`
+	Score        *int                  `json:"score,omitempty"`
+	Notification *schemas.Notification `json:"notification,omitempty"`
+}
+
+// ProfileTopic receives the events of reviews on a profile
+func ProfileTopic(platform string, profileID string) string {
+	return "profile:" + platform + ":" + profileID
+}
+
+// UserTopic receives the notifications of a user
+func UserTopic(userID int32) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// EventBus fans events out to the subscribers of their topic
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscription]struct{}
+	// publish sends events to every instance, events are only delivered locally when it is nil
+	publish func(event Event) error
+}
+
+type Subscription struct {
+	Events <-chan Event
+
+	events chan Event
+	topics []string
+	bus    *EventBus
+	once   sync.Once
+}
+
+// Events is the bus modules publish to, StartEventListener shares it between instances
+var Events = NewEventBus()
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: map[string]map[*Subscription]struct{}{}}
+}
+
+func (bus *EventBus) Subscribe(topics ...string) *Subscription {
+	events := make(chan Event, eventBufferSize)
+	sub := &Subscription{Events: events, events: events, topics: topics, bus: bus}
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	for _, topic := range topics {
+		if bus.subscribers[topic] == nil {
+			bus.subscribers[topic] = map[*Subscription]struct{}{}
+		}
+		bus.subscribers[topic][sub] = struct{}{}
+	}
+	return sub
+}
+
+// Close unsubscribes and closes the Events channel
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		bus := sub.bus
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		for _, topic := range sub.topics {
+			delete(bus.subscribers[topic], sub)
+			if len(bus.subscribers[topic]) == 0 {
+				delete(bus.subscribers, topic)
+			}
+		}
+		close(sub.events)
+	})
+}
+
+func (bus *EventBus) Publish(event Event) {
+	bus.mu.RLock()
+	publish := bus.publish
+	bus.mu.RUnlock()
+
+	if publish == nil {
+		bus.deliver(event)
+		return
+	}
+
+	if err := publish(event); err != nil {
+		fmt.Println("failed to publish event:", err)
+		bus.deliver(event)
+	}
+}
+
+func (bus *EventBus) deliver(event Event) {
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+
+	for sub := range bus.subscribers[event.Topic] {
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+func (bus *EventBus) setPublisher(publish func(event Event) error) {
+	bus.mu.Lock()
+	bus.publish = publish
+	bus.mu.Unlock()
+}
+
+// StartEventListener publishes events through postgres NOTIFY and delivers the events of every instance.
+// Events go back to being delivered locally when the listener stops.
+func StartEventListener() {
+	listener := pgdriver.NewListener(database.DB)
+	defer listener.Close()
+
+	if err := listener.Listen(context.Background(), eventsChannel); err != nil {
+		fmt.Println("failed to listen for events:", err)
+		return
+	}
+
+	Events.setPublisher(func(event Event) error {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return pgdriver.Notify(context.Background(), database.DB, eventsChannel, string(payload))
+	})
+	defer Events.setPublisher(nil)
+
+	for notification := range listener.Channel() {
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			fmt.Println("failed to decode event:", err)
+			continue
+		}
+		Events.deliver(event)
+	}
+}
+
+// publishReviewEvent notifies subscribers and webhooks of the reviewed profile
+func publishReviewEvent(eventType EventType, review *schemas.UserReview) {
+	if eventType == EventReviewAdded && review.RepliesTo != 0 {
+		eventType = EventReviewReplied
+	}
+	profileID := fmt.Sprint(review.ProfileID)
+
+	Events.Publish(Event{
+		Type:     eventType,
+		Topic:    ProfileTopic(review.Platform, profileID),
+		ReviewID: review.ID,
+	})
+
+	payload := WebhookPayload{
+		Event:     eventType,
+		Platform:  review.Platform,
+		ProfileID: profileID,
+		ReviewID:  review.ID,
+		RepliesTo: review.RepliesTo,
+	}
+	if eventType != EventReviewDeleted {
+		payload.Comment = review.Comment
+	}
+	queueWebhookDeliveries(payload)
+}
+
+// publishReviewVoted sends the new score of a review after a vote changed it
+func publishReviewVoted(review *schemas.UserReview) {
+	var score int
+	err := database.DB.NewSelect().
+		Model((*schemas.UserReview)(nil)).
+		Column("score").
+		Where("id = ?", review.ID).
+		Scan(context.Background(), &score)
+	if err != nil {
+		fmt.Println("failed to load score for event:", err)
+		return
+	}
+
+	profileID := fmt.Sprint(review.ProfileID)
+	Events.Publish(Event{
+		Type:     EventReviewVoted,
+		Topic:    ProfileTopic(review.Platform, profileID),
+		ReviewID: review.ID,
+		Score:    &score,
+	})
+	queueWebhookDeliveries(WebhookPayload{
+		Event:     EventReviewVoted,
+		Platform:  review.Platform,
+		ProfileID: profileID,
+		ReviewID:  review.ID,
+		Score:     &score,
+	})
+}
@@ -0,0 +1,65 @@
+package modules
+
+import (
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, sub *Subscription) (Event, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events:
+		return event, ok
+	case <-time.After(100 * time.Millisecond):
+		return Event{}, false
+	}
+}
+
+func TestEventBusDeliversToTopicSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	profile := bus.Subscribe(ProfileTopic("discord", "1"))
+	other := bus.Subscribe(ProfileTopic("discord", "2"))
+	defer profile.Close()
+	defer other.Close()
+
+	bus.Publish(Event{Type: EventReviewAdded, Topic: ProfileTopic("discord", "1"), ReviewID: 5})
+
+	event, ok := receive(t, profile)
+	if !ok || event.Type != EventReviewAdded || event.ReviewID != 5 {
+		t.Fatalf("got %+v, want the published event", event)
+	}
+	if event, ok := receive(t, other); ok {
+		t.Fatalf("subscriber of another topic got %+v", event)
+	}
+}
+
+func TestEventBusCloseUnsubscribes(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(UserTopic(1))
+	sub.Close()
+	sub.Close()
+
+	if _, ok := <-sub.Events; ok {
+		t.Fatal("events channel is still open after Close")
+	}
+	if len(bus.subscribers) != 0 {
+		t.Fatalf("bus still has %d topics", len(bus.subscribers))
+	}
+
+	// publishing without subscribers must not block or panic
+	bus.Publish(Event{Type: EventNotification, Topic: UserTopic(1)})
+}
+
+func TestEventBusDropsEventsOfSlowSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(UserTopic(1))
+	defer sub.Close()
+
+	for i := 0; i < eventBufferSize*2; i++ {
+		bus.Publish(Event{Type: EventNotification, Topic: UserTopic(1)})
+	}
+
+	if len(sub.Events) != eventBufferSize {
+		t.Fatalf("buffered %d events, want %d", len(sub.Events), eventBufferSize)
+	}
+}
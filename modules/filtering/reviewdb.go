@@ -12,6 +12,10 @@ import (
 	"server-go/modules/bitmask"
 	discord_utils "server-go/modules/discord"
 	"slices"
+	"strconv"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
 )
 
 type FilterFunction func(user *schemas.URUser, review *schemas.UserReview) error
@@ -22,9 +26,17 @@ func init() {
 
 	ReviewDB = []FilterFunction{
 
+		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
+			// accounts can only review profiles on their own platform
+			if reviewer.Platform != review.Platform {
+				err = modules.ValidationError("invalid_review", common.INVALID_REVIEW)
+			}
+			return
+		},
+
 		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
 			if !(review.Type == 0 || review.Type == 1) && reviewer.Type != 1 {
-				err = errors.New(common.INVALID_REVIEW_TYPE)
+				err = modules.ValidationError("invalid_review_type", common.INVALID_REVIEW_TYPE)
 			}
 			return
 		},
@@ -36,42 +48,43 @@ func init() {
 					Set("type = ?", -1).
 					Where("id = ?", reviewer.ID).
 					Exec(context.Background())
-				err = errors.New("Your have been banned from reviewdb")
+				modules.InvalidateBadgeCache()
+				err = modules.BannedError("banned_for_profanity", "Your have been banned from reviewdb")
 			}
 			return
 		},
 
 		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
 			if reviewer.Type != 1 && !bitmask.CheckFlag(reviewer.Flags, bitmask.UserDonor) && discord_utils.ContainsCustomDiscordEmoji(review.Comment) {
-				err = errors.New("Only ReviewDB donors are allowed to use custom emojis")
+				err = modules.ForbiddenError("custom_emojis_donor_only", "Only ReviewDB donors are allowed to use custom emojis")
 			}
 			return
 		},
 
 		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
 			if reviewer.Type != 1 && common.ContainsURL(review.Comment) {
-				err = errors.New("You are not allowed to have URLs in your review")
+				err = modules.ValidationError("urls_not_allowed", "You are not allowed to have URLs in your review")
 			}
 			return
 		},
 
 		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
 			if reviewer.OptedOut {
-				err = errors.New(common.OPTED_OUT)
+				err = modules.ForbiddenError("opted_out", common.OPTED_OUT)
 			}
 			return
 		},
 
 		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
 			if reviewer.IsBanned() {
-				err = errors.New("You have been banned from ReviewDB " + common.Ternary(reviewer.Type == -1, "permanently", "until "+reviewer.BanInfo.BanEndDate.Format("2006-01-02 15:04:05")+" UTC"))
+				err = modules.BannedError("banned", "You have been banned from ReviewDB "+common.Ternary(reviewer.Type == -1, "permanently", "until "+reviewer.BanInfo.BanEndDate.Format("2006-01-02 15:04:05")+" UTC"))
 			}
 			return
 		},
 
 		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
 			if reviewer.Type == -1 {
-				err = errors.New("You have been banned from ReviewDB permanently")
+				err = modules.BannedError("banned", "You have been banned from ReviewDB permanently")
 			}
 			return
 		},
@@ -79,14 +92,14 @@ func init() {
 		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
 			count, _ := modules.GetReviewCountInLastHour(reviewer.ID)
 			if count > 20 {
-				err = errors.New("You are reviewing too much")
+				err = modules.RateLimitedError("reviewing_too_much", "You are reviewing too much")
 			}
 			return
 		},
 
 		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
 			if common.LightProfanityDetector.IsProfane(review.Comment) {
-				err = errors.New("Your review contains profanity")
+				err = modules.ValidationError("profanity", "Your review contains profanity")
 			}
 			return
 		},
@@ -94,18 +107,22 @@ func init() {
 		func(reviewer *schemas.URUser, review *schemas.UserReview) (err error) {
 			if common.ProfanityDetector.IsProfane(review.Comment) {
 				review.ID = -1
-				modules.BanUser(reviewer.DiscordID, common.Config.AdminToken, 7, *review)
-				discord_utils.SendUserBannedWebhook(reviewer, review)
-				err = errors.New("Because of trying to post a profane review, you have been banned from ReviewDB for 1 week")
+				modules.BanUserOnPlatform(reviewer.Platform, reviewer.DiscordID, common.Config.AdminToken, 7, *review)
+				modules.QueueDiscordWebhook(modules.WebhookTargetLogger, discord_utils.UserBannedWebhook(reviewer, review))
+				err = modules.BannedError("banned_for_profanity", "Because of trying to post a profane review, you have been banned from ReviewDB for 1 week")
 			}
 			return
 		},
 		func(user *schemas.URUser, review *schemas.UserReview) error {
-			// check if user is blocked from profile
+			// check if user is blocked from profile or doesn't meet the profile's review restrictions
 
 			profileUser := &schemas.URUser{}
 
-			err := database.DB.NewSelect().Model(&schemas.URUser{}).Column("blocked_users").Where("discord_id = ?", review.ProfileID).Scan(context.Background(), profileUser)
+			err := database.DB.NewSelect().
+				Model(&schemas.URUser{}).
+				Column("blocked_users", "review_min_account_age_days", "review_min_reputation").
+				Where("platform = ? AND discord_id = ?", review.Platform, review.ProfileID).
+				Scan(context.Background(), profileUser)
 
 			if err != nil && !errors.Is(err, sql.ErrNoRows) {
 				fmt.Println(err)
@@ -113,7 +130,25 @@ func init() {
 			}
 
 			if profileUser.BlockedUsers != nil && slices.Contains(profileUser.BlockedUsers, user.DiscordID) {
-				return errors.New("You are blocked from commenting this profile")
+				return modules.ForbiddenError("blocked_by_profile", "You are blocked from commenting this profile")
+			}
+
+			if user.Type == 1 {
+				return nil
+			}
+
+			// account age is read from the discord snowflake
+			if profileUser.ReviewMinAccountAgeDays > 0 && user.Platform == schemas.PlatformDiscord {
+				discordID, _ := strconv.ParseUint(user.DiscordID, 10, 64)
+				minCreatedAt := time.Now().AddDate(0, 0, -profileUser.ReviewMinAccountAgeDays)
+
+				if discord.Snowflake(discordID).Time().After(minCreatedAt) {
+					return modules.ForbiddenError("account_too_new_for_profile", fmt.Sprintf("This profile only accepts reviews from accounts older than %d days", profileUser.ReviewMinAccountAgeDays))
+				}
+			}
+
+			if profileUser.ReviewMinReputation != nil && user.Reputation < *profileUser.ReviewMinReputation {
+				return modules.ForbiddenError("reputation_too_low_for_profile", fmt.Sprintf("This profile only accepts reviews from users with at least %d reputation", *profileUser.ReviewMinReputation))
 			}
 			return nil
 		},
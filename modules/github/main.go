@@ -15,7 +15,7 @@ func Authorize(code string) {
 }
 
 var oauthEndpoint = oauth2.Endpoint{
-	AuthURL:   "https://github.com/login/oauth/access_token",
+	AuthURL:   "https://github.com/login/oauth/authorize",
 	TokenURL:  "https://github.com/login/oauth/access_token",
 	AuthStyle: oauth2.AuthStyleInParams,
 }
@@ -25,12 +25,11 @@ func ExchangeCode(code string) (*oauth2.Token, error) {
 
 	conf := &oauth2.Config{
 		Endpoint:     oauthEndpoint,
-		RedirectURL:  "https://manti.vendicated.dev/api/reviewdb-twitter/auth",
-		ClientID:     common.Config.Twitter.ClientID,
-		ClientSecret: common.Config.Twitter.ClientSecret,
+		ClientID:     common.Config.Github.ClientID,
+		ClientSecret: common.Config.Github.ClientSecret,
 	}
 
-	token, err := conf.Exchange(context.Background(), code, oauth2.SetAuthURLParam("grant_type", "authorization_code"), oauth2.SetAuthURLParam("code_verifier", "challenge"))
+	token, err := conf.Exchange(context.Background(), code)
 
 	if err != nil {
 		return nil, err
@@ -61,4 +60,4 @@ func GetUserInfo(accessToken string) (user *GithubUser, err error) {
 
 	err = json.NewDecoder(resp.Body).Decode(&user)
 	return
-}
\ No newline at end of file
+}
@@ -0,0 +1,156 @@
+package github
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Sponsor struct {
+	Login  string `json:"login"`
+	NodeID string `json:"node_id"`
+}
+
+type SponsorTier struct {
+	Name                string `json:"name"`
+	MonthlyPriceInCents int    `json:"monthly_price_in_cents"`
+}
+
+// SponsorshipEvent is the payload of the sponsorship webhook event
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#sponsorship
+type SponsorshipEvent struct {
+	Action      string `json:"action"`
+	Sponsorship struct {
+		Sponsor Sponsor     `json:"sponsor"`
+		Tier    SponsorTier `json:"tier"`
+	} `json:"sponsorship"`
+}
+
+// VerifyWebhookSignature checks the X-Hub-Signature-256 header of a webhook delivery against the body
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	if secret == "" {
+		return false
+	}
+
+	expected, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+
+	expectedMAC, err := hex.DecodeString(expected)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expectedMAC)
+}
+
+type ActiveSponsorship struct {
+	Sponsor Sponsor
+	Tier    SponsorTier
+}
+
+const sponsorsQuery = `
+query($cursor: String) {
+	viewer {
+		sponsorshipsAsMaintainer(first: 100, after: $cursor, activeOnly: true) {
+			pageInfo { hasNextPage endCursor }
+			nodes {
+				tier { name monthlyPriceInCents }
+				sponsorEntity {
+					... on User { login id }
+					... on Organization { login id }
+				}
+			}
+		}
+	}
+}`
+
+type sponsorsResponse struct {
+	Data struct {
+		Viewer struct {
+			SponsorshipsAsMaintainer struct {
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []struct {
+					Tier *struct {
+						Name                string `json:"name"`
+						MonthlyPriceInCents int    `json:"monthlyPriceInCents"`
+					} `json:"tier"`
+					SponsorEntity struct {
+						Login string `json:"login"`
+						ID    string `json:"id"`
+					} `json:"sponsorEntity"`
+				} `json:"nodes"`
+			} `json:"sponsorshipsAsMaintainer"`
+		} `json:"viewer"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// GetActiveSponsorships lists every active sponsorship of the account the token belongs to
+func GetActiveSponsorships(accessToken string) ([]ActiveSponsorship, error) {
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	sponsorships := []ActiveSponsorship{}
+
+	var cursor *string
+	for {
+		body, _ := json.Marshal(map[string]any{
+			"query":     sponsorsQuery,
+			"variables": map[string]any{"cursor": cursor},
+		})
+
+		req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", bytes.NewReader(body))
+		req.Header.Add("Authorization", "Bearer "+accessToken)
+		req.Header.Add("Content-Type", "application/json")
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var response sponsorsResponse
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("github returned status %d", resp.StatusCode)
+		}
+
+		if len(response.Errors) != 0 {
+			return nil, errors.New(response.Errors[0].Message)
+		}
+
+		page := response.Data.Viewer.SponsorshipsAsMaintainer
+		for _, node := range page.Nodes {
+			sponsorship := ActiveSponsorship{
+				Sponsor: Sponsor{Login: node.SponsorEntity.Login, NodeID: node.SponsorEntity.ID},
+			}
+			if node.Tier != nil {
+				sponsorship.Tier = SponsorTier{Name: node.Tier.Name, MonthlyPriceInCents: node.Tier.MonthlyPriceInCents}
+			}
+			sponsorships = append(sponsorships, sponsorship)
+		}
+
+		if !page.PageInfo.HasNextPage {
+			return sponsorships, nil
+		}
+		cursor = &page.PageInfo.EndCursor
+	}
+}
@@ -0,0 +1,180 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"server-go/database"
+	"server-go/database/schemas"
+	"server-go/modules/bitmask"
+	"server-go/modules/github"
+
+	"github.com/uptrace/bun"
+)
+
+// HandleGithubSponsorshipEvent records a sponsorship change and grants or revokes the donor flag of the linked user.
+// Pending changes are ignored, github sends another event once they take effect.
+func HandleGithubSponsorshipEvent(event github.SponsorshipEvent) error {
+	sponsor := event.Sponsorship.Sponsor
+	if sponsor.NodeID == "" {
+		return errors.New("Sponsorship event without sponsor")
+	}
+
+	switch event.Action {
+	case "created", "edited", "tier_changed":
+		return SetGithubSponsor(sponsor, event.Sponsorship.Tier, true)
+	case "cancelled":
+		return SetGithubSponsor(sponsor, event.Sponsorship.Tier, false)
+	}
+	return nil
+}
+
+// SetGithubSponsor stores whether the github account is currently sponsoring and syncs the donor flag
+func SetGithubSponsor(sponsor github.Sponsor, tier github.SponsorTier, active bool) error {
+	_, err := database.DB.NewInsert().
+		Model(&schemas.GithubSponsor{
+			NodeID:            sponsor.NodeID,
+			Login:             sponsor.Login,
+			TierName:          tier.Name,
+			MonthlyPriceCents: tier.MonthlyPriceInCents,
+			Active:            active,
+		}).
+		On("CONFLICT (node_id) DO UPDATE").
+		Set("login = EXCLUDED.login").
+		Set("tier_name = EXCLUDED.tier_name").
+		Set("monthly_price_cents = EXCLUDED.monthly_price_cents").
+		Set("active = EXCLUDED.active").
+		Set("updated_at = now()").
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return syncGithubSponsorDonor(sponsor.NodeID)
+}
+
+// syncGithubSponsorDonor grants the donor flag to the user that linked the sponsoring github account, and takes it
+// away again once the sponsorship ends. Donor flags granted by hand are never revoked.
+func syncGithubSponsorDonor(nodeID string) error {
+	changed := false
+
+	err := database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		sponsor := &schemas.GithubSponsor{}
+		err := tx.NewSelect().Model(sponsor).Where("node_id = ?", nodeID).For("UPDATE").Scan(ctx)
+		if err != nil {
+			return err
+		}
+
+		var userID int32
+		err = tx.NewSelect().
+			Model((*schemas.Oauth2Token)(nil)).
+			Column("user_id").
+			Where("provider = ? AND provider_id = ?", "github", nodeID).
+			OrderExpr("id DESC").
+			Limit(1).
+			Scan(ctx, &userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		// the github account was unlinked or linked to someone else
+		if sponsor.DonorUserID != 0 && (!sponsor.Active || sponsor.DonorUserID != userID) {
+			if err := setDonorFlag(ctx, tx, sponsor.DonorUserID, false); err != nil {
+				return err
+			}
+			sponsor.DonorUserID = 0
+			changed = true
+		}
+
+		if sponsor.Active && userID != 0 && sponsor.DonorUserID == 0 {
+			user := &schemas.URUser{}
+			err := tx.NewSelect().Model(user).Column("id", "flags").Where("id = ?", userID).Scan(ctx)
+			if err != nil {
+				return err
+			}
+
+			if !bitmask.CheckFlag(user.Flags, bitmask.UserDonor) {
+				if err := setDonorFlag(ctx, tx, userID, true); err != nil {
+					return err
+				}
+				sponsor.DonorUserID = userID
+				changed = true
+			}
+		}
+
+		_, err = tx.NewUpdate().
+			Model(sponsor).
+			Set("donor_user_id = ?", bun.NullZero(sponsor.DonorUserID)).
+			WherePK().
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		InvalidateBadgeCache()
+	}
+	return nil
+}
+
+// SyncRelinkedGithubSponsors syncs the donor flag of github accounts whose older links were removed,
+// so users that lost the link also lose the donor status they got through it
+func SyncRelinkedGithubSponsors(nodeIDs []string) error {
+	for _, nodeID := range nodeIDs {
+		err := syncGithubSponsorDonor(nodeID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+	return nil
+}
+
+func setDonorFlag(ctx context.Context, db bun.IDB, userID int32, donor bool) error {
+	query := db.NewUpdate().Model((*schemas.URUser)(nil)).Where("id = ?", userID)
+	if donor {
+		query.Set("flags = flags | ?", bitmask.UserDonor)
+	} else {
+		query.Set("flags = flags & ~?", bitmask.UserDonor)
+	}
+	_, err := query.Exec(ctx)
+	return err
+}
+
+// ReconcileGithubSponsors marks exactly the given sponsorships as active and syncs the donor flag of every sponsor,
+// fixing anything missed by webhooks
+func ReconcileGithubSponsors(sponsorships []github.ActiveSponsorship) (activated int, deactivated int, err error) {
+	active := map[string]bool{}
+	for _, sponsorship := range sponsorships {
+		active[sponsorship.Sponsor.NodeID] = true
+
+		if err = SetGithubSponsor(sponsorship.Sponsor, sponsorship.Tier, true); err != nil {
+			return
+		}
+		activated++
+	}
+
+	var stale []schemas.GithubSponsor
+	err = database.DB.NewSelect().Model(&stale).Where("active = true").Scan(context.Background())
+	if err != nil {
+		return
+	}
+
+	for _, sponsor := range stale {
+		if active[sponsor.NodeID] {
+			continue
+		}
+
+		tier := github.SponsorTier{Name: sponsor.TierName, MonthlyPriceInCents: sponsor.MonthlyPriceCents}
+		if err = SetGithubSponsor(github.Sponsor{Login: sponsor.Login, NodeID: sponsor.NodeID}, tier, false); err != nil {
+			return
+		}
+		fmt.Printf("sponsorship of %s ended\n", sponsor.Login)
+		deactivated++
+	}
+
+	return
+}
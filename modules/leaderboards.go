@@ -0,0 +1,304 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"server-go/common"
+	"server-go/database"
+	"server-go/database/schemas"
+
+	"github.com/uptrace/bun"
+)
+
+type LeaderboardPeriod string
+
+const (
+	LeaderboardWeek    LeaderboardPeriod = "week"
+	LeaderboardMonth   LeaderboardPeriod = "month"
+	LeaderboardAllTime LeaderboardPeriod = "all"
+)
+
+var LeaderboardPeriods = []LeaderboardPeriod{LeaderboardWeek, LeaderboardMonth, LeaderboardAllTime}
+
+const (
+	LeaderboardRefreshInterval = 5 * time.Minute
+	// only this many entries are cached per leaderboard, ranks below it are calculated on request
+	LeaderboardCacheSize = 1000
+)
+
+func ParseLeaderboardPeriod(period string) (LeaderboardPeriod, error) {
+	if period == "" {
+		return LeaderboardAllTime, nil
+	}
+
+	for _, p := range LeaderboardPeriods {
+		if string(p) == period {
+			return p, nil
+		}
+	}
+
+	return "", ValidationError("invalid_period", "Invalid period, must be one of week, month, all")
+}
+
+// since returns the start of the period, zero for all time
+func (p LeaderboardPeriod) since() time.Time {
+	switch p {
+	case LeaderboardWeek:
+		return time.Now().AddDate(0, 0, -7)
+	case LeaderboardMonth:
+		return time.Now().AddDate(0, 0, -30)
+	}
+	return time.Time{}
+}
+
+type LeaderboardUser struct {
+	bun.BaseModel `bun:"table:users"`
+	DiscordID     string `bun:"discord_id" json:"discord_id"`
+	Username      string `bun:"username" json:"username"`
+	Count         int    `bun:"count" json:"review_count"`
+	AvatarURL     string `bun:"avatar_url" json:"avatar_url"`
+	Rank          int    `bun:"rank" json:"rank,omitempty"`
+}
+
+// reviewCountScores selects (user_id, value) with the number of active reviews written in the period.
+// Leaderboards only rank discord accounts.
+func reviewCountScores(period LeaderboardPeriod) (string, []any) {
+	if period == LeaderboardAllTime {
+		return `SELECT reviewer_id AS user_id, COUNT(*) AS value FROM reviews
+			WHERE deleted_at IS NULL AND platform = 'discord' GROUP BY reviewer_id`, nil
+	}
+
+	return `SELECT reviewer_id AS user_id, COUNT(*) AS value FROM reviews
+		WHERE deleted_at IS NULL AND platform = 'discord' AND timestamp >= ? GROUP BY reviewer_id`, []any{period.since()}
+}
+
+// reputationScores selects (user_id, value) with the reputation gained in the period.
+// All time reputation is the counter on users, windows are summed from reputation_events.
+func reputationScores(period LeaderboardPeriod) (string, []any) {
+	if period == LeaderboardAllTime {
+		return `SELECT id AS user_id, reputation AS value FROM users WHERE reputation <> 0 AND platform = 'discord'`, nil
+	}
+
+	return `SELECT e.user_id, SUM(e.delta) AS value FROM reputation_events AS e
+		JOIN reviews AS r ON r.id = e.review_id AND r.deleted_at IS NULL AND r.platform = 'discord'
+		WHERE e.created_at >= ? GROUP BY e.user_id`, []any{period.since()}
+}
+
+func queryLeaderboard(period LeaderboardPeriod) (leaderboard []LeaderboardUser, err error) {
+	scores, args := reviewCountScores(period)
+
+	leaderboard = []LeaderboardUser{}
+	err = database.DB.NewRaw(`
+		SELECT u.discord_id, u.username, u.avatar_url, s.value AS count, RANK() OVER (ORDER BY s.value DESC) AS rank
+		FROM (`+scores+`) AS s
+		JOIN users AS u ON u.id = s.user_id
+		ORDER BY rank, u.id
+		LIMIT ?
+	`, append(args, LeaderboardCacheSize)...).Scan(context.Background(), &leaderboard)
+	return
+}
+
+func queryReputationLeaderboard(period LeaderboardPeriod) (leaderboard []ReputationStats, err error) {
+	scores, args := reputationScores(period)
+
+	leaderboard = []ReputationStats{}
+	err = database.DB.NewRaw(`
+		SELECT ranked.discord_id, ranked.username, ranked.avatar_url, ranked.reputation, ranked.rank,
+			(SELECT COUNT(*) FROM reviews AS r WHERE r.reviewer_id = ranked.id AND r.deleted_at IS NULL) AS review_count
+		FROM (
+			SELECT u.id, u.discord_id, u.username, u.avatar_url, s.value AS reputation,
+				RANK() OVER (ORDER BY s.value DESC) AS rank
+			FROM (`+scores+`) AS s
+			JOIN users AS u ON u.id = s.user_id
+			ORDER BY rank, u.id
+			LIMIT ?
+		) AS ranked
+		ORDER BY rank, review_count DESC, ranked.id
+	`, append(args, LeaderboardCacheSize)...).Scan(context.Background(), &leaderboard)
+	return
+}
+
+func leaderboardCacheKey(name string, period LeaderboardPeriod) string {
+	return "leaderboard:" + name + ":" + string(period)
+}
+
+// RefreshLeaderboards recalculates every cached leaderboard
+func RefreshLeaderboards() error {
+	for _, period := range LeaderboardPeriods {
+		leaderboard, err := queryLeaderboard(period)
+		if err != nil {
+			return err
+		}
+		common.Cache.Set(leaderboardCacheKey("reviews", period), leaderboard, 2*LeaderboardRefreshInterval)
+
+		reputation, err := queryReputationLeaderboard(period)
+		if err != nil {
+			return err
+		}
+		common.Cache.Set(leaderboardCacheKey("reputation", period), reputation, 2*LeaderboardRefreshInterval)
+	}
+	return nil
+}
+
+// StartLeaderboardRefresher keeps the cached leaderboards fresh so requests never aggregate reviews themselves
+func StartLeaderboardRefresher() {
+	for {
+		if err := RefreshLeaderboards(); err != nil {
+			fmt.Println("failed to refresh leaderboards:", err)
+		}
+
+		time.Sleep(LeaderboardRefreshInterval)
+	}
+}
+
+func getCachedLeaderboard(period LeaderboardPeriod) ([]LeaderboardUser, error) {
+	cached, found := common.Cache.Get(leaderboardCacheKey("reviews", period))
+	if found {
+		return cached.([]LeaderboardUser), nil
+	}
+
+	leaderboard, err := queryLeaderboard(period)
+	if err != nil {
+		return nil, err
+	}
+	common.Cache.Set(leaderboardCacheKey("reviews", period), leaderboard, 2*LeaderboardRefreshInterval)
+	return leaderboard, nil
+}
+
+func getCachedReputationLeaderboard(period LeaderboardPeriod) ([]ReputationStats, error) {
+	cached, found := common.Cache.Get(leaderboardCacheKey("reputation", period))
+	if found {
+		return cached.([]ReputationStats), nil
+	}
+
+	leaderboard, err := queryReputationLeaderboard(period)
+	if err != nil {
+		return nil, err
+	}
+	common.Cache.Set(leaderboardCacheKey("reputation", period), leaderboard, 2*LeaderboardRefreshInterval)
+	return leaderboard, nil
+}
+
+func paginate[T any](items []T, offset int, limit int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	return items[offset:min(offset+limit, len(items))]
+}
+
+func GetLeaderboard(period LeaderboardPeriod, offset int, limit int) ([]LeaderboardUser, error) {
+	leaderboard, err := getCachedLeaderboard(period)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(leaderboard, offset, limit), nil
+}
+
+func GetReputationLeaderboard(period LeaderboardPeriod, offset int, limit int) ([]ReputationStats, error) {
+	leaderboard, err := getCachedReputationLeaderboard(period)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(leaderboard, offset, limit), nil
+}
+
+// userScoreAndRank calculates the value and rank of a user that isn't in the cached part of a leaderboard.
+// Users without a score get no rank.
+func userScoreAndRank(scores string, args []any, userID int32) (value int, rank int, err error) {
+	err = database.DB.NewRaw(`SELECT COALESCE((SELECT value FROM (`+scores+`) AS s WHERE s.user_id = ?), 0)`,
+		append(args, userID)...).Scan(context.Background(), &value)
+	if err != nil || value == 0 {
+		return
+	}
+
+	err = database.DB.NewRaw(`SELECT COUNT(*) + 1 FROM (`+scores+`) AS s WHERE s.value > ?`,
+		append(args, value)...).Scan(context.Background(), &rank)
+	return
+}
+
+func GetLeaderboardRank(user *schemas.URUser, period LeaderboardPeriod) (LeaderboardUser, error) {
+	leaderboard, err := getCachedLeaderboard(period)
+	if err != nil {
+		return LeaderboardUser{}, err
+	}
+
+	for _, entry := range leaderboard {
+		if entry.DiscordID == user.DiscordID {
+			return entry, nil
+		}
+	}
+
+	entry := LeaderboardUser{
+		DiscordID: user.DiscordID,
+		Username:  user.Username,
+		AvatarURL: user.AvatarURL,
+	}
+
+	scores, args := reviewCountScores(period)
+	entry.Count, entry.Rank, err = userScoreAndRank(scores, args, user.ID)
+	return entry, err
+}
+
+func GetReputationLeaderboardRank(user *schemas.URUser, period LeaderboardPeriod) (ReputationStats, error) {
+	leaderboard, err := getCachedReputationLeaderboard(period)
+	if err != nil {
+		return ReputationStats{}, err
+	}
+
+	for _, entry := range leaderboard {
+		if entry.DiscordID == user.DiscordID {
+			return entry, nil
+		}
+	}
+
+	entry, err := GetUserReputation(user.DiscordID)
+	if err != nil {
+		return entry, err
+	}
+
+	scores, args := reputationScores(period)
+	entry.Reputation, entry.Rank, err = userScoreAndRank(scores, args, user.ID)
+	return entry, err
+}
+
+type ReputationHistoryPoint struct {
+	Date       string `bun:"date" json:"date"`
+	Delta      int    `bun:"delta" json:"delta"`
+	Reputation int    `bun:"-" json:"reputation"`
+}
+
+// GetReputationHistory returns the user's reputation at the end of each of the last days days, oldest first.
+// Votes cast before reputation events were recorded are part of the starting value.
+func GetReputationHistory(discordID string, days int) ([]ReputationHistoryPoint, error) {
+	user, err := GetDBUserViaDiscordID(discordID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	history := []ReputationHistoryPoint{}
+	err = database.DB.NewRaw(`
+		SELECT to_char(d.day, 'YYYY-MM-DD') AS date, COALESCE(SUM(e.delta), 0) AS delta
+		FROM generate_series(current_date - ?::integer, current_date, interval '1 day') AS d(day)
+		LEFT JOIN reputation_events AS e ON e.user_id = ?
+			AND e.created_at >= d.day AND e.created_at < d.day + interval '1 day'
+			AND EXISTS (SELECT 1 FROM reviews AS r WHERE r.id = e.review_id AND r.deleted_at IS NULL)
+		GROUP BY d.day
+		ORDER BY d.day
+	`, days-1, user.ID).Scan(context.Background(), &history)
+	if err != nil {
+		return nil, err
+	}
+
+	reputation := user.Reputation
+	for i := len(history) - 1; i >= 0; i-- {
+		history[i].Reputation = reputation
+		reputation -= history[i].Delta
+	}
+
+	return history, nil
+}
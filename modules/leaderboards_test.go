@@ -0,0 +1,14 @@
+package modules
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReputationHistoryOfUnknownUser(t *testing.T) {
+	useEmptyDB(t)
+
+	if _, err := GetReputationHistory("1", 30); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetReputationHistory(unknown user) = %v, want ErrUserNotFound", err)
+	}
+}
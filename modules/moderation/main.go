@@ -3,10 +3,14 @@ package moderation
 import (
 	"context"
 	"server-go/common"
+	"time"
 
 	openai "github.com/sashabaranov/go-openai"
 )
 
+// reviews are reported without a rating when moderation takes longer
+const moderationTimeout = 10 * time.Second
+
 var moderationClient *openai.Client
 
 func init() {
@@ -23,7 +27,8 @@ type ModerationResponse struct {
 
 // ModerateContent analyzes content using OpenAI's moderation API
 func ModerateContent(content string) (*ModerationResponse, error) {
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), moderationTimeout)
+	defer cancel()
 
 	req := openai.ModerationRequest{
 		Model: openai.ModerationOmniLatest,
@@ -0,0 +1,125 @@
+package modules
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"server-go/database"
+	"server-go/database/schemas"
+)
+
+type ReviewRestrictions struct {
+	MinAccountAgeDays int  `json:"minAccountAgeDays"`
+	MinReputation     *int `json:"minReputation"`
+}
+
+// profile owners can moderate reviews written on their own profile, admins can moderate every profile
+func canModerateProfile(user *schemas.URUser, review *schemas.UserReview) bool {
+	return user.IsAdmin() || user.DiscordID == strconv.FormatInt(review.ProfileID, 10)
+}
+
+func getModeratableReview(user *schemas.URUser, reviewID int32) (*schemas.UserReview, error) {
+	review, err := GetReview(reviewID)
+	if err != nil {
+		return nil, ErrInvalidReview
+	}
+
+	if !canModerateProfile(user, &review) {
+		return nil, ForbiddenError("not_profile_owner", "You can only moderate reviews on your own profile")
+	}
+
+	return &review, nil
+}
+
+// PinReview pins a review to the top of the profile, replacing the previously pinned review
+func PinReview(user *schemas.URUser, reviewID int32) error {
+	review, err := getModeratableReview(user, reviewID)
+	if err != nil {
+		return err
+	}
+
+	if review.RepliesTo != 0 {
+		return ValidationError("pin_reply", "You can't pin replies")
+	}
+
+	_, err = database.DB.NewUpdate().
+		Model((*schemas.UserReview)(nil)).
+		Set("pinned = (id = ?)", review.ID).
+		Where("profile_id = ?", review.ProfileID).
+		Where("pinned = true OR id = ?", review.ID).
+		Exec(context.Background())
+	return err
+}
+
+func UnpinReview(user *schemas.URUser, reviewID int32) error {
+	review, err := getModeratableReview(user, reviewID)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.NewUpdate().
+		Model((*schemas.UserReview)(nil)).
+		Set("pinned = false").
+		Where("id = ?", review.ID).
+		Exec(context.Background())
+	return err
+}
+
+// HideReview collapses a review on the profile without deleting it, the reason is shown in place of the review
+func HideReview(user *schemas.URUser, reviewID int32, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > 200 {
+		return ValidationError("reason_too_long", "Reason Too Long")
+	}
+
+	review, err := getModeratableReview(user, reviewID)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.NewUpdate().
+		Model((*schemas.UserReview)(nil)).
+		Set("hidden = true").
+		Set("hidden_reason = NULLIF(?, '')", reason).
+		Where("id = ?", review.ID).
+		Exec(context.Background())
+	return err
+}
+
+func UnhideReview(user *schemas.URUser, reviewID int32) error {
+	review, err := getModeratableReview(user, reviewID)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.NewUpdate().
+		Model((*schemas.UserReview)(nil)).
+		Set("hidden = false").
+		Set("hidden_reason = NULL").
+		Where("id = ?", review.ID).
+		Exec(context.Background())
+	return err
+}
+
+func GetReviewRestrictions(user *schemas.URUser) ReviewRestrictions {
+	return ReviewRestrictions{
+		MinAccountAgeDays: user.ReviewMinAccountAgeDays,
+		MinReputation:     user.ReviewMinReputation,
+	}
+}
+
+// SetReviewRestrictions limits who can write new reviews on the user's profile, existing reviews are not affected
+func SetReviewRestrictions(user *schemas.URUser, restrictions ReviewRestrictions) error {
+	if restrictions.MinAccountAgeDays < 0 || restrictions.MinAccountAgeDays > 3650 {
+		return ValidationError("invalid_min_account_age", "Minimum account age must be between 0 and 3650 days")
+	}
+
+	_, err := database.DB.NewUpdate().
+		Model((*schemas.URUser)(nil)).
+		Set("review_min_account_age_days = ?", restrictions.MinAccountAgeDays).
+		Set("review_min_reputation = ?", restrictions.MinReputation).
+		Where("id = ?", user.ID).
+		Exec(context.Background())
+	return err
+}
@@ -0,0 +1,244 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"server-go/database"
+	"server-go/database/schemas"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	RatingMin = 0
+	RatingMax = 100
+	// votes are grouped into buckets this wide for the distribution, the last bucket includes RatingMax
+	RatingBucketSize = 10
+)
+
+const RatingMetricStupidity = "stupidity"
+
+type RatingMetric struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// users can only be rated on opt-in metrics after opting in to them
+	OptIn bool `json:"optIn"`
+}
+
+// RatingMetrics are the metrics StupidityDB accepts votes on
+var RatingMetrics = map[string]RatingMetric{
+	RatingMetricStupidity: {
+		Key:         RatingMetricStupidity,
+		Name:        "Stupidity",
+		Description: "How stupid this user is, 0 is a genius and 100 is a rock",
+	},
+	"helpfulness": {
+		Key:         "helpfulness",
+		Name:        "Helpfulness",
+		Description: "How helpful this user is, 0 is not at all and 100 is always",
+		OptIn:       true,
+	},
+}
+
+var (
+	ErrUnknownMetric     = NotFoundError("unknown_metric", "Unknown metric")
+	ErrInvalidRating     = ValidationError("invalid_rating", fmt.Sprintf("Rating must be between %d and %d", RatingMin, RatingMax))
+	ErrMetricNotOptedIn  = ForbiddenError("metric_not_opted_in", "This user hasn't opted in to being rated on this metric")
+	ErrCannotRateSelf    = ValidationError("rate_self", "You can't rate yourself")
+	ErrRatingVoteMissing = NotFoundError("vote_missing", "You haven't voted on this user")
+)
+
+// GetRatingMetric returns the metric with the given key, stupidity if the key is empty
+func GetRatingMetric(key string) (RatingMetric, error) {
+	if key == "" {
+		key = RatingMetricStupidity
+	}
+
+	metric, ok := RatingMetrics[key]
+	if !ok {
+		return RatingMetric{}, ErrUnknownMetric
+	}
+	return metric, nil
+}
+
+func ValidateRating(value int32) error {
+	if value < RatingMin || value > RatingMax {
+		return ErrInvalidRating
+	}
+	return nil
+}
+
+type RatingBucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Count int `json:"count"`
+}
+
+type RatingSummary struct {
+	Metric string `json:"metric"`
+	// nil when nobody voted yet
+	Average      *float64       `json:"average"`
+	Count        int            `json:"count"`
+	Distribution []RatingBucket `json:"distribution"`
+	MyVote       *int32         `json:"myVote,omitempty"`
+	OptedIn      bool           `json:"optedIn"`
+}
+
+// GetRatingSummary aggregates the votes on a user's metric. voterDiscordID is optional, when set the summary
+// includes that voter's own vote.
+func GetRatingSummary(discordID int64, metric RatingMetric, voterDiscordID string) (*RatingSummary, error) {
+	summary := &RatingSummary{Metric: metric.Key, Distribution: []RatingBucket{}}
+
+	for min := RatingMin; min <= RatingMax-RatingBucketSize; min += RatingBucketSize {
+		max := min + RatingBucketSize - 1
+		if max+RatingBucketSize > RatingMax {
+			max = RatingMax
+		}
+		summary.Distribution = append(summary.Distribution, RatingBucket{Min: min, Max: max})
+	}
+
+	var buckets []struct {
+		Bucket int     `bun:"bucket"`
+		Count  int     `bun:"count"`
+		Sum    float64 `bun:"sum"`
+	}
+	err := database.DB.NewSelect().
+		Model((*schemas.StupitStat)(nil)).
+		ColumnExpr("LEAST(stupidity_value / ?, ?) AS bucket", RatingBucketSize, len(summary.Distribution)-1).
+		ColumnExpr("COUNT(*) AS count").
+		ColumnExpr("SUM(stupidity_value) AS sum").
+		Where("reviewed_discord_id = ? AND metric = ?", discordID, metric.Key).
+		GroupExpr("bucket").
+		Scan(context.Background(), &buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	var sum float64
+	for _, bucket := range buckets {
+		if bucket.Bucket < 0 || bucket.Bucket >= len(summary.Distribution) {
+			continue
+		}
+		summary.Distribution[bucket.Bucket].Count = bucket.Count
+		summary.Count += bucket.Count
+		sum += bucket.Sum
+	}
+
+	if summary.Count != 0 {
+		average := math.Round(sum/float64(summary.Count)*100) / 100
+		summary.Average = &average
+	}
+
+	if voterDiscordID != "" {
+		vote := &schemas.StupitStat{}
+		err = database.DB.NewSelect().
+			Model(vote).
+			Where("reviewed_discord_id = ? AND reviewer_discord_id = ? AND metric = ?", discordID, voterDiscordID, metric.Key).
+			Scan(context.Background())
+		if err == nil {
+			summary.MyVote = &vote.StupidityValue
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	summary.OptedIn = !metric.OptIn
+	if metric.OptIn {
+		summary.OptedIn, err = hasOptedInToMetric(fmt.Sprint(discordID), metric.Key)
+	}
+
+	return summary, err
+}
+
+func hasOptedInToMetric(discordID string, metric string) (bool, error) {
+	return database.DB.NewSelect().
+		Model((*schemas.RatingOptIn)(nil)).
+		Where("discord_id = ? AND metric = ?", discordID, metric).
+		Exists(context.Background())
+}
+
+// SetRating records the voter's rating of a user, replacing their previous one. updated reports whether there was one.
+func SetRating(voterDiscordID string, discordID int64, metric RatingMetric, value int32) (updated bool, err error) {
+	if err = ValidateRating(value); err != nil {
+		return
+	}
+
+	if fmt.Sprint(discordID) == voterDiscordID {
+		return false, ErrCannotRateSelf
+	}
+
+	if metric.OptIn {
+		optedIn, err := hasOptedInToMetric(fmt.Sprint(discordID), metric.Key)
+		if err != nil {
+			return false, err
+		}
+		if !optedIn {
+			return false, ErrMetricNotOptedIn
+		}
+	}
+
+	// xmax is only set on rows that were updated by the upsert
+	err = database.DB.NewInsert().
+		Model(&schemas.StupitStat{
+			ReviewedDiscordID: discordID,
+			ReviewerDiscordID: voterDiscordID,
+			StupidityValue:    value,
+			Metric:            metric.Key,
+		}).
+		On("CONFLICT (reviewed_discord_id, reviewer_discord_id, metric) DO UPDATE").
+		Set("stupidity_value = EXCLUDED.stupidity_value").
+		Returning("xmax <> 0").
+		Scan(context.Background(), &updated)
+	return
+}
+
+func DeleteRating(voterDiscordID string, discordID int64, metric RatingMetric) error {
+	res, err := database.DB.NewDelete().
+		Model((*schemas.StupitStat)(nil)).
+		Where("reviewed_discord_id = ? AND reviewer_discord_id = ? AND metric = ?", discordID, voterDiscordID, metric.Key).
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := res.RowsAffected(); deleted == 0 {
+		return ErrRatingVoteMissing
+	}
+	return nil
+}
+
+// SetMetricOptIn allows or forbids rating the user on an opt-in metric. Opting out removes every vote on it.
+func SetMetricOptIn(discordID string, metric RatingMetric, optIn bool) error {
+	if !metric.OptIn {
+		return ValidationError("metric_not_opt_in", "Everyone can be rated on this metric")
+	}
+
+	if optIn {
+		_, err := database.DB.NewInsert().
+			Model(&schemas.RatingOptIn{DiscordID: discordID, Metric: metric.Key}).
+			On("CONFLICT DO NOTHING").
+			Exec(context.Background())
+		return err
+	}
+
+	return database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().
+			Model((*schemas.RatingOptIn)(nil)).
+			Where("discord_id = ? AND metric = ?", discordID, metric.Key).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*schemas.StupitStat)(nil)).
+			Where("reviewed_discord_id = ? AND metric = ?", discordID, metric.Key).
+			Exec(ctx)
+		return err
+	})
+}
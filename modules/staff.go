@@ -0,0 +1,91 @@
+package modules
+
+import (
+	"context"
+	"server-go/database"
+	"server-go/database/schemas"
+)
+
+func getPlatformUser(platform string, accountID string) (*schemas.URUser, error) {
+	user, err := GetDBUserViaPlatformID(platform, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	// GetDBUserViaPlatformID doesn't load the ban
+	banned, err := GetDBUserViaID(user.ID)
+	return &banned, err
+}
+
+// UnbanUserAs lifts the ban of an account, taking back the warning the ban gave it
+func UnbanUserAs(actor *schemas.URUser, platform string, accountID string) error {
+	if !actor.Can(schemas.CapabilityBanUsers) {
+		return ForbiddenError("not_admin", "You are not allowed to unban users")
+	}
+
+	user, err := getPlatformUser(platform, accountID)
+	if err != nil {
+		return err
+	}
+	if !user.IsBanned() {
+		return ConflictError("not_banned", "This user is not banned")
+	}
+
+	return unbanUser(user.ID)
+}
+
+func unbanUser(userID int32) error {
+	_, err := database.DB.NewUpdate().
+		Model(&schemas.URUser{}).
+		Set("type = 0").
+		Set("ban_id = NULL").
+		Set("warning_count = GREATEST(0, warning_count - 1)").
+		Where("id = ?", userID).
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+	InvalidateBadgeCache()
+
+	return SendNotification(&schemas.Notification{
+		UserID:  userID,
+		Title:   "ReviewDB",
+		Type:    schemas.NotificationTypeUnban,
+		Content: "You have been unbanned from ReviewDB",
+	})
+}
+
+// WarnUserAs sends a warning notification, warnings don't count towards a permanent ban like bans do
+func WarnUserAs(actor *schemas.URUser, platform string, accountID string, reason string) error {
+	if !actor.Can(schemas.CapabilityBanUsers) {
+		return ForbiddenError("not_admin", "You are not allowed to warn users")
+	}
+
+	user, err := getPlatformUser(platform, accountID)
+	if err != nil {
+		return err
+	}
+
+	return SendNotification(&schemas.Notification{
+		UserID:  user.ID,
+		Title:   "You have been warned by a ReviewDB moderator",
+		Type:    schemas.NotificationTypeWarning,
+		Content: "**Reason:** " + reason + "\n\nContinued offenses will result in a ban.",
+	})
+}
+
+// AddManualOptOut opts out a discord account that isn't registered to ReviewDB, registering removes it again
+func AddManualOptOut(discordID string, reason string) error {
+	_, err := database.DB.NewInsert().
+		Model(&schemas.ManualOptOut{
+			DiscordID: discordID,
+			Reason:    reason,
+		}).
+		On("CONFLICT (discord_id) DO UPDATE").
+		Set("reason = EXCLUDED.reason").
+		Exec(context.Background())
+	return err
+}
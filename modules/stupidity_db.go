@@ -1,15 +1,11 @@
 package modules
 
 import (
-	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"log"
 
 	"server-go/common"
-	"server-go/database"
 	"server-go/database/schemas"
-	discord_utils "server-go/modules/discord"
 )
 
 type SDB_RequestData struct {
@@ -24,125 +20,59 @@ func CalculateHash(token string) string {
 	return hex.EncodeToString(checksum[:])
 }
 
-func AddStupidityDBUser(code string) (string, error) {
-	discordToken, err := discord_utils.ExchangeCode(code, common.Config.Origin+"/auth")
-	if err != nil {
-		return "", err
-	}
-
-	discordUser, err := discord_utils.GetUser(discordToken.AccessToken)
-	if err != nil {
-		return "", err
-	}
-	token := GenerateToken()
-
-	var user = &schemas.UserInfo{DiscordID: discordUser.ID.String(), Token: CalculateHash(token)}
-
-	res, err := database.DB.NewUpdate().Where("discord_id = ?", discordUser.ID).Model(user).Exec(context.Background())
-	if err != nil {
-		return "", err
-	}
-
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return "", err
+// GetRatingVoter resolves who is voting with the given ReviewDB token. The bot integration votes on behalf of
+// senderDiscordID, who may not have an account yet.
+func GetRatingVoter(token string, senderDiscordID string) (string, error) {
+	if token == "" {
+		return "", ErrUnauthorized
 	}
 
-	if rowsAffected == 0 {
-		_, err = database.DB.NewInsert().Model(user).Exec(context.Background())
+	if token == common.Config.BotIntegrationToken {
+		user, err := GetDBUserViaDiscordID(senderDiscordID)
 		if err != nil {
 			return "", err
 		}
+		if user != nil && user.IsBanned() {
+			return "", ErrBanned
+		}
+		return senderDiscordID, nil
 	}
 
-	return token, nil
-}
-
-func GetDiscordIDWithToken(token string) (string, bool) {
-	if token == "" {
-		return "", false
+	user, err := GetDBUserViaToken(token)
+	if err != nil || user.Platform != schemas.PlatformDiscord {
+		return "", ErrUnauthorized
 	}
-
-	var user schemas.UserInfo
-	err := database.DB.NewSelect().
-		Model(&user).
-		Where("token = ? OR token = ?", CalculateHash(token), token).
-		Scan(context.Background())
-	if err != nil {
-		return "", false
+	if user.IsBanned() {
+		return "", ErrBanned
 	}
-	return user.DiscordID, true
+	return user.DiscordID, nil
 }
 
-func VoteStupidity(discordID int64, token string, stupidity int32, senderDiscordID string) string {
-	var senderID string
-	if token == common.Config.BotIntegrationToken {
-		senderID = senderDiscordID
-	} else {
-		var ok bool
-		senderID, ok = GetDiscordIDWithToken(token)
-		if !ok {
-			return "Unauthorized"
-		}
-	}
-
-	stupit := &schemas.StupitStat{ReviewedDiscordID: discordID, StupidityValue: stupidity, ReviewerDiscordID: senderID}
-
-	res, err := database.DB.
-		NewUpdate().
-		Where("reviewed_discord_id = ?", discordID).
-		Where("reviewer_discord_id = ?", senderID).
-		Model(stupit).
-		Exec(context.Background())
-	if err != nil {
-		log.Println(err)
-		return "An error occurred"
-	}
-	rowsAffected, err := res.RowsAffected()
+func VoteStupidity(discordID int64, token string, stupidity int32, senderDiscordID string) (string, error) {
+	senderID, err := GetRatingVoter(token, senderDiscordID)
 	if err != nil {
-		return "An error occurred"
-	}
-	if rowsAffected != 0 {
-		return "Updated Your Vote"
+		return "", err
 	}
 
-	_, err = database.DB.NewInsert().Model(stupit).Exec(context.Background())
+	metric, _ := GetRatingMetric(RatingMetricStupidity)
+	updated, err := SetRating(senderID, discordID, metric, stupidity)
 	if err != nil {
-		return "An Error Occurred"
+		return "", err
 	}
 
-	return "Successfully voted"
+	if updated {
+		return "Updated Your Vote", nil
+	}
+	return "Successfully voted", nil
 }
 
+// GetStupidity returns the average stupidity of the user rounded down, -1 if nobody voted
 func GetStupidity(discordID int64) (int, error) {
-	// check if user has votes
-	exists, err := database.DB.
-		NewSelect().
-		Where("reviewed_discord_id = ?", discordID).
-		Model((*schemas.StupitStat)(nil)).
-		Exists(context.Background())
-	if err != nil || !exists {
-		return -1, err
-	}
-
-	rows, err := database.DB.Query("SELECT AVG(stupidity_value) FROM stupidity_reviews WHERE reviewed_discord_id = ?", discordID)
-	defer func() {
-		err := rows.Close()
-		if err != nil {
-			print("Failed to release Rows connection this may be bad")
-		}
-	}()
-
-	if err != nil {
-		return -1, err
-	}
-
-	var stupidity float64 = -1
-	err = database.DB.ScanRows(context.Background(), rows, &stupidity)
-
-	if err != nil {
+	metric, _ := GetRatingMetric(RatingMetricStupidity)
+	summary, err := GetRatingSummary(discordID, metric, "")
+	if err != nil || summary.Average == nil {
 		return -1, err
 	}
 
-	return int(stupidity), nil
+	return int(*summary.Average), nil
 }
@@ -0,0 +1,222 @@
+package translation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"server-go/common"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// translations give up after this long, reports are sent without one
+const translateTimeout = 5 * time.Second
+
+// Result is an english translation of a text. Text is empty when the text already is english or the
+// language couldn't be detected confidently.
+type Result struct {
+	Text       string `json:"text,omitempty"`
+	SourceLang string `json:"sourceLang,omitempty"`
+}
+
+// Translated tells if there is a translation to show
+func (result Result) Translated() bool {
+	return result.Text != ""
+}
+
+type Translator interface {
+	Translate(ctx context.Context, text string) (Result, error)
+}
+
+var client = &http.Client{Timeout: translateTimeout}
+
+// defaultTranslator is configured with translation_provider, "google" when it is empty
+var (
+	defaultTranslator     Translator = Noop{}
+	defaultTranslatorLock sync.RWMutex
+)
+
+func init() {
+	Reload()
+}
+
+// Reload rebuilds the default translator from the config, it has to be called whenever the config is reloaded
+func Reload() {
+	translator := Cached(New(), 24*time.Hour)
+
+	defaultTranslatorLock.Lock()
+	defaultTranslator = translator
+	defaultTranslatorLock.Unlock()
+}
+
+func New() Translator {
+	switch common.Config.TranslationProvider {
+	case "", "google":
+		return Google{}
+	case "libretranslate":
+		return LibreTranslate{URL: common.Config.LibreTranslateURL, APIKey: common.Config.LibreTranslateAPIKey}
+	case "none":
+		return Noop{}
+	}
+
+	fmt.Println("unknown translation provider", common.Config.TranslationProvider+", translations are disabled")
+	return Noop{}
+}
+
+// Translate translates text to english with the default translator
+func Translate(text string) (Result, error) {
+	defaultTranslatorLock.RLock()
+	translator := defaultTranslator
+	defaultTranslatorLock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), translateTimeout)
+	defer cancel()
+	return translator.Translate(ctx, text)
+}
+
+// Google uses the free endpoint of the google translate website, it has no api key and no guarantees
+type Google struct{}
+
+func (Google) Translate(ctx context.Context, text string) (result Result, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://translate.googleapis.com/translate_a/single?client=gtx&sl=auto&tl=en&dt=t&dj=1&source=input&q="+url.QueryEscape(text), nil)
+	if err != nil {
+		return
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("google translate answered with %d", res.StatusCode)
+	}
+
+	var trans common.Translate
+	if err = json.NewDecoder(res.Body).Decode(&trans); err != nil {
+		return
+	}
+
+	if trans.Src == "en" || trans.Confidence <= 0.3 {
+		return Result{SourceLang: trans.Src}, nil
+	}
+
+	var translated strings.Builder
+	for _, sentence := range trans.Sentences {
+		translated.WriteString(sentence.Trans + "\n")
+	}
+	return Result{Text: translated.String(), SourceLang: trans.Src}, nil
+}
+
+// LibreTranslate uses a LibreTranslate instance, like a self-hosted one
+type LibreTranslate struct {
+	URL    string
+	APIKey string
+}
+
+func (libre LibreTranslate) Translate(ctx context.Context, text string) (result Result, err error) {
+	if libre.URL == "" {
+		return result, fmt.Errorf("libretranslate_url is not configured")
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"q":       text,
+		"source":  "auto",
+		"target":  "en",
+		"format":  "text",
+		"api_key": libre.APIKey,
+	})
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(libre.URL, "/")+"/translate", bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("libretranslate answered with %d", res.StatusCode)
+	}
+
+	var translated struct {
+		TranslatedText   string `json:"translatedText"`
+		DetectedLanguage struct {
+			Language   string  `json:"language"`
+			Confidence float64 `json:"confidence"` // 0 to 100
+		} `json:"detectedLanguage"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&translated); err != nil {
+		return
+	}
+
+	result.SourceLang = translated.DetectedLanguage.Language
+	if result.SourceLang != "en" && translated.DetectedLanguage.Confidence > 30 {
+		result.Text = translated.TranslatedText
+	}
+	return
+}
+
+// Noop never translates
+type Noop struct{}
+
+func (Noop) Translate(ctx context.Context, text string) (Result, error) {
+	return Result{}, nil
+}
+
+// Fake answers with fixed translations, texts it doesn't know are treated as english
+type Fake struct {
+	Translations map[string]Result
+	Err          error
+	Calls        int
+}
+
+func (fake *Fake) Translate(ctx context.Context, text string) (Result, error) {
+	fake.Calls++
+	if fake.Err != nil {
+		return Result{}, fake.Err
+	}
+	if result, ok := fake.Translations[text]; ok {
+		return result, nil
+	}
+	return Result{SourceLang: "en"}, nil
+}
+
+type cached struct {
+	translator Translator
+	cache      *cache.Cache
+}
+
+// Cached remembers the results of translator by the hash of the text, errors are not cached
+func Cached(translator Translator, ttl time.Duration) Translator {
+	return &cached{translator: translator, cache: cache.New(ttl, ttl/2)}
+}
+
+func (c *cached) Translate(ctx context.Context, text string) (Result, error) {
+	hash := sha256.Sum256([]byte(text))
+	key := hex.EncodeToString(hash[:])
+
+	if result, ok := c.cache.Get(key); ok {
+		return result.(Result), nil
+	}
+
+	result, err := c.translator.Translate(ctx, text)
+	if err == nil {
+		c.cache.SetDefault(key, result)
+	}
+	return result, err
+}
@@ -0,0 +1,115 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server-go/common"
+	"testing"
+	"time"
+)
+
+func TestLibreTranslate(t *testing.T) {
+	var request map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/translate" {
+			t.Errorf("request to %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Write([]byte(`{"translatedText":"hello","detectedLanguage":{"language":"tr","confidence":92}}`))
+	}))
+	defer server.Close()
+
+	result, err := LibreTranslate{URL: server.URL + "/", APIKey: "key"}.Translate(context.Background(), "merhaba")
+	if err != nil || result != (Result{Text: "hello", SourceLang: "tr"}) || !result.Translated() {
+		t.Fatalf("Translate = %+v, %v", result, err)
+	}
+	if request["q"] != "merhaba" || request["target"] != "en" || request["api_key"] != "key" {
+		t.Errorf("unexpected request %v", request)
+	}
+}
+
+func TestLibreTranslateSkipsEnglish(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"translatedText":"hello","detectedLanguage":{"language":"en","confidence":99}}`))
+	}))
+	defer server.Close()
+
+	result, err := LibreTranslate{URL: server.URL}.Translate(context.Background(), "hello")
+	if err != nil || result.Translated() || result.SourceLang != "en" {
+		t.Fatalf("Translate = %+v, %v, want no translation", result, err)
+	}
+}
+
+func TestReloadUsesTheNewProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"translatedText":"hello","detectedLanguage":{"language":"tr","confidence":92}}`))
+	}))
+	defer server.Close()
+
+	config := *common.Config
+	defer func() {
+		*common.Config = config
+		Reload()
+	}()
+
+	common.Config.TranslationProvider = "libretranslate"
+	common.Config.LibreTranslateURL = server.URL
+	Reload()
+
+	result, err := Translate("merhaba")
+	if err != nil || result.Text != "hello" {
+		t.Fatalf("Translate = %+v, %v, want the libretranslate translation", result, err)
+	}
+}
+
+func TestTranslateGivesUpAfterTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := (LibreTranslate{URL: server.URL}).Translate(ctx, "merhaba"); err == nil {
+		t.Fatal("Translate succeeded without an answer")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("Translate took %v", time.Since(start))
+	}
+}
+
+func TestCachedTranslatesOnce(t *testing.T) {
+	fake := &Fake{Translations: map[string]Result{"merhaba": {Text: "hello", SourceLang: "tr"}}}
+	translator := Cached(fake, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if result, err := translator.Translate(context.Background(), "merhaba"); err != nil || result.Text != "hello" {
+			t.Fatalf("Translate = %+v, %v", result, err)
+		}
+	}
+	translator.Translate(context.Background(), "hello")
+
+	if fake.Calls != 2 {
+		t.Errorf("translator was called %d times, want 2", fake.Calls)
+	}
+}
+
+func TestCachedDoesNotCacheErrors(t *testing.T) {
+	fake := &Fake{Err: errors.New("unavailable")}
+	translator := Cached(fake, time.Minute)
+
+	translator.Translate(context.Background(), "merhaba")
+	fake.Err = nil
+	translator.Translate(context.Background(), "merhaba")
+
+	if fake.Calls != 2 {
+		t.Errorf("translator was called %d times, want 2", fake.Calls)
+	}
+}
@@ -0,0 +1,103 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server-go/common"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Client is the part of the Twitter API ReviewDB uses, APIClient talks to the real one
+type Client interface {
+	// RefreshToken trades a refresh token for a new token, twitter rotates refresh tokens so the returned one has to be stored
+	RefreshToken(ctx context.Context, refreshToken string) (*oauth2.Token, error)
+	FetchUser(ctx context.Context, accessToken string) (*TwitterUser, error)
+}
+
+type APIClient struct {
+	Endpoint     string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	HTTPClient   *http.Client
+}
+
+var _ Client = (*APIClient)(nil)
+
+// NewAPIClient creates a client for the configured twitter app
+func NewAPIClient() *APIClient {
+	return &APIClient{
+		Endpoint:     common.Config.Twitter.ApiEndpoint,
+		ClientID:     common.Config.Twitter.ClientID,
+		ClientSecret: common.Config.Twitter.ClientSecret,
+		RedirectURL:  "https://manti.vendicated.dev/api/reviewdb-twitter/auth",
+		HTTPClient:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (c *APIClient) oauthConfig() *oauth2.Config {
+	return &oauth2.Config{
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   c.Endpoint + "/oauth2/authorize",
+			TokenURL:  c.Endpoint + "/oauth2/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		RedirectURL:  c.RedirectURL,
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+	}
+}
+
+// oauthContext makes the oauth2 package use our http client
+func (c *APIClient) oauthContext(ctx context.Context) context.Context {
+	if c.HTTPClient == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, c.HTTPClient)
+}
+
+func (c *APIClient) ExchangeCode(ctx context.Context, code string) (*oauth2.Token, error) {
+	return c.oauthConfig().Exchange(c.oauthContext(ctx), code, oauth2.SetAuthURLParam("grant_type", "authorization_code"), oauth2.SetAuthURLParam("code_verifier", "challenge"))
+}
+
+func (c *APIClient) RefreshToken(ctx context.Context, refreshToken string) (*oauth2.Token, error) {
+	// an expired token makes the token source refresh right away
+	expired := &oauth2.Token{RefreshToken: refreshToken, Expiry: time.Unix(1, 0)}
+	return c.oauthConfig().TokenSource(c.oauthContext(ctx), expired).Token()
+}
+
+func (c *APIClient) FetchUser(ctx context.Context, accessToken string) (*TwitterUser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint+"/users/me?user.fields=id%2Cname%2Cusername%2Cprofile_image_url", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+accessToken)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("twitter returned status %d", resp.StatusCode)
+	}
+
+	user := &TwitterUser{}
+	if err = json.NewDecoder(resp.Body).Decode(user); err != nil {
+		return nil, err
+	}
+	if user.Data.ID == "" {
+		return nil, fmt.Errorf("twitter returned no user")
+	}
+	return user, nil
+}
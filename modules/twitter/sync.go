@@ -0,0 +1,187 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"server-go/database"
+	"server-go/database/schemas"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// tokens are refreshed this long before they expire
+const tokenRefreshMargin = 10 * time.Minute
+
+// ErrGrantRevoked means the user revoked ReviewDB's access on twitter, their tokens are useless from then on
+var ErrGrantRevoked = errors.New("Twitter access was revoked")
+
+type SyncResult struct {
+	Updated   int
+	Refreshed int
+	Revoked   int
+	Failed    int
+}
+
+// SyncUser refreshes the user's access token if it is about to expire and copies their current twitter profile
+// onto the user. refreshed reports whether the tokens changed.
+func SyncUser(ctx context.Context, client Client, user *schemas.URUser) (refreshed bool, err error) {
+	if user.AccessToken == "" || time.Until(user.AccessTokenExpiry) < tokenRefreshMargin {
+		if user.RefreshToken == "" {
+			return false, ErrGrantRevoked
+		}
+
+		token, err := client.RefreshToken(ctx, user.RefreshToken)
+		if err != nil {
+			// rate limits and other failures are retried on the next sync with the same tokens
+			if grantRevoked(err) {
+				user.AccessToken = ""
+				user.RefreshToken = ""
+				return true, ErrGrantRevoked
+			}
+			return false, err
+		}
+
+		user.AccessToken = token.AccessToken
+		if token.RefreshToken != "" {
+			user.RefreshToken = token.RefreshToken
+		}
+		user.AccessTokenExpiry = token.Expiry
+		refreshed = true
+	}
+
+	profile, err := client.FetchUser(ctx, user.AccessToken)
+	if err != nil {
+		return refreshed, err
+	}
+
+	if profile.Data.ID != user.DiscordID {
+		return refreshed, fmt.Errorf("token of %s belongs to %s", user.DiscordID, profile.Data.ID)
+	}
+
+	user.Username = profile.Data.Username
+	user.DisplayName = profile.Data.Name
+	user.AvatarURL = profile.Data.AvatarURL
+	return refreshed, nil
+}
+
+// grantRevoked tells if twitter refused to refresh the token because the grant is gone
+func grantRevoked(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) || retrieveErr.Response == nil || retrieveErr.ErrorCode != "invalid_grant" {
+		return false
+	}
+	status := retrieveErr.Response.StatusCode
+	return status == http.StatusBadRequest || status == http.StatusUnauthorized
+}
+
+// SyncUsers syncs the users with at most concurrency requests to twitter at once. save is called with the columns
+// to write back for every user whose row changed, including ones whose grant was revoked or whose tokens were
+// refreshed before fetching their profile failed.
+func SyncUsers(ctx context.Context, client Client, users []schemas.URUser, concurrency int, save func(user *schemas.URUser, columns []string) error) SyncResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		result SyncResult
+		lock   sync.Mutex
+		wg     sync.WaitGroup
+	)
+	slots := make(chan struct{}, concurrency)
+
+	for i := range users {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(user *schemas.URUser) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			refreshed, err := SyncUser(ctx, client, user)
+			if err != nil && !refreshed {
+				fmt.Printf("failed to sync twitter user %s: %s\n", user.DiscordID, err)
+				lock.Lock()
+				result.Failed++
+				lock.Unlock()
+				return
+			}
+
+			// twitter rotates refresh tokens, the stored one is useless once a refresh succeeded so the new
+			// tokens are saved even if the profile couldn't be fetched
+			columns := syncedColumns
+			if err != nil {
+				columns = tokenColumns
+			}
+			saveErr := save(user, columns)
+
+			lock.Lock()
+			defer lock.Unlock()
+			switch {
+			case saveErr != nil:
+				fmt.Printf("failed to save twitter user %s: %s\n", user.DiscordID, saveErr)
+				result.Failed++
+			case errors.Is(err, ErrGrantRevoked):
+				result.Revoked++
+			case err != nil:
+				fmt.Printf("failed to sync twitter user %s: %s\n", user.DiscordID, err)
+				result.Failed++
+			default:
+				result.Updated++
+				if refreshed {
+					result.Refreshed++
+				}
+			}
+		}(&users[i])
+	}
+
+	wg.Wait()
+	return result
+}
+
+var (
+	tokenColumns  = []string{"access_token", "refresh_token", "access_token_expiry"}
+	syncedColumns = append([]string{"username", "display_name", "avatar_url"}, tokenColumns...)
+)
+
+func saveSyncedUser(user *schemas.URUser, columns []string) error {
+	_, err := database.DB.NewUpdate().
+		Model(user).
+		Column(columns...).
+		WherePK().
+		Exec(context.Background())
+	return err
+}
+
+// SyncAllUsers syncs every twitter user that still has a refresh token, batchSize users at a time
+func SyncAllUsers(client Client, batchSize int, concurrency int) (total SyncResult, err error) {
+	var lastID int32
+	for {
+		users := []schemas.URUser{}
+		err = database.DB.NewSelect().
+			Model(&users).
+			Where("platform = ?", schemas.PlatformTwitter).
+			Where("refresh_token IS NOT NULL AND refresh_token != ''").
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Scan(context.Background())
+		if err != nil || len(users) == 0 {
+			return
+		}
+		lastID = users[len(users)-1].ID
+
+		result := SyncUsers(context.Background(), client, users, concurrency, saveSyncedUser)
+		total.Updated += result.Updated
+		total.Refreshed += result.Refreshed
+		total.Revoked += result.Revoked
+		total.Failed += result.Failed
+
+		fmt.Printf("synced twitter users up to %d: %+v\n", lastID, result)
+	}
+}
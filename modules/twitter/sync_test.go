@@ -0,0 +1,254 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"server-go/database/schemas"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeTwitter serves the token and users/me endpoints, every access token is "access-<refresh token>"
+func fakeTwitter(t *testing.T, profiles map[string]string) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "refresh_token" {
+			t.Errorf("unexpected token request: %v", r.Form)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		refreshToken := r.Form.Get("refresh_token")
+		switch refreshToken {
+		case "revoked":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant","error_description":"Value passed for the token was invalid."}`))
+			return
+		case "rate-limited":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"title":"Too Many Requests","status":429}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"token_type":    "bearer",
+			"access_token":  "access-" + refreshToken,
+			"refresh_token": refreshToken + "-rotated",
+			"expires_in":    7200,
+		})
+	})
+
+	mux.HandleFunc("/users/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer access-busy" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		id, ok := profiles[r.Header.Get("Authorization")]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		user := TwitterUser{}
+		user.Data.ID = id
+		user.Data.Username = "user" + id
+		user.Data.Name = "User " + id
+		user.Data.AvatarURL = "https://pbs.twimg.com/" + id + ".jpg"
+		json.NewEncoder(w).Encode(user)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testClient(server *httptest.Server) *APIClient {
+	return &APIClient{Endpoint: server.URL, ClientID: "client", HTTPClient: server.Client()}
+}
+
+func TestSyncUserRefreshesExpiredToken(t *testing.T) {
+	server := fakeTwitter(t, map[string]string{"Bearer access-refresh1": "1"})
+
+	user := &schemas.URUser{
+		DiscordID:         "1",
+		Username:          "old",
+		AccessToken:       "stale",
+		RefreshToken:      "refresh1",
+		AccessTokenExpiry: time.Now().Add(time.Minute),
+	}
+
+	refreshed, err := SyncUser(context.Background(), testClient(server), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refreshed {
+		t.Fatal("token about to expire was not refreshed")
+	}
+	if user.AccessToken != "access-refresh1" || user.RefreshToken != "refresh1-rotated" {
+		t.Fatalf("tokens = %q %q", user.AccessToken, user.RefreshToken)
+	}
+	if time.Until(user.AccessTokenExpiry) < time.Hour {
+		t.Fatalf("expiry = %s, want about two hours from now", user.AccessTokenExpiry)
+	}
+	if user.Username != "user1" || user.DisplayName != "User 1" || user.AvatarURL != "https://pbs.twimg.com/1.jpg" {
+		t.Fatalf("profile was not updated: %+v", user)
+	}
+}
+
+func TestSyncUserKeepsValidToken(t *testing.T) {
+	server := fakeTwitter(t, map[string]string{"Bearer valid": "2"})
+
+	user := &schemas.URUser{
+		DiscordID:         "2",
+		AccessToken:       "valid",
+		RefreshToken:      "refresh2",
+		AccessTokenExpiry: time.Now().Add(time.Hour),
+	}
+
+	refreshed, err := SyncUser(context.Background(), testClient(server), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed || user.RefreshToken != "refresh2" {
+		t.Fatal("valid token was refreshed")
+	}
+	if user.Username != "user2" {
+		t.Fatalf("username = %q, want user2", user.Username)
+	}
+}
+
+func TestSyncUserClearsRevokedGrant(t *testing.T) {
+	server := fakeTwitter(t, nil)
+
+	user := &schemas.URUser{DiscordID: "3", RefreshToken: "revoked"}
+
+	refreshed, err := SyncUser(context.Background(), testClient(server), user)
+	if !errors.Is(err, ErrGrantRevoked) {
+		t.Fatalf("err = %v, want ErrGrantRevoked", err)
+	}
+	if !refreshed || user.RefreshToken != "" || user.AccessToken != "" {
+		t.Fatalf("revoked tokens were not cleared: %+v", user)
+	}
+}
+
+func TestSyncUserKeepsTokensWhenRateLimited(t *testing.T) {
+	server := fakeTwitter(t, nil)
+
+	user := &schemas.URUser{DiscordID: "6", AccessToken: "stale", RefreshToken: "rate-limited"}
+
+	refreshed, err := SyncUser(context.Background(), testClient(server), user)
+	if err == nil || errors.Is(err, ErrGrantRevoked) {
+		t.Fatalf("err = %v, want a retryable error", err)
+	}
+	if refreshed || user.RefreshToken != "rate-limited" || user.AccessToken != "stale" {
+		t.Fatalf("tokens of a rate limited user were changed: %+v", user)
+	}
+
+	saved := false
+	result := SyncUsers(context.Background(), testClient(server), []schemas.URUser{*user}, 1, func(user *schemas.URUser, columns []string) error {
+		saved = true
+		return nil
+	})
+	if saved || result.Failed != 1 || result.Revoked != 0 {
+		t.Fatalf("rate limited user was saved or counted as revoked: %+v", result)
+	}
+}
+
+func TestSyncUsersSavesRotatedTokensWhenProfileFails(t *testing.T) {
+	server := fakeTwitter(t, nil)
+
+	users := []schemas.URUser{{DiscordID: "7", RefreshToken: "busy"}}
+
+	var savedColumns []string
+	result := SyncUsers(context.Background(), testClient(server), users, 1, func(user *schemas.URUser, columns []string) error {
+		savedColumns = columns
+		return nil
+	})
+
+	if result.Failed != 1 || result.Updated != 0 {
+		t.Fatalf("result = %+v, want the user to count as failed", result)
+	}
+	if users[0].RefreshToken != "busy-rotated" || users[0].AccessToken != "access-busy" {
+		t.Fatalf("tokens = %q %q, want the rotated ones", users[0].AccessToken, users[0].RefreshToken)
+	}
+	if !slices.Equal(savedColumns, tokenColumns) {
+		t.Fatalf("saved %v, want only the token columns", savedColumns)
+	}
+}
+
+func TestSyncUserRejectsTokenOfOtherAccount(t *testing.T) {
+	server := fakeTwitter(t, map[string]string{"Bearer valid": "5"})
+
+	user := &schemas.URUser{DiscordID: "4", AccessToken: "valid", AccessTokenExpiry: time.Now().Add(time.Hour)}
+
+	if _, err := SyncUser(context.Background(), testClient(server), user); err == nil {
+		t.Fatal("profile of another account was accepted")
+	}
+}
+
+// slowClient counts how many requests are in flight at once
+type slowClient struct {
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (c *slowClient) RefreshToken(ctx context.Context, refreshToken string) (*oauth2.Token, error) {
+	return nil, errors.New("unexpected refresh")
+}
+
+func (c *slowClient) FetchUser(ctx context.Context, accessToken string) (*TwitterUser, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		max := c.maxInFlight.Load()
+		if n <= max || c.maxInFlight.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	user := &TwitterUser{}
+	user.Data.ID = accessToken
+	return user, nil
+}
+
+func TestSyncUsersBoundsConcurrency(t *testing.T) {
+	client := &slowClient{}
+
+	users := make([]schemas.URUser, 20)
+	for i := range users {
+		id := string(rune('a' + i))
+		users[i] = schemas.URUser{DiscordID: id, AccessToken: id, AccessTokenExpiry: time.Now().Add(time.Hour)}
+	}
+
+	var saved sync.Map
+	result := SyncUsers(context.Background(), client, users, 3, func(user *schemas.URUser, columns []string) error {
+		saved.Store(user.DiscordID, true)
+		return nil
+	})
+
+	if result.Updated != len(users) || result.Failed != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+	if max := client.maxInFlight.Load(); max > 3 {
+		t.Fatalf("%d requests were in flight at once, want at most 3", max)
+	}
+	for _, user := range users {
+		if _, ok := saved.Load(user.DiscordID); !ok {
+			t.Fatalf("user %s was not saved", user.DiscordID)
+		}
+	}
+}
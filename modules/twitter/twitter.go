@@ -8,69 +8,82 @@ import (
 	"server-go/database"
 	"server-go/database/schemas"
 	"server-go/modules"
+	"strconv"
 
 	discord_utils "server-go/modules/discord"
 
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/patrickmn/go-cache"
+	"github.com/uptrace/bun"
 )
 
-func GetReview(id int32) (*schemas.TwitterUserReview, error) {
-	var review schemas.TwitterUserReview
-	err := database.DB.NewSelect().Model(&review).Where("id = ?", id).Scan(context.Background())
+// ToTwitterReview converts a review of the shared review core to the shape ReviewDB Twitter clients expect
+func ToTwitterReview(review schemas.UserReview) schemas.TwitterReview {
+	twitterReview := schemas.TwitterReview{
+		ID:        review.ID,
+		Comment:   review.Comment,
+		Type:      review.Type,
+		Timestamp: review.Timestamp,
+		Score:     review.Score,
+		Edited:    review.Edited,
+		EditedAt:  review.EditedAt,
+		Replies:   []schemas.TwitterReview{},
+	}
+
+	if review.User != nil {
+		twitterReview.Sender = schemas.TwitterSender{
+			ID:          review.User.ID,
+			TwitterID:   review.User.DiscordID,
+			Username:    review.User.Username,
+			DisplayName: review.User.DisplayName,
+			AvatarURL:   review.User.AvatarURL,
+			Badges:      GetBadgesOfUser(review.User.DiscordID),
+		}
+	}
 
-	if err != nil {
-		return nil, err
+	for _, reply := range review.Replies {
+		twitterReview.Replies = append(twitterReview.Replies, ToTwitterReview(reply))
 	}
 
-	return &review, nil
+	return twitterReview
 }
 
-func GetTwitterReviews(userID string, offset int) ([]schemas.TwitterUserReview, int, error) {
-	var reviews []schemas.TwitterUserReview
-
-	count, err := database.DB.NewSelect().
-		Model(&reviews).
-		Relation("User").
-		Where("profile_id = ?", userID).
-		OrderExpr("id desc").Limit(51).
-		Offset(offset).
-		ScanAndCount(context.Background(), &reviews)
+func GetTwitterReviews(requester *schemas.URUser, profileID string, offset int) ([]schemas.TwitterReview, int, error) {
+	userID, err := strconv.ParseInt(profileID, 10, 64)
+	if err != nil {
+		return nil, 0, modules.ValidationError("invalid_profile_id", "Invalid profile id")
+	}
 
+	reviews, count, err := modules.GetReviewsWithOptions(requester, userID, offset, modules.GetReviewsOptions{
+		Limit:    51,
+		Platform: schemas.PlatformTwitter,
+	})
 	if err != nil {
 		return nil, 0, err
 	}
 
-	for i, review := range reviews {
-		badges := GetBadgesOfUser(review.User.TwitterID)
-
-		if review.User != nil {
-			reviews[i].Sender.TwitterID = review.User.TwitterID
-			reviews[i].Sender.AvatarURL = review.User.AvatarURL
-			reviews[i].Sender.Username = review.User.Username
-			reviews[i].Sender.DisplayName = review.User.DisplayName
-			reviews[i].Sender.ID = review.User.ID
-			reviews[i].Sender.Badges = badges
-		}
-		reviews[i].Timestamp = review.TimestampStr.Unix()
+	twitterReviews := make([]schemas.TwitterReview, 0, len(reviews))
+	for _, review := range reviews {
+		twitterReviews = append(twitterReviews, ToTwitterReview(review))
 	}
 
-	return reviews, count, nil
+	return twitterReviews, count, nil
 }
 
-func GetBadgesOfUser(twitterID string) []schemas.TwitterUserBadge {
-	userBadges := []schemas.TwitterUserBadge{}
+// GetBadgesOfUser returns the system badges of the twitter account followed by the ones given to it by hand
+func GetBadgesOfUser(twitterID string) []schemas.UserBadge {
+	userBadges := append([]schemas.UserBadge{}, modules.GetPlatformBadgesOfUser(schemas.PlatformTwitter, twitterID)...)
 
 	badges, _ := GetAllBadges()
 	for _, badge := range badges {
-
 		if badge.TargetTwitterID == twitterID {
-			userBadges = append(userBadges, badge)
+			userBadges = append(userBadges, badge.ToUserBadge())
 		}
 	}
 	return userBadges
 }
 
+// GetAllBadges returns the badges given to twitter accounts by hand
 func GetAllBadges() (badges []schemas.TwitterUserBadge, err error) {
 
 	cachedBadges, found := common.Cache.Get("twitterBadges")
@@ -81,74 +94,32 @@ func GetAllBadges() (badges []schemas.TwitterUserBadge, err error) {
 
 	badges = []schemas.TwitterUserBadge{}
 	err = database.DB.NewSelect().Model(&badges).Scan(context.Background(), &badges)
-
-	users := []schemas.TwitterUser{}
-
-	database.DB.NewSelect().Distinct().Model(&users).Column("twitter_id", "type").Where("type = ? or type = ?", 1, -1).Scan(context.Background(), &users)
-
-	for _, user := range users {
-		if user.Type == 1 {
-			badges = append(badges, schemas.TwitterUserBadge{
-				TargetTwitterID: user.TwitterID,
-				Name:            "Admin",
-				Icon:            "https://cdn.discordapp.com/emojis/1040004306100826122.gif?size=128",
-				RedirectURL:     "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
-				Description:     "This user is an admin of ReviewDB.",
-			})
-		} else {
-			badges = append(badges, schemas.TwitterUserBadge{
-				TargetTwitterID: user.TwitterID,
-				Name:            "Banned",
-				Icon:            "https://cdn.discordapp.com/emojis/399233923898540053.gif?size=128",
-				RedirectURL:     "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
-				Description:     "This user is banned from ReviewDB.",
-			})
-		}
-
+	if err != nil {
+		return
 	}
 
 	common.Cache.Set("twitterBadges", badges, cache.DefaultExpiration)
 	return
 }
 
-func GetReviewCountInLastHour(userID string) (int, error) {
-	//return 0, nil
-	count, err := database.DB.
-		NewSelect().Table("reviewdb_twitter.reviews").
-		Where("reviewer_id = ? AND timestamp > now() - interval '1 hour'", userID).
-		Count(context.Background())
-	if err != nil {
-		return 0, err
-	}
-	return count, nil
+func GetDBUserViaTwitterID(twitterID string) (*schemas.URUser, error) {
+	return modules.GetDBUserViaPlatformID(schemas.PlatformTwitter, twitterID)
 }
 
-func GetDBUserViaTwitterID(twitterID string) (*schemas.TwitterUser, error) {
-	var user schemas.TwitterUser
-	err := database.DB.NewSelect().Model(&user).Where("twitter_id = ?", twitterID).Limit(1).Scan(context.Background())
-
+func GetDBUserViaToken(token string) (*schemas.URUser, error) {
+	user, err := modules.GetDBUserViaToken(token)
 	if err != nil {
-		if err.Error() == "sql: no rows in result set" { //SOMEONE TELL ME BETTER WAY TO DO THIS
-			return nil, nil
-		}
 		return nil, err
 	}
 
-	return &user, nil
-}
-
-func GetDBUserViaToken(token string) (*schemas.TwitterUser, error) {
-	var user schemas.TwitterUser
-	err := database.DB.NewSelect().Model(&user).Where("token = ?", token).Limit(1).Scan(context.Background())
-
-	if err != nil {
-		return nil, err
+	if user.Platform != schemas.PlatformTwitter {
+		return nil, modules.ErrInvalidToken
 	}
 
 	return &user, nil
 }
 
-func AddTwitterUser(code string, ip string) (*schemas.TwitterUser, error) {
+func AddTwitterUser(code string, ip string) (*schemas.URUser, error) {
 	twitterToken, err := ExchangeCode(code)
 	if err != nil {
 		return nil, err
@@ -160,28 +131,37 @@ func AddTwitterUser(code string, ip string) (*schemas.TwitterUser, error) {
 	}
 	token := modules.GenerateToken()
 
-	user := &schemas.TwitterUser{
-		TwitterID:    twitterUser.Data.ID,
-		Token:        token,
-		Username:     twitterUser.Data.Username,
-		DisplayName:  twitterUser.Data.Name,
-		AvatarURL:    twitterUser.Data.AvatarURL,
-		Type:         0,
-		IpHash:       modules.CalculateHash(ip),
-		RefreshToken: twitterToken.RefreshToken,
-		ExpiresAt:    twitterToken.Expiry,
+	user := &schemas.URUser{
+		DiscordID:         twitterUser.Data.ID,
+		Platform:          schemas.PlatformTwitter,
+		Token:             token,
+		Username:          twitterUser.Data.Username,
+		DisplayName:       twitterUser.Data.Name,
+		AvatarURL:         twitterUser.Data.AvatarURL,
+		Type:              0,
+		ClientMods:        []string{"reviewdb-twitter"},
+		IpHash:            modules.CalculateHash(ip),
+		AccessToken:       twitterToken.AccessToken,
+		RefreshToken:      twitterToken.RefreshToken,
+		AccessTokenExpiry: twitterToken.Expiry,
 	}
 
 	dbUser, err := GetDBUserViaTwitterID(twitterUser.Data.ID)
+	if err != nil {
+		return nil, err
+	}
 
 	if dbUser != nil {
 		if dbUser.Type == -1 {
-			return nil, errors.New("You have been banned from ReviewDB")
+			return nil, modules.ErrBanned
 		}
 
 		dbUser.Username = twitterUser.Data.Username
 		dbUser.DisplayName = twitterUser.Data.Name
 		dbUser.AvatarURL = twitterUser.Data.AvatarURL
+		dbUser.AccessToken = twitterToken.AccessToken
+		dbUser.RefreshToken = twitterToken.RefreshToken
+		dbUser.AccessTokenExpiry = twitterToken.Expiry
 
 		_, err = database.DB.NewUpdate().Where("id = ?", dbUser.ID).Model(dbUser).Exec(context.Background())
 		if err != nil {
@@ -197,7 +177,7 @@ func AddTwitterUser(code string, ip string) (*schemas.TwitterUser, error) {
 		return nil, errors.New(common.ERROR)
 	}
 
-	discord_utils.SendLoggerWebhook(discord_utils.WebhookData{
+	modules.QueueDiscordWebhook(modules.WebhookTargetLogger, discord_utils.WebhookData{
 		Username:  twitterUser.Data.Username,
 		AvatarURL: twitterUser.Data.AvatarURL,
 		Content:   fmt.Sprintf("User %s (%s) has been registered to ReviewDB Twitter", twitterUser.Data.Username, twitterUser.Data.ID),
@@ -205,152 +185,101 @@ func AddTwitterUser(code string, ip string) (*schemas.TwitterUser, error) {
 	return user, nil
 }
 
-func AddReview(user *schemas.TwitterUser, data schemas.TwitterRequestData) (response string, err error) {
-
-	if common.LightProfanityDetector.IsProfane(data.Comment) || common.ProfanityDetector.IsProfane(data.Comment) {
-		return "", errors.New("Your review contains profanity")
-	}
-
-	count, err := GetReviewCountInLastHour(user.TwitterID)
-
-	if err != nil {
-		println(err.Error())
-		return "", errors.New(common.ERROR)
-	}
-
-	if count > 20 {
-		return "", errors.New("You are reviewing too much")
-	}
-
-	review := &schemas.TwitterUserReview{
-		Comment:    data.Comment,
-		ProfileID:  data.ProfileID,
-		ReviewerID: user.TwitterID,
-	}
-
-	res, err := database.DB.NewUpdate().Where("profile_id = ? AND reviewer_id = ?", data.ProfileID, user.TwitterID).OmitZero().Model(review).Exec(context.Background())
-	if err != nil {
-		return common.UPDATE_FAILED, err
-	}
-
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return common.UPDATE_FAILED, err
-	}
-	if rowsAffected != 0 {
-		return common.UPDATED, nil
-	}
-
-	_, err = database.DB.NewInsert().Model(review).Exec(context.Background())
-	if err != nil {
-		return common.ERROR, err
-	}
-	return common.ADDED, nil
-}
-
-func DeleteReview(user *schemas.TwitterUser, reviewID int32) (err error) {
-	review, err := GetReview(reviewID)
-	if err != nil {
-		fmt.Println(err.Error())
-		return errors.New("Invalid Review ID")
-	}
-
-	if (review.ReviewerID == user.TwitterID) || user.IsAdmin() {
-		//LogAction("DELETE", review, user.ID)
-
-		_, err = database.DB.NewDelete().Model(review).Where("id = ?", reviewID).Exec(context.Background())
-		if err != nil {
-			println(err.Error())
-			return errors.New(common.ERROR)
-		}
-		return nil
-	}
-	return errors.New("You are not allowed to delete this review")
-}
-
-func ReportReview(user *schemas.TwitterUser, reviewID int32) error {
+func ReportReview(user *schemas.URUser, reviewID int32) error {
 
 	if user.IsBanned() {
-		return errors.New("You cant report reviews while banned")
+		return modules.BannedError("banned", "You cant report reviews while banned")
 	}
 
-	reportCount, _ := GetReportCountInLastHour(user.TwitterID)
+	reportCount, _ := GetReportCountInLastHour(user.ID)
 
 	if reportCount > 20 {
-		return errors.New("You are reporting too much")
+		return modules.RateLimitedError("reporting_too_much", "You are reporting too much")
 	}
 
-	count, _ := database.DB.NewSelect().Model(&schemas.TwitterReviewReport{}).Where("review_id = ? AND reporter_id = ?", reviewID, user.TwitterID).Count(context.Background())
+	count, _ := database.DB.NewSelect().Model(&schemas.TwitterReviewReport{}).Where("review_id = ? AND reporter_id = ?", reviewID, user.ID).Count(context.Background())
 	if count > 0 {
-		return errors.New("You have already reported this review")
+		return modules.ConflictError("already_reported", "You have already reported this review")
 	}
 
-	review, err := GetReview(reviewID)
-	if err != nil {
-		return errors.New("Invalid Review ID")
+	review, err := modules.GetReview(reviewID)
+	if err != nil || review.Platform != schemas.PlatformTwitter {
+		return modules.ErrInvalidReview
 	}
 
-	if review.ReviewerID == user.TwitterID {
-		return errors.New("You cant report your own review")
+	if review.ReviewerID == user.ID {
+		return modules.ForbiddenError("report_own_review", "You cant report your own review")
 	}
 
-	reportedUser, _ := GetDBUserViaTwitterID(review.ReviewerID)
-
 	report := schemas.TwitterReviewReport{
 		ReviewID:   reviewID,
-		ReporterID: user.TwitterID,
+		ReporterID: user.ID,
 	}
 
-	/*
-		reviewedUsername := "?"
-		if reviewedUser, err := ArikawaState.User(discord.UserID(review.ProfileID)); err == nil {
-			reviewedUsername = reviewedUser.Tag()
+	return database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewInsert().Model(&report).Exec(ctx)
+		if err != nil {
+			return err
 		}
+		return modules.QueueDiscordWebhookTx(tx, modules.WebhookTargetTwitterReport, ReportWebhook(user, &review))
+	})
+}
 
-				Components: []modules.WebhookComponent{
-				{
-					Type: 1,
-					Components: []modules.WebhookComponent{
-						{
-							Type:     2,
-							Label:    "Delete Review",
-							Style:    4,
-							CustomID: fmt.Sprintf("delete_review:%d", reviewID),
-							Emoji: modules.WebhookEmoji{
-								Name: "🗑️",
-							},
+func ReportWebhook(reporter *schemas.URUser, review *schemas.UserReview) discord_utils.WebhookData {
+	reportedUser := review.User
+
+	webhookData := discord_utils.WebhookData{
+		Username: "ReviewDB Twitter",
+		Content:  "Reported Review",
+		Components: []discord_utils.WebhookComponent{
+			{
+				Type: 1,
+				Components: []discord_utils.WebhookComponent{
+					{
+						Type:     2,
+						Label:    "Delete Review",
+						Style:    4,
+						CustomID: discord_utils.ActionDeleteReview.CustomID(schemas.PlatformTwitter, review.ID),
+						Emoji: discord.ComponentEmoji{
+							Name: "🗑️",
+						},
+					},
+					{
+						Type:     2,
+						Label:    "Ban User",
+						Style:    4,
+						CustomID: discord_utils.ActionBanSelect.CustomID(schemas.PlatformTwitter, reportedUser.DiscordID, review.ID),
+						Emoji: discord.ComponentEmoji{
+							Name:     "banned",
+							ID:       590237837299941382,
+							Animated: true,
 						},
-						{
-							Type:     2,
-							Label:    "Ban User",
-							Style:    4,
-							CustomID: fmt.Sprintf("ban_select:%s:%d", reportedUser.DiscordID, reviewID), //string(reportedUser.DiscordID)
-							Emoji: modules.WebhookEmoji{
-								Name:     "banned",
-								ID:       "590237837299941382",
-								Animated: true,
-							},
+					},
+					{
+						Type:     2,
+						Label:    "Delete Review and Ban User",
+						Style:    4,
+						CustomID: discord_utils.ActionSelectDeleteAndBan.CustomID(schemas.PlatformTwitter, review.ID, reportedUser.DiscordID),
+						Emoji: discord.ComponentEmoji{
+							Name:     "banned",
+							ID:       590237837299941382,
+							Animated: true,
 						},
-						{
-							Type:     2,
-							Label:    "Delete Review and Ban User",
-							Style:    4,
-							CustomID: fmt.Sprintf("select_delete_and_ban:%d:%s", reviewID, string(reportedUser.DiscordID)),
-							Emoji: modules.WebhookEmoji{
-								Name:     "banned",
-								ID:       "590237837299941382",
-								Animated: true,
-							},
+					},
+					{
+						Type:     2,
+						Label:    "Ban Reporter",
+						Style:    4,
+						CustomID: discord_utils.ActionBanSelect.CustomID(schemas.PlatformTwitter, reporter.DiscordID, 0),
+						Emoji: discord.ComponentEmoji{
+							Name:     "banned",
+							ID:       590237837299941382,
+							Animated: true,
 						},
 					},
 				},
 			},
-	*/
-
-	webhookData := discord_utils.WebhookData{
-		Username: "ReviewDB Twitter",
-		Content:  "Reported Review",
-
+		},
 		Embeds: []discord.Embed{
 			{
 				Fields: []discord.EmbedField{
@@ -364,51 +293,28 @@ func ReportReview(user *schemas.TwitterUser, reviewID int32) error {
 					},
 					{
 						Name:  "**Author**",
-						Value: formatUser(reportedUser.Username, reportedUser.TwitterID),
+						Value: formatUser(reportedUser.Username, reportedUser.DiscordID),
 					},
 					{
 						Name:  "**Reviewed User**",
-						Value: review.ProfileID,
+						Value: fmt.Sprintf("https://twitter.com/i/user/%d", review.ProfileID),
 					},
 					{
 						Name:  "**Reporter**",
-						Value: formatUser(user.Username, user.TwitterID),
+						Value: formatUser(reporter.Username, reporter.DiscordID),
 					},
 				},
 			},
 		},
 	}
 
-	/*
-		if reportedUser.TwitterID != user.TwitterID {
-			webhookData.Components[0].Components = append(webhookData.Components[0].Components, WebhookComponent{
-				Type:     2,
-				Label:    "Ban Reporter",
-				Style:    4,
-				CustomID: fmt.Sprintf("ban_select:" + user.DiscordID + ":" + "0"),
-				Emoji: WebhookEmoji{
-					Name:     "banned",
-					ID:       "590237837299941382",
-					Animated: true,
-				},
-			})
-		}
-	*/
-
-	err = discord_utils.SendWebhook(common.Config.ReportWebhook, webhookData)
-
-	if err != nil {
-		println(err.Error())
-	}
-
-	database.DB.NewInsert().Model(&report).Exec(context.Background())
-	return nil
+	return webhookData
 }
 
-func GetReportCountInLastHour(twitterUserID string) (int, error) {
+func GetReportCountInLastHour(userID int32) (int, error) {
 	count, err := database.DB.
-		NewSelect().Table("reviewdb_twitter.reports").
-		Where("reporter_id = ? AND timestamp > now() - interval '1 hour'", twitterUserID).
+		NewSelect().Model((*schemas.TwitterReviewReport)(nil)).
+		Where("reporter_id = ? AND timestamp > now() - interval '1 hour'", userID).
 		Count(context.Background())
 	if err != nil {
 		return 0, err
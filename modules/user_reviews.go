@@ -19,10 +19,11 @@ import (
 
 	discord_utils "server-go/modules/discord"
 	"server-go/modules/github"
+	"server-go/modules/translation"
 
 	"github.com/diamondburned/arikawa/v3/discord"
-	"github.com/patrickmn/go-cache"
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
 )
 
 type UR_RequestData struct {
@@ -54,6 +55,7 @@ type Settings struct {
 type GetReviewsOptions struct {
 	IncludeReviewsById string
 	Limit              int
+	Platform           string // defaults to schemas.PlatformDiscord
 }
 
 func GetReviews(requester *schemas.URUser, userID int64, offset int) ([]schemas.UserReview, int, error) {
@@ -66,10 +68,15 @@ func GetReviews(requester *schemas.URUser, userID int64, offset int) ([]schemas.
 func GetReviewsWithOptions(requester *schemas.URUser, userID int64, offset int, options GetReviewsOptions) ([]schemas.UserReview, int, error) {
 	var reviews []schemas.UserReview
 
+	if options.Platform == "" {
+		options.Platform = schemas.PlatformDiscord
+	}
+
 	req := database.DB.NewSelect().
 		Model(&reviews).
 		Relation("User").
 		Where("profile_id = ?", userID).
+		Where("user_review.platform = ?", options.Platform).
 		Offset(offset).
 		Limit(options.Limit)
 	// TODO: REPLIES ARE ALWAYS NULL FIX
@@ -80,9 +87,9 @@ func GetReviewsWithOptions(requester *schemas.URUser, userID int64, offset int,
 	}
 
 	if options.IncludeReviewsById != "" {
-		req = req.OrderExpr("reviewer_id = ? desc ,\"user\".discord_id = ? desc , id desc", options.IncludeReviewsById, options.IncludeReviewsById)
+		req = req.OrderExpr("reviewer_id = ? desc ,\"user\".discord_id = ? desc , pinned desc, id desc", options.IncludeReviewsById, options.IncludeReviewsById)
 	} else {
-		req = req.OrderExpr("id desc")
+		req = req.OrderExpr("pinned desc, id desc")
 	}
 	count, err := req.ScanAndCount(context.Background(), &reviews)
 	if err != nil {
@@ -102,7 +109,7 @@ func GetReviewsWithOptions(requester *schemas.URUser, userID int64, offset int,
 
 	for i, review := range reviews {
 
-		badges := GetBadgesOfUser(review.User.DiscordID)
+		badges := GetPlatformBadgesOfUser(review.User.Platform, review.User.DiscordID)
 
 		if review.User.DiscordID == "1134864775000629298" {
 			// troll
@@ -118,6 +125,7 @@ func GetReviewsWithOptions(requester *schemas.URUser, userID int64, offset int,
 		}
 		reviews[i].Reputation = &review.User.Reputation
 		reviews[i].Timestamp = review.TimestampStr.Unix()
+		setEditedFields(&reviews[i])
 
 		if review.RepliesTo != 0 {
 			addReply(&reviews[i])
@@ -137,8 +145,13 @@ func GetReviewsWithOptions(requester *schemas.URUser, userID int64, offset int,
 }
 
 func GetDBUserViaDiscordID(discordID string) (*schemas.URUser, error) {
+	return GetDBUserViaPlatformID(schemas.PlatformDiscord, discordID)
+}
+
+// GetDBUserViaPlatformID looks up the account with the given id on an identity provider, nil if there is none
+func GetDBUserViaPlatformID(platform string, platformID string) (*schemas.URUser, error) {
 	var user schemas.URUser
-	err := database.DB.NewSelect().Model(&user).Where("discord_id = ?", discordID).Limit(1).Scan(context.Background())
+	err := database.DB.NewSelect().Model(&user).Where("platform = ? AND discord_id = ?", platform, platformID).Limit(1).Scan(context.Background())
 
 	if err != nil {
 		if err.Error() == "sql: no rows in result set" { //SOMEONE TELL ME BETTER WAY TO DO THIS
@@ -159,7 +172,7 @@ func SearchReviews(query string, token string) ([]schemas.UserReview, error) {
 		return nil, err
 	}
 	if user.Type != 1 {
-		return nil, errors.New("You are not allowed to use this route")
+		return nil, ErrNotAdmin
 	}
 
 	err = database.DB.NewSelect().Model(&reviews).Relation("User").Where("comment LIKE ?", "%"+query+"%").OrderExpr("ID DESC").Limit(100).Scan(context.Background(), &reviews)
@@ -178,20 +191,24 @@ func SearchReviews(query string, token string) ([]schemas.UserReview, error) {
 			reviews[i].Sender.Badges = badges
 		}
 		reviews[i].Timestamp = review.TimestampStr.Unix()
+		setEditedFields(&reviews[i])
 	}
 
 	return reviews, nil
 }
 
 func AddReview(reviewer *schemas.URUser, review *schemas.UserReview) (string, error) {
-	var err error
+	existing := schemas.UserReview{}
+
+	if review.Platform == "" {
+		review.Platform = schemas.PlatformDiscord
+	}
 
 	query := database.DB.
-		NewUpdate().
-		Model(review).
+		NewSelect().
+		Model(&existing).
 		Where("profile_id = ?", review.ProfileID).
-		Where("reviewer_id = ?", reviewer.ID).
-		OmitZero()
+		Where("reviewer_id = ?", reviewer.ID)
 
 	if review.RepliesTo != 0 {
 		query = query.Where("replies_to = ?", review.RepliesTo)
@@ -199,17 +216,56 @@ func AddReview(reviewer *schemas.URUser, review *schemas.UserReview) (string, er
 		query = query.Where("replies_to IS NULL")
 	}
 
-	res, err := query.Exec(context.Background())
-
-	if err != nil {
+	err := query.Limit(1).Scan(context.Background())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return common.UPDATE_FAILED, err
 	}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return common.UPDATE_FAILED, err
-	}
-	if rowsAffected != 0 {
+	if err == nil {
+		commentChanged := existing.Comment != review.Comment
+		if commentChanged {
+			review.EditedAtStr = time.Now()
+		}
+
+		err = database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+			// keep the old comment around so edits can't be used to get rid of reported content
+			if commentChanged {
+				revision := schemas.ReviewRevision{
+					ReviewID:     existing.ID,
+					Comment:      existing.Comment,
+					TimestampStr: existing.TimestampStr,
+				}
+
+				if _, err := tx.NewInsert().Model(&revision).Exec(ctx); err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.NewUpdate().
+				Model(review).
+				Where("id = ?", existing.ID).
+				OmitZero().
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+
+			if existing.TranslatedFrom != "" && commentChanged {
+				_, err = tx.NewUpdate().
+					Model((*schemas.UserReview)(nil)).
+					Set("translated_comment = NULL").
+					Set("translated_from = NULL").
+					Where("id = ?", existing.ID).
+					Exec(ctx)
+			}
+			return err
+		})
+		if err != nil {
+			return common.UPDATE_FAILED, err
+		}
+
+		review.ID = existing.ID
+		publishReviewEvent(EventReviewUpdated, review)
 		return common.UPDATED, nil
 	}
 
@@ -217,6 +273,7 @@ func AddReview(reviewer *schemas.URUser, review *schemas.UserReview) (string, er
 	if err != nil {
 		return common.ERROR, err
 	}
+	publishReviewEvent(EventReviewAdded, review)
 	return common.ADDED, nil
 }
 
@@ -275,7 +332,7 @@ func GetDBUserViaTokenAndData(token string, data UR_RequestData) (user schemas.U
 
 	if err != nil {
 		fmt.Println(err.Error())
-		return schemas.URUser{}, errors.New("Invalid Token")
+		return schemas.URUser{}, ErrInvalidToken
 	}
 
 	// update last_online field so that we can check active users
@@ -345,7 +402,7 @@ func AddUserReviewsUser(code string, clientmod string, authUrl string, ip string
 	discordToken, err := discord_utils.ExchangeCode(code, common.Config.Origin+authUrl)
 	if err != nil {
 		fmt.Println(err)
-		return "", errors.New("Invalid Code")
+		return "", ValidationError("invalid_code", "Invalid Code")
 	}
 
 	discordUser, err := discord_utils.GetUser(discordToken.AccessToken)
@@ -356,13 +413,14 @@ func AddUserReviewsUser(code string, clientmod string, authUrl string, ip string
 	}
 
 	if discordUser.CreatedAt().After(time.Now().Add(-time.Hour * 24 * 30)) {
-		return "", errors.New("Your account is too new")
+		return "", ForbiddenError("account_too_new", "Your account is too new")
 	}
 
 	token := GenerateToken()
 
 	user := &schemas.URUser{
 		DiscordID:    discordUser.ID.String(),
+		Platform:     schemas.PlatformDiscord,
 		Token:        token,
 		Username:     common.Ternary(discordUser.Discriminator == "0", discordUser.Username, discordUser.Username+"#"+discordUser.Discriminator),
 		AvatarURL:    discordUser.AvatarURL(),
@@ -382,7 +440,7 @@ func AddUserReviewsUser(code string, clientmod string, authUrl string, ip string
 
 	if dbUser != nil {
 		if dbUser.Type == -1 {
-			return "You have been banned from ReviewDB", errors.New("You have been banned from ReviewDB")
+			return "You have been banned from ReviewDB", ErrBanned
 		}
 
 		if !strings.HasPrefix(dbUser.Token, "rdb.") {
@@ -418,7 +476,7 @@ func AddUserReviewsUser(code string, clientmod string, authUrl string, ip string
 		return "", err
 	}
 
-	discord_utils.SendLoggerWebhook(discord_utils.WebhookData{
+	QueueDiscordWebhook(WebhookTargetLogger, discord_utils.WebhookData{
 		Username:  discordUser.Username + "#" + discordUser.Discriminator,
 		AvatarURL: discordUser.AvatarURL(),
 		Content:   fmt.Sprintf("User <@%s> has been registered to ReviewDB from %s", discordUser.ID, clientmod),
@@ -434,7 +492,7 @@ func GetReview(id int32) (rep schemas.UserReview, err error) {
 		return rep, err
 	}
 
-	badges := GetBadgesOfUser(rep.User.DiscordID)
+	badges := GetPlatformBadgesOfUser(rep.User.Platform, rep.User.DiscordID)
 
 	if rep.User != nil {
 		rep.Sender = schemas.Sender{}
@@ -445,56 +503,97 @@ func GetReview(id int32) (rep schemas.UserReview, err error) {
 		rep.Sender.Badges = badges
 	}
 	rep.Timestamp = rep.TimestampStr.Unix()
+	setEditedFields(&rep)
+
+	return
+}
+
+func setEditedFields(review *schemas.UserReview) {
+	if !review.EditedAtStr.IsZero() {
+		review.Edited = true
+		review.EditedAt = review.EditedAtStr.Unix()
+	}
+}
+
+// GetReviewRevisions returns every previous version of a review, oldest first
+func GetReviewRevisions(reviewID int32) (revisions []schemas.ReviewRevision, err error) {
+	revisions = []schemas.ReviewRevision{}
+	err = database.DB.NewSelect().
+		Model(&revisions).
+		Where("review_id = ?", reviewID).
+		Order("id ASC").
+		Scan(context.Background(), &revisions)
 
+	for i := range revisions {
+		revisions[i].Timestamp = revisions[i].TimestampStr.Unix()
+	}
 	return
 }
 
+// TranslateReview translates a review's comment to english, saving the translation so it is only done once
+func TranslateReview(review *schemas.UserReview) (translation.Result, error) {
+	if review.TranslatedFrom != "" {
+		return translation.Result{Text: review.TranslatedComment, SourceLang: review.TranslatedFrom}, nil
+	}
+
+	result, err := translation.Translate(review.Comment)
+	if err != nil || result.SourceLang == "" {
+		return result, err
+	}
+
+	review.TranslatedComment = result.Text
+	review.TranslatedFrom = result.SourceLang
+	_, err = database.DB.NewUpdate().
+		Model(review).
+		Column("translated_comment", "translated_from").
+		Where("id = ? AND comment = ?", review.ID, review.Comment).
+		Exec(context.Background())
+	return result, err
+}
+
 func ReportReview(data UR_RequestData) error {
 
 	user, err := GetDBUserViaTokenAndData(data.Token, data)
 	if err != nil {
-		return errors.New(common.INVALID_REVIEW)
+		return ValidationError("invalid_review", common.INVALID_REVIEW)
 	}
 
 	if user.IsBanned() {
-		return errors.New("You cant report reviews while banned")
+		return BannedError("banned", "You cant report reviews while banned")
 	}
 
 	reportCount, _ := GetReportCountInLastHour(user.ID)
 
 	if reportCount > 20 {
-		return errors.New("You are reporting too much")
+		return RateLimitedError("reporting_too_much", "You are reporting too much")
 	}
 
 	count, _ := database.DB.NewSelect().Model(&schemas.ReviewReport{}).Where("review_id = ? AND reporter_id = ?", data.ReviewID, user.ID).Count(context.Background())
 	if count > 0 {
-		return errors.New("You have already reported this review")
+		return ConflictError("already_reported", "You have already reported this review")
 	}
 
 	review, err := GetReview(data.ReviewID)
-	if err != nil {
-		return errors.New("Invalid Review ID")
+	if err != nil || review.Platform != user.Platform {
+		return ErrInvalidReview
 	}
 
-	if review.Sender.DiscordID == user.DiscordID {
-		return errors.New("You cant report your own reviews")
+	if review.ReviewerID == user.ID {
+		return ForbiddenError("report_own_review", "You cant report your own reviews")
 	}
 
-	reportedUser, _ := GetDBUserViaID(review.ReviewerID)
-
 	report := schemas.ReviewReport{
 		ReviewID:   data.ReviewID,
 		ReporterID: user.ID,
 	}
 
-	err = discord_utils.SendReportWebhook(&user, &review, &reportedUser)
-
-	if err != nil {
-		println(err.Error())
-	}
-
-	database.DB.NewInsert().Model(&report).Exec(context.Background())
-	return nil
+	// the webhook worker translates and rates the review, the report is only queued here
+	return database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(&report).Exec(ctx); err != nil {
+			return err
+		}
+		return queueReportWebhook(tx, report.ID)
+	})
 }
 
 func GetReports(offset int, limit int) (reports []schemas.ReviewReport, err error) {
@@ -512,7 +611,7 @@ func GetReports(offset int, limit int) (reports []schemas.ReviewReport, err erro
 
 // checks if user is admin **or** moderator
 func IsUserAdminDC(discordid int64) bool {
-	count, _ := database.DB.NewSelect().Model(&schemas.URUser{}).Where("discord_id = ? and (type = 1 or type = 2)", discordid).Count(context.Background())
+	count, _ := database.DB.NewSelect().Model(&schemas.URUser{}).Where("platform = ? AND discord_id = ? and (type = 1 or type = 2)", schemas.PlatformDiscord, discordid).Count(context.Background())
 
 	if count > 0 {
 		return true
@@ -541,112 +640,178 @@ func DeleteReviewWithData(data UR_RequestData) (err error) {
 	review, err := GetReview(data.ReviewID)
 	if err != nil {
 		fmt.Println(err.Error())
-		return errors.New("Invalid Review ID")
+		return ErrInvalidReview
 	}
 
-	user, err := GetDBUserViaTokenAndData(data.Token, data)
+	if data.Token == common.Config.AdminToken { // todo create a admin account on database and handle things that way
+		return deleteReview(&review, nil)
+	}
 
-	if err != nil && data.Token != common.Config.AdminToken { // todo create a admin account on database and handle things that way
+	user, err := GetDBUserViaTokenAndData(data.Token, data)
+	if err != nil {
 		println(err.Error())
-		return errors.New("Invalid Token")
+		return ErrInvalidToken
 	}
+	return deleteReview(&review, &user)
+}
 
-	if (review.User.DiscordID == user.DiscordID) || user.IsAdmin() || data.Token == common.Config.AdminToken || user.DiscordID == strconv.FormatInt(review.ProfileID, 10) {
-		LogAction("DELETE", review, user.ID)
-
-		_, err = database.DB.NewDelete().Model(&review).Where("id = ?", data.ReviewID).Exec(context.Background())
-		_, err = database.DB.NewDelete().Model(&review).Where("replies_to = ?", data.ReviewID).Exec(context.Background())
-		return nil
+// DeleteReviewAs deletes a review for a staff member or the review's author or profile owner
+func DeleteReviewAs(actor *schemas.URUser, reviewID int32) error {
+	review, err := GetReview(reviewID)
+	if err != nil {
+		return ErrInvalidReview
 	}
-	return errors.New("You are not allowed to delete this review")
+	return deleteReview(&review, actor)
 }
 
-func GetBadgesOfUser(discordid string) []schemas.UserBadge {
-	userBadges := []schemas.UserBadge{}
-
-	badges, _ := GetAllBadges()
-	for _, badge := range badges {
-
-		if badge.TargetDiscordID == discordid {
-			userBadges = append(userBadges, badge)
+// deleteReview deletes a review if actor is allowed to, a nil actor is the configured admin token
+func deleteReview(review *schemas.UserReview, actor *schemas.URUser) error {
+	var actorID int32
+	if actor != nil {
+		isProfileOwner := actor.Platform == review.Platform && actor.DiscordID == strconv.FormatInt(review.ProfileID, 10)
+		if review.ReviewerID != actor.ID && !actor.Can(schemas.CapabilityModerateReviews) && !isProfileOwner {
+			return ForbiddenError("not_review_owner", "You are not allowed to delete this review")
+		}
+		actorID = actor.ID
+	}
+
+	// reviews are only soft deleted so accidental deletions can be undone, PurgeDeletedReviews removes them for good
+	err := database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		var ids []int32
+		_, err := tx.NewUpdate().
+			Model((*schemas.UserReview)(nil)).
+			Set("deleted_at = ?", time.Now()).
+			Set("deleted_by = NULLIF(?, 0)", actorID).
+			Where("id = ? OR replies_to = ?", review.ID, review.ID).
+			Returning("id").
+			Exec(ctx, &ids)
+		if err != nil {
+			return err
 		}
+		return updateReputationOfReviews(ctx, tx, ids, -1)
+	})
+	if err != nil {
+		fmt.Println(err)
+		return errors.New(common.ERROR)
 	}
-	return userBadges
+	LogAction("DELETE", *review, actorID)
+	publishReviewEvent(EventReviewDeleted, review)
+	return nil
 }
 
-func GetAllBadges() (badges []schemas.UserBadge, err error) {
-
-	cachedBadges, found := common.Cache.Get("badges")
-	if found {
-		badges = cachedBadges.([]schemas.UserBadge)
-		return
+// RestoreReview undoes a deletion, bringing back the review and the replies that were deleted along with it
+func RestoreReview(reviewID int32, actorID int32) error {
+	review := schemas.UserReview{}
+	err := database.DB.NewSelect().
+		Model(&review).
+		WhereDeleted().
+		Where("id = ?", reviewID).
+		Scan(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ConflictError("review_not_deleted", "This review is not deleted")
+	} else if err != nil {
+		return err
 	}
 
-	badges = []schemas.UserBadge{}
-	err = database.DB.NewSelect().Model(&badges).Scan(context.Background(), &badges)
-
-	users := []schemas.URUser{}
+	query := database.DB.NewSelect().
+		Model((*schemas.UserReview)(nil)).
+		Where("profile_id = ?", review.ProfileID).
+		Where("reviewer_id = ?", review.ReviewerID)
 
-	database.DB.NewSelect().Distinct().Model(&users).Column("discord_id", "type").Where("type = 1").Scan(context.Background(), &users)
+	if review.RepliesTo != 0 {
+		query = query.Where("replies_to = ?", review.RepliesTo)
+	} else {
+		query = query.Where("replies_to IS NULL")
+	}
 
-	for _, user := range users {
-		if user.Type == 1 {
-			badges = append(badges, schemas.UserBadge{
-				TargetDiscordID: user.DiscordID,
-				Name:            "Admin",
-				Icon:            "https://cdn.discordapp.com/emojis/1040004306100826122.gif?size=128",
-				RedirectURL:     "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
-				Description:     "This user is an admin of ReviewDB.",
-			})
-		} else {
-			badges = append(badges, schemas.UserBadge{
-				TargetDiscordID: user.DiscordID,
-				Name:            "Banned",
-				Icon:            "https://cdn.discordapp.com/emojis/399233923898540053.gif?size=128",
-				RedirectURL:     "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
-				Description:     "This user is banned from ReviewDB.",
-			})
+	exists, err := query.Exists(context.Background())
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ConflictError("review_superseded", "The author has written a new review since this one was deleted")
+	}
+
+	err = database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		var ids []int32
+		_, err := tx.NewUpdate().
+			Model((*schemas.UserReview)(nil)).
+			WhereDeleted().
+			Set("deleted_at = NULL").
+			Set("deleted_by = NULL").
+			Where("id = ? OR replies_to = ?", reviewID, reviewID).
+			Where("deleted_at = ?", review.DeletedAt).
+			Returning("id").
+			Exec(ctx, &ids)
+		if err != nil {
+			return err
 		}
-
+		return updateReputationOfReviews(ctx, tx, ids, 1)
+	})
+	if err != nil {
+		return err
 	}
 
-	common.Cache.Set("badges", badges, cache.DefaultExpiration)
-	return
+	LogAction("RESTORE", review, actorID)
+	publishReviewEvent(EventReviewAdded, &review)
+	return nil
 }
 
-func GetBadgesMap() (map[string][]schemas.UserBadge, error) {
-	cachedBadgesMap, found := common.Cache.Get("badgesMap")
-	if found {
-		return cachedBadgesMap.(map[string][]schemas.UserBadge), nil
-	}
+// PurgeDeletedReviews permanently removes reviews that were soft deleted before the given time
+func PurgeDeletedReviews(deletedBefore time.Time) (int, error) {
+	var ids []int32
+	err := database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().
+			Model((*schemas.UserReview)(nil)).
+			WhereDeleted().
+			Where("deleted_at < ?", deletedBefore).
+			ForceDelete().
+			Returning("id").
+			Exec(ctx, &ids)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
 
-	badges := []schemas.UserBadge{}
-	if err := database.DB.NewSelect().Model(&badges).Scan(context.Background(), &badges); err != nil {
-		return nil, err
+		// everything that points at the purged reviews goes with them
+		for _, model := range []any{
+			(*schemas.ReviewRevision)(nil),
+			(*schemas.ReputationEvent)(nil),
+			(*schemas.ReviewVote)(nil),
+			(*schemas.ReviewReport)(nil),
+		} {
+			_, err = tx.NewDelete().
+				Model(model).
+				Where("review_id IN (?)", bun.In(ids)).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
+	return len(ids), nil
+}
 
-	users := []schemas.URUser{}
-	if err := database.DB.NewSelect().Distinct().Model(&users).Column("discord_id", "type").Where("type = ?", 1).Scan(context.Background(), &users); err != nil {
-		return nil, err
+// StartDeletedReviewPurger periodically purges reviews that have been deleted for longer than the configured retention period
+func StartDeletedReviewPurger() {
+	retentionDays := common.Config.ReviewRetentionDays
+	if retentionDays <= 0 {
+		retentionDays = 30
 	}
 
-	for _, user := range users {
-		badges = append(badges, schemas.UserBadge{
-			TargetDiscordID: user.DiscordID,
-			Name:            "Admin",
-			Icon:            "https://cdn.discordapp.com/emojis/1040004306100826122.gif?size=128",
-			RedirectURL:     "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
-			Description:     "This user is an admin of ReviewDB.",
-		})
-	}
+	for {
+		purged, err := PurgeDeletedReviews(time.Now().AddDate(0, 0, -retentionDays))
+		if err != nil {
+			fmt.Println("failed to purge deleted reviews:", err)
+		} else if purged > 0 {
+			fmt.Printf("purged %d deleted reviews\n", purged)
+		}
 
-	badgesMap := make(map[string][]schemas.UserBadge)
-	for _, badge := range badges {
-		badgesMap[badge.TargetDiscordID] = append(badgesMap[badge.TargetDiscordID], badge)
+		time.Sleep(1 * time.Hour)
 	}
-
-	common.Cache.Set("badgesMap", badgesMap, cache.DefaultExpiration)
-	return badgesMap, nil
 }
 
 func GetVencordBadges() error {
@@ -675,36 +840,56 @@ func GetLastReviewID(userID string) int32 {
 }
 
 func BanUser(userToBan string, adminToken string, banDuration int32, review schemas.UserReview) error {
-	user := schemas.URUser{}
+	return BanUserOnPlatform(schemas.PlatformDiscord, userToBan, adminToken, banDuration, review)
+}
 
-	admin, _ := GetDBUserViaToken(adminToken)
-	if adminToken != common.Config.AdminToken && !admin.IsAdmin() {
-		return errors.New("You are not allowed to ban users")
+// BanUserOnPlatform bans the account with the given id on an identity provider for the user of adminToken
+func BanUserOnPlatform(platform string, userToBan string, adminToken string, banDuration int32, review schemas.UserReview) error {
+	if adminToken == common.Config.AdminToken {
+		return BanUserAs(nil, platform, userToBan, banDuration, review, "")
 	}
 
-	/*
-		AdminDiscordID := 0
-		if token != common.Config.AdminToken {
-			admin , _ := GetDBUserViaToken(token)
-			AdminDiscordID = admin.DiscordID
+	admin, err := GetDBUserViaToken(adminToken)
+	if err != nil {
+		return ForbiddenError("not_admin", "You are not allowed to ban users")
+	}
+	return BanUserAs(&admin, platform, userToBan, banDuration, review, "")
+}
+
+// BanUserAs bans the account with the given id on an identity provider, accounts reach a permanent ban after
+// three bans. The ban is attributed to actor, a nil actor is the configured admin token. reason is shown to the
+// user next to the offending review and can be empty.
+func BanUserAs(actor *schemas.URUser, platform string, userToBan string, banDuration int32, review schemas.UserReview, reason string) error {
+	user := schemas.URUser{}
+
+	var adminDiscordID *string
+	if actor != nil {
+		if !actor.Can(schemas.CapabilityBanUsers) {
+			return ForbiddenError("not_admin", "You are not allowed to ban users")
 		}
-	*/
+		adminDiscordID = &actor.DiscordID
+	}
 
-	database.DB.NewSelect().Model(&user).Where("discord_id = ?", userToBan).Scan(context.Background(), &user)
+	database.DB.NewSelect().Model(&user).Where("platform = ? AND discord_id = ?", platform, userToBan).Scan(context.Background(), &user)
+
+	if user.ID == 0 {
+		return ErrUserNotFound
+	}
 
 	if user.Type == 1 {
-		return errors.New("You can't ban an admin")
+		return ForbiddenError("ban_admin", "You can't ban an admin")
 	}
 
 	if user.IsBanned() {
-		return errors.New("This user is already banned")
+		return ConflictError("already_banned", "This user is already banned")
 	}
 
 	if user.WarningCount >= 3 {
-		_, err := database.DB.NewUpdate().Model(&schemas.URUser{}).Where("discord_id = ?", userToBan).Set("type = -1").Exec(context.Background())
+		_, err := database.DB.NewUpdate().Model(&schemas.URUser{}).Where("id = ?", user.ID).Set("type = -1").Exec(context.Background())
 		if err != nil {
 			return err
 		}
+		InvalidateBadgeCache()
 		return nil
 	}
 
@@ -717,12 +902,16 @@ func BanUser(userToBan string, adminToken string, banDuration int32, review sche
 			BanEndDate:      time.Now().AddDate(0, 0, int(banDuration)),
 			ReviewContent:   review.Comment,
 			ReviewTimestamp: review.TimestampStr,
+			AdminDiscordID:  adminDiscordID,
+			Reason:          reason,
 		}
 
 	} else {
 		banData = schemas.ReviewDBBanLog{
-			DiscordID:  userToBan,
-			BanEndDate: time.Now().AddDate(0, 0, int(banDuration)),
+			DiscordID:      userToBan,
+			BanEndDate:     time.Now().AddDate(0, 0, int(banDuration)),
+			AdminDiscordID: adminDiscordID,
+			Reason:         reason,
 		}
 	}
 
@@ -731,11 +920,12 @@ func BanUser(userToBan string, adminToken string, banDuration int32, review sche
 		return err
 	}
 
-	_, err = database.DB.NewUpdate().Model(&schemas.URUser{}).Where("discord_id = ?", userToBan).Set("ban_id = ?", banData.ID).Set("warning_count = warning_count + 1").Exec(context.Background())
+	_, err = database.DB.NewUpdate().Model(&schemas.URUser{}).Where("id = ?", user.ID).Set("ban_id = ?", banData.ID).Set("warning_count = warning_count + 1").Exec(context.Background())
 
 	if err != nil {
 		return err
 	}
+	InvalidateBadgeCache()
 
 	SendNotification(&schemas.Notification{
 		UserID: user.ID,
@@ -745,11 +935,12 @@ func BanUser(userToBan string, adminToken string, banDuration int32, review sche
 			You have been banned from ReviewDB %s
 
 			**Offending Review:** %s
-
+			%s
 			Continued offenses will result in a permanent ban.
 		`,
 			common.Ternary(user.Type == schemas.UserTypeBanned, "permanently", "until <t:"+strconv.FormatInt(banData.BanEndDate.Unix(), 10)+":F>"),
 			review.Comment,
+			common.Ternary(reason == "", "", "**Reason:** "+reason+"\n"),
 		),
 	})
 	return nil
@@ -787,6 +978,7 @@ func CreateUserViaBot(discordid string, username string, profilePhoto string) (s
 	user := schemas.URUser{}
 
 	user.DiscordID = discordid
+	user.Platform = schemas.PlatformDiscord
 	user.Username = username
 	user.Type = 0
 	user.WarningCount = 0
@@ -803,7 +995,7 @@ func CreateUserViaBot(discordid string, username string, profilePhoto string) (s
 		return schemas.URUser{}, err
 	}
 
-	discord_utils.SendLoggerWebhook(discord_utils.WebhookData{
+	QueueDiscordWebhook(WebhookTargetLogger, discord_utils.WebhookData{
 		Username:  username,
 		AvatarURL: profilePhoto,
 		Content:   fmt.Sprintf("User <@%s> has been registered to ReviewDB from Bot integration", discordid),
@@ -814,7 +1006,7 @@ func CreateUserViaBot(discordid string, username string, profilePhoto string) (s
 
 func SetSettings(settings Settings) error {
 
-	_, err := database.DB.NewUpdate().Model(&settings).Where("discord_id = ?", settings.DiscordID).Exec(context.Background())
+	_, err := database.DB.NewUpdate().Model(&settings).Where("platform = ? AND discord_id = ?", schemas.PlatformDiscord, settings.DiscordID).Exec(context.Background())
 	if err != nil {
 		return err
 	}
@@ -824,7 +1016,7 @@ func SetSettings(settings Settings) error {
 func GetSettings(discordid string) (Settings, error) {
 	settings := Settings{}
 
-	err := database.DB.NewSelect().Model(&settings).Where("discord_id = ?", discordid).Limit(1).Scan(context.Background(), &settings)
+	err := database.DB.NewSelect().Model(&settings).Where("platform = ? AND discord_id = ?", schemas.PlatformDiscord, discordid).Limit(1).Scan(context.Background(), &settings)
 
 	return settings, err
 }
@@ -886,8 +1078,13 @@ func GetReportCountInLastHour(userID int32) (int, error) {
 func AppealBan(appeal schemas.ReviewDBAppeal, user *schemas.URUser) (err error) {
 	_, err = database.DB.NewInsert().Model(&appeal).Exec(context.Background())
 
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) && pgErr.Field('C') == "23505" {
+		return ConflictError("already_appealed", "You have already appealed this ban")
+	}
+
 	if err == nil {
-		discord_utils.SendAppealWebhook(&appeal, user)
+		QueueDiscordWebhook(WebhookTargetAppeal, discord_utils.AppealWebhook(&appeal, user))
 	}
 
 	return
@@ -899,26 +1096,19 @@ func GetAppeal(id int32) (appeal schemas.ReviewDBAppeal, err error) {
 }
 
 func AcceptAppeal(appeal *schemas.ReviewDBAppeal, userId int32) (err error) {
-	_, err = database.DB.NewUpdate().Model(&schemas.URUser{}).Set("type = 0").Set("ban_id = NULL").Set("warning_count = GREATEST(0, warning_count - 1)").Where("id = ?", userId).Exec(context.Background())
-
+	_, err = database.DB.NewUpdate().Model(appeal).Set("action_taken=true").WherePK().Exec(context.Background())
 	if err != nil {
 		return
 	}
 
-	_, err = database.DB.NewUpdate().Model(appeal).Set("action_taken=true").Exec(context.Background())
-
-	err = SendNotification(&schemas.Notification{
-		UserID:  userId,
-		Title:   "ReviewDB",
-		Content: "You have been unbanned from ReviewDB",
-	})
-
-	return
+	return unbanUser(userId)
 }
 
 func DenyAppeal(appeal *schemas.ReviewDBAppeal, denyText string) (err error) {
-
-	database.DB.NewUpdate().Model(appeal).Set("action_taken=true").Exec(context.Background())
+	_, err = database.DB.NewUpdate().Model(appeal).Set("action_taken=true").WherePK().Exec(context.Background())
+	if err != nil {
+		return
+	}
 
 	return SendNotification(&schemas.Notification{
 		UserID: appeal.UserID,
@@ -932,19 +1122,28 @@ func DenyAppeal(appeal *schemas.ReviewDBAppeal, denyText string) (err error) {
 	})
 }
 
-func GetBlockedUsers(blocker *schemas.URUser) (users []schemas.BaseRDBUser, err error) {
-	if blocker.BlockedUsers == nil {
-		return []schemas.BaseRDBUser{}, nil
-	}
+const MaxBlockedUsers = 500
 
+func GetBlockedUsers(blocker *schemas.URUser, offset int, limit int) (users []schemas.BaseRDBUser, err error) {
 	users = []schemas.BaseRDBUser{}
-	err = database.DB.NewSelect().Model(&users).Where("discord_id IN (?)", bun.In(blocker.BlockedUsers)).Scan(context.Background(), &users)
+	if offset >= len(blocker.BlockedUsers) {
+		return
+	}
+
+	// page over the blocked ids themselves so blocked people without a ReviewDB account don't shift pages
+	page := blocker.BlockedUsers[offset:min(offset+limit, len(blocker.BlockedUsers))]
+
+	err = database.DB.NewSelect().Model(&users).Where("platform = ? AND discord_id IN (?)", schemas.PlatformDiscord, bun.In(page)).Scan(context.Background(), &users)
 	return
 }
 
 func BlockUser(blocker *schemas.URUser, discordID string) (err error) {
-	if len(blocker.BlockedUsers) > 50 {
-		return errors.New("You can block maximum 50 users")
+	if slices.Contains(blocker.BlockedUsers, discordID) {
+		return nil
+	}
+
+	if len(blocker.BlockedUsers) >= MaxBlockedUsers {
+		return ValidationError("too_many_blocked_users", fmt.Sprintf("You can block maximum %d users", MaxBlockedUsers))
 	}
 
 	_, err = database.DB.NewUpdate().Model(&schemas.URUser{}).Set("blocked_users = array_append(blocked_users, ?)", discordID).Where("id = ?", blocker.ID).Exec(context.Background())
@@ -973,19 +1172,27 @@ func LinkGithub(githubCode string, user *schemas.URUser) (err error) {
 		AccessToken:  token.AccessToken,
 		RefreshToken: token.RefreshToken,
 		Expiry:       token.Expiry,
-		Provider:     "github",
+		Provider:     ConnectionProviderGithub,
 		Username:     userInfo.Login,
 		Avatar:       userInfo.AvatarURL,
 		ProviderId:   userInfo.NodeID,
 	}
 
-	_, err = database.DB.NewInsert().Model(&tokenEntry).Exec(context.Background())
+	err = AddConnection(&tokenEntry)
+	if err != nil {
+		return
+	}
 
+	// the account might have been sponsoring before it was linked
+	err = syncGithubSponsorDonor(userInfo.NodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
 	return
 }
 
 func ResetToken(discordId string) (err error) {
-	_, err = database.DB.NewUpdate().Model(&schemas.URUser{}).Set("token = ?", GenerateToken()).Where("discord_id = ?", discordId).Exec(context.Background())
+	_, err = database.DB.NewUpdate().Model(&schemas.URUser{}).Set("token = ?", GenerateToken()).Where("platform = ? AND discord_id = ?", schemas.PlatformDiscord, discordId).Exec(context.Background())
 	return
 }
 
@@ -1020,7 +1227,12 @@ func GetUsersAdmin(query string, limit int, offset int, ip_hash string) (err err
 
 func PatchUserAdmin(user schemas.ReviewDBUserFull) error {
 	_, err := database.DB.NewUpdate().Model(&user).OmitZero().Exec(context.Background())
-	return err
+	if err != nil {
+		return err
+	}
+
+	InvalidateBadgeCache()
+	return nil
 }
 
 func GetUserAdmin(id string) (user schemas.ReviewDBUserFull, err error) {
@@ -1028,7 +1240,7 @@ func GetUserAdmin(id string) (user schemas.ReviewDBUserFull, err error) {
 	dbQuery := database.DB.NewSelect().Model(&user)
 
 	if len(id) > 10 {
-		dbQuery = dbQuery.Where("discord_id = ?", id)
+		dbQuery = dbQuery.Where("platform = ? AND discord_id = ?", schemas.PlatformDiscord, id)
 	} else {
 		dbQuery = dbQuery.Where("id = ?", id)
 	}
@@ -1038,28 +1250,6 @@ func GetUserAdmin(id string) (user schemas.ReviewDBUserFull, err error) {
 	return
 }
 
-func AddBadge(badge schemas.UserBadge) error {
-	_, err := database.DB.NewInsert().Model(&badge).Exec(context.Background())
-	common.Cache.Delete("badges")
-	common.Cache.Delete("badgesMap")
-	return err
-}
-
-func DeleteBadge(id string) error {
-	_, err := database.DB.NewDelete().Model(&schemas.UserBadge{}).Where("id = ?", id).Exec(context.Background())
-	common.Cache.Delete("badges")
-	common.Cache.Delete("badgesMap")
-	return err
-}
-
-type LeaderboardUser struct {
-	bun.BaseModel `bun:"table:users"`
-	DiscordID     string `bun:"discord_id" json:"discord_id"`
-	Username      string `bun:"username" json:"username"`
-	Count         int    `bun:"count" json:"review_count"`
-	AvatarURL     string `bun:"avatar_url" json:"avatar_url"`
-}
-
 type ReputationStats struct {
 	DiscordID   string `bun:"discord_id" json:"discordID"`
 	Username    string `bun:"username" json:"username,omitempty"`
@@ -1068,23 +1258,7 @@ type ReputationStats struct {
 	Upvotes     int    `bun:"upvotes" json:"upvotes,omitempty"`
 	Downvotes   int    `bun:"downvotes" json:"downvotes,omitempty"`
 	ReviewCount int    `bun:"review_count" json:"reviewCount"`
-}
-
-func GetLeaderboard() (leaderboard []LeaderboardUser, err error) {
-
-	err = database.DB.NewSelect().
-		ColumnExpr("u.discord_id").
-		ColumnExpr("u.username").
-		ColumnExpr("u.avatar_url").
-		ColumnExpr("COUNT(r.id) AS count").
-		TableExpr("reviews AS r").
-		Join("JOIN users AS u ON u.id = r.reviewer_id").
-		GroupExpr("r.reviewer_id, u.discord_id, u.username, u.avatar_url").
-		OrderExpr("count DESC").
-		Limit(50).
-		Scan(context.Background(), &leaderboard)
-
-	return
+	Rank        int    `bun:"rank" json:"rank,omitempty"`
 }
 
 func GetUserReputation(discordID string) (ReputationStats, error) {
@@ -1096,8 +1270,8 @@ func GetUserReputation(discordID string) (ReputationStats, error) {
 		ColumnExpr("u.avatar_url").
 		ColumnExpr("u.reputation").
 		ColumnExpr("COALESCE(COUNT(DISTINCT r.id), 0) AS review_count").
-		Join("LEFT JOIN reviews AS r ON r.reviewer_id = u.id").
-		Where("u.discord_id = ?", discordID).
+		Join("LEFT JOIN reviews AS r ON r.reviewer_id = u.id AND r.deleted_at IS NULL").
+		Where("u.platform = ? AND u.discord_id = ?", schemas.PlatformDiscord, discordID).
 		GroupExpr("u.id, u.discord_id, u.username, u.avatar_url, u.reputation").
 		Limit(1).
 		Scan(context.Background(), &stats)
@@ -1107,149 +1281,195 @@ func GetUserReputation(discordID string) (ReputationStats, error) {
 	return stats, err
 }
 
-func GetReputationLeaderboard() (leaderboard []ReputationStats, err error) {
-	leaderboard = []ReputationStats{}
-	err = database.DB.NewSelect().
-		TableExpr("users AS u").
-		ColumnExpr("u.discord_id").
-		ColumnExpr("u.username").
-		ColumnExpr("u.avatar_url").
-		ColumnExpr("u.reputation").
-		ColumnExpr("COUNT(DISTINCT r.id) AS review_count").
-		Join("LEFT JOIN reviews AS r ON r.reviewer_id = u.id").
-		GroupExpr("u.id, u.discord_id, u.username, u.avatar_url, u.reputation").
-		OrderExpr("u.reputation DESC, review_count DESC").
-		Limit(50).
-		Scan(context.Background(), &leaderboard)
-	return
-}
-
-func updateUserReputation(userID int32, delta int) error {
-	_, err := database.DB.NewUpdate().
+func updateUserReputation(ctx context.Context, db bun.IDB, userID int32, delta int) error {
+	_, err := db.NewUpdate().
 		TableExpr("users").
 		Set("reputation = reputation + ?", delta).
 		Where("id = ?", userID).
-		Exec(context.Background())
+		Exec(ctx)
+	return err
+}
+
+// updateReputationOfReviews adds sign times the weighted votes on the reviews to their authors' reputation.
+// Votes on deleted reviews don't count, so deleting takes them back and restoring adds them again.
+func updateReputationOfReviews(ctx context.Context, db bun.IDB, reviewIDs []int32, sign int) error {
+	if len(reviewIDs) == 0 {
+		return nil
+	}
+
+	_, err := db.NewRaw(`
+		UPDATE users AS u SET reputation = u.reputation + ? * v.reputation
+		FROM (
+			SELECT r.reviewer_id, SUM(CASE WHEN rv.is_upvote THEN rv.weight ELSE -rv.weight END) AS reputation
+			FROM reviews AS r
+			JOIN review_votes AS rv ON rv.review_id = r.id
+			WHERE r.id IN (?)
+			GROUP BY r.reviewer_id
+		) AS v
+		WHERE u.id = v.reviewer_id
+	`, sign, bun.In(reviewIDs)).Exec(ctx)
 	return err
 }
 
-func updateReviewScoreAndUserReputation(reviewID int32, reviewerID int32, delta int) error {
-	_, err := database.DB.NewUpdate().
+func updateReviewScoreAndUserReputation(ctx context.Context, db bun.IDB, reviewID int32, reviewerID int32, voterID int32, scoreDelta int, reputationDelta int) error {
+	_, err := db.NewUpdate().
 		TableExpr("reviews").
-		Set("score = score + ?", delta).
+		Set("score = score + ?", scoreDelta).
 		Where("id = ?", reviewID).
-		Exec(context.Background())
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	if reputationDelta == 0 {
+		return nil
+	}
+
+	_, err = db.NewInsert().
+		Model(&schemas.ReputationEvent{
+			UserID:   reviewerID,
+			VoterID:  voterID,
+			ReviewID: reviewID,
+			Delta:    reputationDelta,
+		}).
+		Exec(ctx)
 	if err != nil {
 		return err
 	}
 
-	return updateUserReputation(reviewerID, delta)
+	return updateUserReputation(ctx, db, reviewerID, reputationDelta)
+}
+
+// voteSign is +1 for upvotes and -1 for downvotes
+func voteSign(isUpvote bool) int {
+	return common.Ternary(isUpvote, 1, -1)
 }
 
 // VoteReview records an upvote (isUpvote=true) or downvote (isUpvote=false) for a review.
 // The score column on the reviews table is updated atomically (+1 for upvote, -1 for downvote).
 // Toggling from one vote direction to the other adds ±2 to the score.
+// The author's reputation changes by the vote's weight instead, see VoteWeight.
 // A user cannot vote on their own review and cannot vote the same direction twice.
+// The vote and the score/reputation changes are written in one transaction, the vote_unique
+// constraint decides which of two concurrent votes by the same user wins.
 func VoteReview(voter *schemas.URUser, reviewID int32, isUpvote bool) error {
 	review, err := GetReview(reviewID)
 	if err != nil {
-		return errors.New("invalid review ID")
+		return ErrInvalidReview
 	}
 
-	if review.ReviewerID == voter.ID {
-		return errors.New("you cannot vote on your own review")
+	if review.Platform != voter.Platform {
+		return ErrInvalidReview
 	}
 
-	// Check for an existing vote by this user on this review.
-	existingVote := &schemas.ReviewVote{}
-	err = database.DB.NewSelect().
-		Model(existingVote).
-		Where("review_id = ? AND voter_id = ?", reviewID, voter.ID).
-		Limit(1).
-		Scan(context.Background())
+	if review.ReviewerID == voter.ID {
+		return ForbiddenError("vote_own_review", "you cannot vote on your own review")
+	}
 
-	if err != nil && err.Error() != "sql: no rows in result set" {
+	author, err := GetDBUserViaID(review.ReviewerID)
+	if err != nil {
 		return err
 	}
+	weight := VoteWeight(voter, &author)
 
-	voteExists := err == nil
+	err = database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		// New vote: insert and adjust score by ±1.
+		newVote := &schemas.ReviewVote{
+			ReviewID: reviewID,
+			VoterID:  voter.ID,
+			IsUpvote: isUpvote,
+			Weight:   weight,
+		}
+		res, err := tx.NewInsert().
+			Model(newVote).
+			On("CONFLICT (review_id, voter_id) DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		if inserted, _ := res.RowsAffected(); inserted == 1 {
+			return updateReviewScoreAndUserReputation(ctx, tx, reviewID, review.ReviewerID, voter.ID, voteSign(isUpvote), voteSign(isUpvote)*weight)
+		}
+
+		// The user already voted, lock their vote so a concurrent toggle or delete can't apply twice.
+		existingVote := &schemas.ReviewVote{}
+		err = tx.NewSelect().
+			Model(existingVote).
+			Where("review_id = ? AND voter_id = ?", reviewID, voter.ID).
+			For("UPDATE").
+			Scan(ctx)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ConflictError("vote_changed", "your vote was changed at the same time, please try again")
+			}
+			return err
+		}
 
-	if voteExists {
 		if existingVote.IsUpvote == isUpvote {
-			return errors.New("you have already voted in this direction")
+			return ConflictError("already_voted", "you have already voted in this direction")
 		}
+
 		// Toggle: flip the vote direction and adjust score by ±2.
-		_, err = database.DB.NewUpdate().
+		_, err = tx.NewUpdate().
 			Model(existingVote).
 			Set("is_upvote = ?", isUpvote).
+			Set("weight = ?", weight).
 			Where("id = ?", existingVote.ID).
-			Exec(context.Background())
+			Exec(ctx)
 		if err != nil {
 			return err
 		}
 
-		delta := 2
-		if !isUpvote {
-			delta = -2
-		}
-		return updateReviewScoreAndUserReputation(reviewID, review.ReviewerID, delta)
-	}
-
-	// New vote: insert and adjust score by ±1.
-	newVote := &schemas.ReviewVote{
-		ReviewID: reviewID,
-		VoterID:  voter.ID,
-		IsUpvote: isUpvote,
-	}
-	_, err = database.DB.NewInsert().Model(newVote).Exec(context.Background())
+		reputationDelta := voteSign(isUpvote)*weight - voteSign(existingVote.IsUpvote)*existingVote.Weight
+		return updateReviewScoreAndUserReputation(ctx, tx, reviewID, review.ReviewerID, voter.ID, 2*voteSign(isUpvote), reputationDelta)
+	})
 	if err != nil {
 		return err
 	}
 
-	delta := 1
-	if !isUpvote {
-		delta = -1
-	}
-	return updateReviewScoreAndUserReputation(reviewID, review.ReviewerID, delta)
+	publishReviewVoted(&review)
+	return nil
 }
 
 // DeleteReviewVote removes the voter's vote from a review and adjusts the review score.
 func DeleteReviewVote(voter *schemas.URUser, reviewID int32) error {
 	review, err := GetReview(reviewID)
 	if err != nil {
-		return errors.New("invalid review ID")
+		return ErrInvalidReview
+	}
+
+	if review.Platform != voter.Platform {
+		return ErrInvalidReview
 	}
 
 	if review.ReviewerID == voter.ID {
-		return errors.New("you cannot vote on your own review")
+		return ForbiddenError("vote_own_review", "you cannot vote on your own review")
 	}
 
-	existingVote := &schemas.ReviewVote{}
-	err = database.DB.NewSelect().
-		Model(existingVote).
-		Where("review_id = ? AND voter_id = ?", reviewID, voter.ID).
-		Limit(1).
-		Scan(context.Background())
-	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			return errors.New("you have not voted on this review")
+	err = database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		// only the request that actually deletes the row gets it back, so the score is reverted once
+		deletedVote := &schemas.ReviewVote{}
+		err := tx.NewDelete().
+			Model(deletedVote).
+			Where("review_id = ? AND voter_id = ?", reviewID, voter.ID).
+			Returning("*").
+			Scan(ctx)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NotFoundError("vote_missing", "you have not voted on this review")
+			}
+			return err
 		}
-		return err
-	}
 
-	_, err = database.DB.NewDelete().
-		Model(existingVote).
-		Where("id = ?", existingVote.ID).
-		Exec(context.Background())
+		return updateReviewScoreAndUserReputation(ctx, tx, reviewID, review.ReviewerID, voter.ID, -voteSign(deletedVote.IsUpvote), -voteSign(deletedVote.IsUpvote)*deletedVote.Weight)
+	})
 	if err != nil {
 		return err
 	}
 
-	delta := -1
-	if !existingVote.IsUpvote {
-		delta = 1
-	}
-	return updateReviewScoreAndUserReputation(reviewID, review.ReviewerID, delta)
+	publishReviewVoted(&review)
+	return nil
 }
 
 func GetReviewVotesOnUser(voter *schemas.URUser, profileID int64) ([]schemas.ReviewVote, error) {
@@ -1258,6 +1478,7 @@ func GetReviewVotesOnUser(voter *schemas.URUser, profileID int64) ([]schemas.Rev
 		Model(&votes).
 		Join("JOIN reviews AS r ON r.id = review_vote.review_id").
 		Where("r.profile_id = ?", profileID).
+		Where("r.deleted_at IS NULL").
 		Where("review_vote.voter_id = ?", voter.ID).
 		OrderExpr("review_vote.review_id DESC").
 		Scan(context.Background())
@@ -1271,6 +1492,7 @@ func GetUserRating(discordID string) (int, error) {
 		TableExpr("reviews").
 		ColumnExpr("COALESCE(SUM(score), 0)").
 		Where("profile_id = ?", discordID).
+		Where("deleted_at IS NULL").
 		Scan(context.Background(), &rating)
 	return rating, err
 }
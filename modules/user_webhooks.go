@@ -0,0 +1,365 @@
+package modules
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"server-go/database"
+	"server-go/database/schemas"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	maxWebhooksPerUser = 5
+	maxWebhookAttempts = 10
+	webhookBatchSize   = 20
+	webhookSendTimeout = 10 * time.Second
+	// deliveries are claimed for long enough to send the whole batch one after another, other instances don't
+	// pick them up again while they are in flight. A worker that dies while sending leaves them to the next one.
+	webhookClaimDuration = webhookBatchSize*webhookSendTimeout + time.Minute
+)
+
+// EventWebhookPing is only sent when a user tests their webhook
+const EventWebhookPing EventType = "ping"
+
+// WebhookEvents are the events webhooks can subscribe to
+var WebhookEvents = []EventType{EventReviewAdded, EventReviewReplied, EventReviewUpdated, EventReviewDeleted, EventReviewVoted}
+
+var ErrWebhookNotFound = NotFoundError("webhook_not_found", "Webhook not found")
+
+// WebhookPayload is the body of every delivery
+type WebhookPayload struct {
+	Event     EventType `json:"event"`
+	Timestamp int64     `json:"timestamp"`
+	Platform  string    `json:"platform,omitempty"`
+	ProfileID string    `json:"profileId,omitempty"`
+	ReviewID  int32     `json:"reviewId,omitempty"`
+	RepliesTo int32     `json:"repliesTo,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	Score     *int      `json:"score,omitempty"`
+}
+
+// webhookClient refuses to connect to private addresses so webhooks can't be used to reach internal services
+var webhookClient = &http.Client{
+	Timeout: webhookSendTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: rejectPrivateAddress}).DialContext,
+	},
+	// a redirect is answered like any other non 2xx status
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func rejectPrivateAddress(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return fmt.Errorf("%s is not a public address", host)
+	}
+	return nil
+}
+
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" || parsed.User != nil || len(rawURL) > 500 {
+		return ValidationError("invalid_webhook_url", "Webhook url must be a https url")
+	}
+
+	if ip := net.ParseIP(parsed.Hostname()); ip != nil && rejectPrivateAddress("tcp", net.JoinHostPort(ip.String(), "443"), nil) != nil {
+		return ValidationError("invalid_webhook_url", "Webhook url must point to a public address")
+	}
+	return nil
+}
+
+// SignWebhookPayload is the X-ReviewDB-Signature of a delivery, receivers recompute it with their secret
+func SignWebhookPayload(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is how long to wait after the given failed attempt, doubling from 30 seconds up to 6 hours
+func webhookBackoff(attempt int) time.Duration {
+	backoff := 30 * time.Second
+	for i := 1; i < attempt && backoff < 6*time.Hour; i++ {
+		backoff *= 2
+	}
+	return min(backoff, 6*time.Hour)
+}
+
+func CreateWebhook(user *schemas.URUser, webhookURL string, events []string) (webhook schemas.UserWebhook, err error) {
+	if err = validateWebhookURL(webhookURL); err != nil {
+		return
+	}
+
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, EventType(event)) {
+			return webhook, ValidationError("invalid_webhook_event", "Unknown event "+event)
+		}
+	}
+
+	count, err := database.DB.NewSelect().Model((*schemas.UserWebhook)(nil)).Where("user_id = ?", user.ID).Count(context.Background())
+	if err != nil {
+		return
+	}
+	if count >= maxWebhooksPerUser {
+		return webhook, ConflictError("too_many_webhooks", fmt.Sprintf("You can't have more than %d webhooks", maxWebhooksPerUser))
+	}
+
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return
+	}
+
+	webhook = schemas.UserWebhook{
+		UserID: user.ID,
+		URL:    webhookURL,
+		Secret: "whsec_" + hex.EncodeToString(secret),
+		Events: events,
+	}
+	_, err = database.DB.NewInsert().Model(&webhook).Exec(context.Background())
+	return
+}
+
+func GetWebhooks(user *schemas.URUser) (webhooks []schemas.UserWebhook, err error) {
+	webhooks = []schemas.UserWebhook{}
+	err = database.DB.NewSelect().Model(&webhooks).Where("user_id = ?", user.ID).Order("id").Scan(context.Background())
+	return
+}
+
+func getOwnWebhook(user *schemas.URUser, webhookID int32) (webhook schemas.UserWebhook, err error) {
+	err = database.DB.NewSelect().Model(&webhook).Where("id = ? AND user_id = ?", webhookID, user.ID).Scan(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrWebhookNotFound
+	}
+	return
+}
+
+// DeleteWebhook removes a webhook together with its delivery log
+func DeleteWebhook(user *schemas.URUser, webhookID int32) error {
+	return database.DB.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewDelete().Model((*schemas.UserWebhook)(nil)).Where("id = ? AND user_id = ?", webhookID, user.ID).Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if deleted, _ := res.RowsAffected(); deleted == 0 {
+			return ErrWebhookNotFound
+		}
+
+		_, err = tx.NewDelete().Model((*schemas.WebhookDelivery)(nil)).Where("webhook_id = ?", webhookID).Exec(ctx)
+		return err
+	})
+}
+
+func GetWebhookDeliveries(user *schemas.URUser, webhookID int32, offset int, limit int) (deliveries []schemas.WebhookDelivery, err error) {
+	if _, err = getOwnWebhook(user, webhookID); err != nil {
+		return
+	}
+
+	deliveries = []schemas.WebhookDelivery{}
+	err = database.DB.NewSelect().
+		Model(&deliveries).
+		Where("webhook_id = ?", webhookID).
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Scan(context.Background())
+	return
+}
+
+// PingWebhook queues a ping delivery so users can check that their endpoint works
+func PingWebhook(user *schemas.URUser, webhookID int32) error {
+	webhook, err := getOwnWebhook(user, webhookID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(WebhookPayload{Event: EventWebhookPing, Timestamp: time.Now().Unix()})
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.NewInsert().Model(&schemas.WebhookDelivery{
+		WebhookID: webhook.ID,
+		Event:     string(EventWebhookPing),
+		Payload:   string(payload),
+	}).Exec(context.Background())
+	return err
+}
+
+// queueWebhookDeliveries queues the payload for every webhook of the profile's owner that wants the event
+func queueWebhookDeliveries(payload WebhookPayload) {
+	webhooks := []schemas.UserWebhook{}
+	err := database.DB.NewSelect().
+		Model(&webhooks).
+		Join("JOIN users AS u ON u.id = user_webhook.user_id").
+		Where("u.platform = ? AND u.discord_id = ?", payload.Platform, payload.ProfileID).
+		Where("coalesce(cardinality(user_webhook.events), 0) = 0 OR ? = ANY(user_webhook.events)", string(payload.Event)).
+		Scan(context.Background())
+	if err != nil {
+		fmt.Println("failed to load webhooks:", err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload.Timestamp = time.Now().Unix()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Println("failed to encode webhook payload:", err)
+		return
+	}
+
+	deliveries := make([]schemas.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = schemas.WebhookDelivery{
+			WebhookID: webhook.ID,
+			Event:     string(payload.Event),
+			Payload:   string(body),
+		}
+	}
+
+	if _, err = database.DB.NewInsert().Model(&deliveries).Exec(context.Background()); err != nil {
+		fmt.Println("failed to queue webhook deliveries:", err)
+	}
+}
+
+// sendWebhook posts a delivery, any status other than 2xx is an error
+func sendWebhook(client *http.Client, webhook *schemas.UserWebhook, delivery *schemas.WebhookDelivery) (statusCode int, err error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ReviewDB-Webhooks")
+	req.Header.Set("X-ReviewDB-Event", delivery.Event)
+	req.Header.Set("X-ReviewDB-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-ReviewDB-Timestamp", timestamp)
+	req.Header.Set("X-ReviewDB-Signature", SignWebhookPayload(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// recordWebhookAttempt stores the outcome of an attempt and schedules the next one
+func recordWebhookAttempt(delivery *schemas.WebhookDelivery, statusCode int, sendErr error) error {
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	now := time.Now()
+	switch {
+	case sendErr == nil:
+		delivery.Status = schemas.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= maxWebhookAttempts:
+		delivery.Status = schemas.WebhookDeliveryFailed
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.LastError = sendErr.Error()
+		delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts))
+	}
+
+	_, err := database.DB.NewUpdate().
+		Model(delivery).
+		Column("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at").
+		WherePK().
+		Exec(context.Background())
+	return err
+}
+
+// deliverDueWebhooks claims pending deliveries that are due and sends them. Claiming pushes next_attempt_at
+// back, so several instances can run the worker without sending a delivery twice.
+func deliverDueWebhooks(client *http.Client) error {
+	deliveries := []schemas.WebhookDelivery{}
+	claimedUntil := time.Now().Add(webhookClaimDuration)
+	err := database.DB.NewUpdate().
+		Model((*schemas.WebhookDelivery)(nil)).
+		Set("next_attempt_at = ?", claimedUntil).
+		Where("id IN (?)", database.DB.NewSelect().
+			Model((*schemas.WebhookDelivery)(nil)).
+			Column("id").
+			Where("status = ?", schemas.WebhookDeliveryPending).
+			Where("next_attempt_at <= now()").
+			Order("next_attempt_at").
+			Limit(webhookBatchSize).
+			For("UPDATE SKIP LOCKED")).
+		Returning("*").
+		Scan(context.Background(), &deliveries)
+	if err != nil || len(deliveries) == 0 {
+		return err
+	}
+
+	webhookIDs := make([]int32, len(deliveries))
+	for i, delivery := range deliveries {
+		webhookIDs[i] = delivery.WebhookID
+	}
+
+	webhooks := []schemas.UserWebhook{}
+	err = database.DB.NewSelect().Model(&webhooks).Where("id IN (?)", bun.In(webhookIDs)).Scan(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		// the rest stays claimed and is sent once the claim runs out, sending it now could race another instance
+		if time.Until(claimedUntil) < webhookSendTimeout {
+			break
+		}
+
+		delivery := &deliveries[i]
+		index := slices.IndexFunc(webhooks, func(webhook schemas.UserWebhook) bool { return webhook.ID == delivery.WebhookID })
+		if index == -1 {
+			// the webhook was deleted after the delivery was claimed
+			continue
+		}
+
+		statusCode, sendErr := sendWebhook(client, &webhooks[index], delivery)
+		if err := recordWebhookAttempt(delivery, statusCode, sendErr); err != nil {
+			fmt.Println("failed to record webhook attempt:", err)
+		}
+	}
+	return nil
+}
+
+func StartWebhookDeliveryWorker() {
+	for range time.Tick(5 * time.Second) {
+		if err := deliverDueWebhooks(webhookClient); err != nil {
+			fmt.Println("failed to deliver webhooks:", err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package modules
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server-go/database/schemas"
+)
+
+func TestSendWebhookSignsDelivery(t *testing.T) {
+	webhook := &schemas.UserWebhook{Secret: "whsec_test"}
+	delivery := &schemas.WebhookDelivery{ID: 7, Event: string(EventReviewAdded), Payload: `{"event":"review.added","reviewId":1}`}
+
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	webhook.URL = server.URL
+
+	statusCode, err := sendWebhook(server.Client(), webhook, delivery)
+	if err != nil || statusCode != http.StatusNoContent {
+		t.Fatalf("sendWebhook = %d, %v", statusCode, err)
+	}
+
+	if string(body) != delivery.Payload {
+		t.Errorf("body = %s, want %s", body, delivery.Payload)
+	}
+	if received.Header.Get("X-ReviewDB-Event") != "review.added" || received.Header.Get("X-ReviewDB-Delivery") != "7" {
+		t.Errorf("unexpected headers %v", received.Header)
+	}
+
+	want := SignWebhookPayload("whsec_test", received.Header.Get("X-ReviewDB-Timestamp"), body)
+	if got := received.Header.Get("X-ReviewDB-Signature"); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+	if SignWebhookPayload("other secret", received.Header.Get("X-ReviewDB-Timestamp"), body) == want {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestSendWebhookFailsOnErrorStatus(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusFound} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", "/elsewhere")
+			w.WriteHeader(status)
+		}))
+
+		client := server.Client()
+		client.CheckRedirect = webhookClient.CheckRedirect
+
+		statusCode, err := sendWebhook(client, &schemas.UserWebhook{URL: server.URL}, &schemas.WebhookDelivery{Payload: "{}"})
+		if err == nil || statusCode != status {
+			t.Errorf("sendWebhook = %d, %v, want an error with status %d", statusCode, err, status)
+		}
+		server.Close()
+	}
+}
+
+func TestWebhookClientRejectsPrivateAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	_, err := sendWebhook(webhookClient, &schemas.UserWebhook{URL: server.URL}, &schemas.WebhookDelivery{Payload: "{}"})
+	if err == nil || called {
+		t.Fatal("webhook client connected to a loopback address")
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for url, valid := range map[string]bool{
+		"https://example.com/hook":     true,
+		"http://example.com/hook":      false,
+		"https://user:pw@example.com/": false,
+		"https://127.0.0.1/hook":       false,
+		"https://10.0.0.1/hook":        false,
+		"https://[::1]/hook":           false,
+		"not a url":                    false,
+	} {
+		if err := validateWebhookURL(url); (err == nil) != valid {
+			t.Errorf("validateWebhookURL(%q) = %v, want valid %v", url, err, valid)
+		}
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	if webhookBackoff(1) != 30*time.Second || webhookBackoff(2) != time.Minute || webhookBackoff(3) != 2*time.Minute {
+		t.Errorf("backoff does not double from 30 seconds: %v %v %v", webhookBackoff(1), webhookBackoff(2), webhookBackoff(3))
+	}
+	if webhookBackoff(maxWebhookAttempts) > 6*time.Hour || webhookBackoff(100) != 6*time.Hour {
+		t.Errorf("backoff is not capped at 6 hours: %v", webhookBackoff(100))
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
-	"fmt"
 	"server-go/database"
 	"server-go/database/schemas"
 )
@@ -25,7 +24,10 @@ func SendNotification(notification *schemas.Notification) (err error) {
 	_, err = database.DB.NewInsert().Model(notification).Exec(context.Background())
 	if err != nil {
 		println(err.Error())
+		return
 	}
+
+	Events.Publish(Event{Type: EventNotification, Topic: UserTopic(notification.UserID), Notification: notification})
 	return
 }
 
@@ -37,8 +39,8 @@ func ReadNotification(user *schemas.URUser, notificationId int32) (err error) {
 
 	rowsAffected, err := res.RowsAffected()
 
-	if rowsAffected == 0 {
-		fmt.Println("Couldnt delete notification")
+	if err == nil && rowsAffected == 0 {
+		err = NotFoundError("notification_not_found", "Notification not found")
 	}
 	return
 }
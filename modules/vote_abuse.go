@@ -0,0 +1,173 @@
+package modules
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"server-go/database"
+	"server-go/database/schemas"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	// votes from accounts younger than this are not worth any reputation
+	VoteWeightMinAccountAge = 90 * 24 * time.Hour
+	// old accounts with this much reputation count twice
+	VoteWeightTrustedAccountAge = 365 * 24 * time.Hour
+	VoteWeightTrustedReputation = 25
+)
+
+// hash of an empty ip, users that registered without CF-Connecting-IP all share it
+var emptyIPHash = CalculateHash("")
+
+// VoteWeight returns how much reputation a vote from voter is worth to the author of the review.
+// The review score itself always changes by one, only reputation is weighted.
+func VoteWeight(voter *schemas.URUser, author *schemas.URUser) int {
+	if voter.IpHash != "" && voter.IpHash != emptyIPHash && voter.IpHash == author.IpHash {
+		return 0
+	}
+
+	if voter.Reputation < 0 {
+		return 0
+	}
+
+	accountAge := time.Since(accountCreatedAt(voter))
+
+	if accountAge < VoteWeightMinAccountAge {
+		return 0
+	}
+
+	if accountAge >= VoteWeightTrustedAccountAge && voter.Reputation >= VoteWeightTrustedReputation {
+		return 2
+	}
+
+	return 1
+}
+
+// twitter snowflakes count milliseconds since 2010-11-04, discord ones since 2015
+const twitterEpoch = 1288834974657
+
+// accountCreatedAt reads when the account was created from its id on its platform
+func accountCreatedAt(user *schemas.URUser) time.Time {
+	id, _ := strconv.ParseUint(user.DiscordID, 10, 64)
+	if user.Platform == schemas.PlatformTwitter {
+		// accounts from before twitter used snowflakes have small sequential ids and end up at the epoch
+		return time.UnixMilli(int64(id>>22) + twitterEpoch)
+	}
+	return discord.Snowflake(id).Time()
+}
+
+type VoteRing struct {
+	Members     []string `json:"members"`
+	MutualVotes int      `json:"mutualVotes"`
+}
+
+type SharedIPVoters struct {
+	IpHash          string   `bun:"ip_hash" json:"ipHash"`
+	AuthorDiscordID string   `bun:"author_discord_id" json:"authorDiscordID"`
+	VoterDiscordIDs []string `bun:"voter_discord_ids,array" json:"voterDiscordIDs"`
+	Votes           int      `bun:"votes" json:"votes"`
+}
+
+type VoteAbuseReport struct {
+	VoteRings      []VoteRing       `json:"voteRings"`
+	SharedIPVoters []SharedIPVoters `json:"sharedIPVoters"`
+}
+
+type mutualVotePair struct {
+	UserA string `bun:"user_a"`
+	UserB string `bun:"user_b"`
+	Votes int    `bun:"votes"`
+}
+
+// GetVoteAbuseReport finds groups of accounts that upvote each other's reviews at least minVotes times in both
+// directions, and accounts sharing an ip hash that upvote the same author
+func GetVoteAbuseReport(minVotes int) (report VoteAbuseReport, err error) {
+	pairs := []mutualVotePair{}
+	err = database.DB.NewRaw(`
+		WITH upvotes AS (
+			SELECT rv.voter_id, r.reviewer_id AS author_id, COUNT(*) AS votes
+			FROM review_votes AS rv
+			JOIN reviews AS r ON r.id = rv.review_id AND r.deleted_at IS NULL
+			WHERE rv.is_upvote
+			GROUP BY rv.voter_id, r.reviewer_id
+		)
+		SELECT ua.discord_id AS user_a, ub.discord_id AS user_b, a.votes + b.votes AS votes
+		FROM upvotes AS a
+		JOIN upvotes AS b ON b.voter_id = a.author_id AND b.author_id = a.voter_id
+		JOIN users AS ua ON ua.id = a.voter_id
+		JOIN users AS ub ON ub.id = a.author_id
+		WHERE a.voter_id < a.author_id AND a.votes >= ? AND b.votes >= ?
+		ORDER BY votes DESC
+		LIMIT 1000
+	`, minVotes, minVotes).Scan(context.Background(), &pairs)
+	if err != nil {
+		return
+	}
+
+	report.VoteRings = groupVoteRings(pairs)
+
+	report.SharedIPVoters = []SharedIPVoters{}
+	err = database.DB.NewRaw(`
+		SELECT voter.ip_hash, author.discord_id AS author_discord_id,
+			array_agg(DISTINCT voter.discord_id::text) AS voter_discord_ids, COUNT(*) AS votes
+		FROM review_votes AS rv
+		JOIN reviews AS r ON r.id = rv.review_id AND r.deleted_at IS NULL
+		JOIN users AS voter ON voter.id = rv.voter_id
+		JOIN users AS author ON author.id = r.reviewer_id
+		WHERE rv.is_upvote AND voter.ip_hash IS NOT NULL AND voter.ip_hash NOT IN ('', ?)
+		GROUP BY voter.ip_hash, author.discord_id
+		HAVING COUNT(DISTINCT rv.voter_id) >= 2
+		ORDER BY votes DESC
+		LIMIT 100
+	`, emptyIPHash).Scan(context.Background(), &report.SharedIPVoters)
+	return
+}
+
+// groupVoteRings merges mutual voting pairs that share an account into rings
+func groupVoteRings(pairs []mutualVotePair) []VoteRing {
+	parent := map[string]string{}
+	var find func(id string) string
+	find = func(id string) string {
+		if parent[id] == "" || parent[id] == id {
+			parent[id] = id
+			return id
+		}
+		parent[id] = find(parent[id])
+		return parent[id]
+	}
+
+	for _, pair := range pairs {
+		parent[find(pair.UserA)] = find(pair.UserB)
+	}
+
+	ringIndex := map[string]int{}
+	rings := []VoteRing{}
+	for _, pair := range pairs {
+		root := find(pair.UserA)
+		ix, ok := ringIndex[root]
+		if !ok {
+			ix = len(rings)
+			ringIndex[root] = ix
+			rings = append(rings, VoteRing{Members: []string{}})
+		}
+		rings[ix].MutualVotes += pair.Votes
+	}
+
+	for id := range parent {
+		ix := ringIndex[find(id)]
+		rings[ix].Members = append(rings[ix].Members, id)
+	}
+
+	for _, ring := range rings {
+		sort.Strings(ring.Members)
+	}
+	sort.Slice(rings, func(i, j int) bool {
+		return rings[i].MutualVotes > rings[j].MutualVotes
+	})
+
+	return rings
+}
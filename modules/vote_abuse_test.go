@@ -0,0 +1,35 @@
+package modules
+
+import (
+	"testing"
+	"time"
+
+	"server-go/database/schemas"
+)
+
+func TestAccountCreatedAtPerPlatform(t *testing.T) {
+	for _, test := range []struct {
+		user schemas.URUser
+		want time.Time
+	}{
+		// discord's own example snowflake
+		{schemas.URUser{Platform: schemas.PlatformDiscord, DiscordID: "175928847299117063"}, time.UnixMilli(1462015105796)},
+		// ids from before snowflakes are small and sequential
+		{schemas.URUser{Platform: schemas.PlatformTwitter, DiscordID: "20"}, time.UnixMilli(1288834974657)},
+		{schemas.URUser{Platform: schemas.PlatformTwitter, DiscordID: "1590000000000000000"}, time.UnixMilli(1667920515428)},
+	} {
+		if got := accountCreatedAt(&test.user); !got.Equal(test.want) {
+			t.Errorf("accountCreatedAt(%s %s) = %s, want %s", test.user.Platform, test.user.DiscordID, got, test.want)
+		}
+	}
+}
+
+func TestVoteWeightOfTwitterVoters(t *testing.T) {
+	author := &schemas.URUser{}
+	// created in november 2022, discord's epoch would place it in 2027
+	voter := &schemas.URUser{Platform: schemas.PlatformTwitter, DiscordID: "1590000000000000000", Reputation: VoteWeightTrustedReputation}
+
+	if weight := VoteWeight(voter, author); weight != 2 {
+		t.Errorf("VoteWeight(old trusted twitter account) = %d, want 2", weight)
+	}
+}
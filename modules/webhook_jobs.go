@@ -0,0 +1,307 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server-go/common"
+	"server-go/database"
+	"server-go/database/schemas"
+	discord_utils "server-go/modules/discord"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// targets are resolved to a webhook url when a job is sent, so config reloads apply to queued jobs
+const (
+	WebhookTargetLogger        = "logger"
+	WebhookTargetReport        = "report"
+	WebhookTargetJunkReport    = "junk_report"
+	WebhookTargetTwitterReport = "twitter_report"
+	WebhookTargetAppeal        = "appeal"
+)
+
+const (
+	// the payload is the discord_utils.WebhookData to send
+	webhookJobMessage = "message"
+	// the payload is a reportJob, the worker builds the message because it translates and rates the review
+	webhookJobReport = "report"
+)
+
+const (
+	maxWebhookJobAttempts = 8
+	webhookJobBatchSize   = 20
+	// jobs are claimed one at a time for this long, building a report translates and rates the review before it
+	// is posted so a job can take a while. A worker that dies while sending leaves the job to the next one.
+	webhookJobClaimDuration = 2 * time.Minute
+)
+
+var ErrWebhookJobNotFound = NotFoundError("webhook_job_not_found", "Webhook job not found")
+
+type reportJob struct {
+	ReportID int32 `json:"reportId"`
+}
+
+func webhookTargetURL(target string) string {
+	switch target {
+	case WebhookTargetLogger:
+		return common.Config.LoggerWebhook
+	case WebhookTargetReport:
+		return common.Config.ReportWebhook
+	case WebhookTargetJunkReport:
+		return common.Config.JunkReportWebhook
+	case WebhookTargetTwitterReport:
+		if common.Config.TwitterReportWebhook != "" {
+			return common.Config.TwitterReportWebhook
+		}
+		return common.Config.ReportWebhook
+	case WebhookTargetAppeal:
+		return common.Config.AppealWebhook
+	}
+	return ""
+}
+
+// QueueDiscordWebhook queues a message for a staff channel, failures are only logged so callers don't fail on them
+func QueueDiscordWebhook(target string, data discord_utils.WebhookData) {
+	if err := QueueDiscordWebhookTx(database.DB, target, data); err != nil {
+		fmt.Println("failed to queue discord webhook:", err)
+	}
+}
+
+// QueueDiscordWebhookTx queues a message with db, pass a transaction to only send it when the transaction commits
+func QueueDiscordWebhookTx(db bun.IDB, target string, data discord_utils.WebhookData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.NewInsert().Model(&schemas.WebhookJob{
+		Kind:    webhookJobMessage,
+		Target:  target,
+		Payload: string(payload),
+	}).Exec(context.Background())
+	return err
+}
+
+func queueReportWebhook(db bun.IDB, reportID int32) error {
+	payload, err := json.Marshal(reportJob{ReportID: reportID})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.NewInsert().Model(&schemas.WebhookJob{
+		Kind:    webhookJobReport,
+		Payload: string(payload),
+	}).Exec(context.Background())
+	return err
+}
+
+// buildReportWebhook turns a report job into a message job, so a retry sends the same message without
+// translating and rating the review again
+func buildReportWebhook(job *schemas.WebhookJob) error {
+	var payload reportJob
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+
+	report := schemas.ReviewReport{}
+	err := database.DB.NewSelect().Model(&report).Where("id = ?", payload.ReportID).Scan(context.Background())
+	if err != nil {
+		return err
+	}
+
+	review, err := GetReview(report.ReviewID)
+	if err != nil {
+		return err
+	}
+	reporter, err := GetDBUserViaID(report.ReporterID)
+	if err != nil {
+		return err
+	}
+	reportedUser, err := GetDBUserViaID(review.ReviewerID)
+	if err != nil {
+		return err
+	}
+	revisions, err := GetReviewRevisions(review.ID)
+	if err != nil {
+		return err
+	}
+
+	// reports go out without a translation when translating fails, the report matters more
+	translated, err := TranslateReview(&review)
+	if err != nil {
+		fmt.Println("failed to translate reported review:", err)
+	}
+
+	data, flagged := discord_utils.ReportWebhook(&reporter, &review, &reportedUser, revisions, translated)
+	message, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	job.Kind = webhookJobMessage
+	job.Target = WebhookTargetJunkReport
+	if flagged {
+		job.Target = WebhookTargetReport
+	}
+	job.Payload = string(message)
+
+	_, err = database.DB.NewUpdate().Model(job).Column("kind", "target", "payload").WherePK().Exec(context.Background())
+	return err
+}
+
+func sendWebhookJob(job *schemas.WebhookJob) error {
+	var data discord_utils.WebhookData
+	if err := json.Unmarshal([]byte(job.Payload), &data); err != nil {
+		return err
+	}
+	return discord_utils.SendWebhook(webhookTargetURL(job.Target), data)
+}
+
+// applyWebhookJobResult records the outcome of sending a job. Rate limits don't count as an attempt, the returned
+// duration is how long discord wants the job's target to be left alone.
+func applyWebhookJobResult(job *schemas.WebhookJob, sendErr error, now time.Time) (rateLimitedFor time.Duration) {
+	if sendErr == nil {
+		job.Attempts++
+		job.Status = schemas.WebhookJobSent
+		job.LastError = ""
+		job.SentAt = &now
+		return 0
+	}
+
+	job.LastError = sendErr.Error()
+
+	var webhookErr *discord_utils.WebhookError
+	isWebhookErr := errors.As(sendErr, &webhookErr)
+	if isWebhookErr && webhookErr.StatusCode == 429 {
+		rateLimitedFor = max(webhookErr.RetryAfter, time.Second)
+		job.NextAttemptAt = now.Add(rateLimitedFor)
+		return rateLimitedFor
+	}
+
+	job.Attempts++
+	switch {
+	case errors.Is(sendErr, discord_utils.ErrWebhookNotConfigured), isWebhookErr && !webhookErr.Temporary():
+		// sending it again won't help, an admin can retry the job after fixing the webhook
+		job.Status = schemas.WebhookJobDead
+	case job.Attempts >= maxWebhookJobAttempts:
+		job.Status = schemas.WebhookJobDead
+	default:
+		job.NextAttemptAt = now.Add(webhookBackoff(job.Attempts))
+	}
+	return 0
+}
+
+func saveWebhookJob(job *schemas.WebhookJob) error {
+	_, err := database.DB.NewUpdate().
+		Model(job).
+		Column("status", "attempts", "next_attempt_at", "last_error", "sent_at").
+		WherePK().
+		Exec(context.Background())
+	return err
+}
+
+// claimWebhookJob claims the next due job so other instances don't send it too, nil when there is none
+func claimWebhookJob() (*schemas.WebhookJob, error) {
+	jobs := []schemas.WebhookJob{}
+	err := database.DB.NewUpdate().
+		Model((*schemas.WebhookJob)(nil)).
+		Set("next_attempt_at = ?", time.Now().Add(webhookJobClaimDuration)).
+		Where("id IN (?)", database.DB.NewSelect().
+			Model((*schemas.WebhookJob)(nil)).
+			Column("id").
+			Where("status = ?", schemas.WebhookJobPending).
+			Where("next_attempt_at <= now()").
+			Order("next_attempt_at").
+			Limit(1).
+			For("UPDATE SKIP LOCKED")).
+		Returning("*").
+		Scan(context.Background(), &jobs)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// sendDueWebhookJobs sends up to a batch of due jobs. Once discord rate limits a target, the rest of the batch's
+// jobs for it wait until the rate limit is over.
+func sendDueWebhookJobs() error {
+	rateLimitedUntil := map[string]time.Time{}
+	for i := 0; i < webhookJobBatchSize; i++ {
+		job, err := claimWebhookJob()
+		if err != nil || job == nil {
+			return err
+		}
+
+		processWebhookJob(job, rateLimitedUntil)
+		if err := saveWebhookJob(job); err != nil {
+			fmt.Println("failed to record webhook job:", err)
+		}
+	}
+	return nil
+}
+
+func processWebhookJob(job *schemas.WebhookJob, rateLimitedUntil map[string]time.Time) {
+	if job.Kind == webhookJobReport {
+		err := buildReportWebhook(job)
+		if errors.Is(err, sql.ErrNoRows) {
+			// the report, review or one of the users is gone
+			job.Attempts++
+			job.Status = schemas.WebhookJobDead
+			job.LastError = err.Error()
+			return
+		}
+		if err != nil {
+			applyWebhookJobResult(job, err, time.Now())
+			return
+		}
+	}
+
+	if until, ok := rateLimitedUntil[job.Target]; ok {
+		job.NextAttemptAt = until
+		return
+	}
+
+	if applyWebhookJobResult(job, sendWebhookJob(job), time.Now()) > 0 {
+		rateLimitedUntil[job.Target] = job.NextAttemptAt
+	}
+}
+
+func StartWebhookJobWorker() {
+	for range time.Tick(2 * time.Second) {
+		if err := sendDueWebhookJobs(); err != nil {
+			fmt.Println("failed to send discord webhooks:", err)
+		}
+	}
+}
+
+func GetWebhookJobs(status string, offset int, limit int) (jobs []schemas.WebhookJob, err error) {
+	jobs = []schemas.WebhookJob{}
+	query := database.DB.NewSelect().Model(&jobs).Order("id DESC").Offset(offset).Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err = query.Scan(context.Background())
+	return
+}
+
+// RetryWebhookJob gives a dead job a fresh set of attempts
+func RetryWebhookJob(jobID int64) error {
+	res, err := database.DB.NewUpdate().
+		Model((*schemas.WebhookJob)(nil)).
+		Set("status = ?", schemas.WebhookJobPending).
+		Set("attempts = 0").
+		Set("next_attempt_at = now()").
+		Where("id = ? AND status = ?", jobID, schemas.WebhookJobDead).
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+	if updated, _ := res.RowsAffected(); updated == 0 {
+		return ErrWebhookJobNotFound
+	}
+	return nil
+}
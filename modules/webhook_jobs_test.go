@@ -0,0 +1,47 @@
+package modules
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"server-go/database/schemas"
+	discord_utils "server-go/modules/discord"
+)
+
+func TestApplyWebhookJobResult(t *testing.T) {
+	now := time.Now()
+
+	job := &schemas.WebhookJob{Status: schemas.WebhookJobPending}
+	if applyWebhookJobResult(job, nil, now) != 0 || job.Status != schemas.WebhookJobSent || job.SentAt == nil || job.Attempts != 1 {
+		t.Errorf("sent job = %+v", job)
+	}
+
+	job = &schemas.WebhookJob{Status: schemas.WebhookJobPending, Attempts: 2}
+	rateLimitedFor := applyWebhookJobResult(job, &discord_utils.WebhookError{StatusCode: 429, RetryAfter: 3 * time.Second}, now)
+	if rateLimitedFor != 3*time.Second || job.Attempts != 2 || job.Status != schemas.WebhookJobPending || !job.NextAttemptAt.Equal(now.Add(3*time.Second)) {
+		t.Errorf("rate limited job = %+v, rate limited for %v", job, rateLimitedFor)
+	}
+
+	for _, err := range []error{&discord_utils.WebhookError{StatusCode: 502}, errors.New("connection reset")} {
+		job = &schemas.WebhookJob{Status: schemas.WebhookJobPending, Attempts: 1}
+		applyWebhookJobResult(job, err, now)
+		if job.Attempts != 2 || job.Status != schemas.WebhookJobPending || !job.NextAttemptAt.Equal(now.Add(webhookBackoff(2))) {
+			t.Errorf("job failing with %v = %+v, want a retry with backoff", err, job)
+		}
+	}
+
+	for _, err := range []error{&discord_utils.WebhookError{StatusCode: 404}, discord_utils.ErrWebhookNotConfigured} {
+		job = &schemas.WebhookJob{Status: schemas.WebhookJobPending}
+		applyWebhookJobResult(job, err, now)
+		if job.Status != schemas.WebhookJobDead || job.LastError == "" {
+			t.Errorf("job failing with %v = %+v, want it dead", err, job)
+		}
+	}
+
+	job = &schemas.WebhookJob{Status: schemas.WebhookJobPending, Attempts: maxWebhookJobAttempts - 1}
+	applyWebhookJobResult(job, &discord_utils.WebhookError{StatusCode: 500}, now)
+	if job.Status != schemas.WebhookJobDead {
+		t.Errorf("job out of attempts = %+v, want it dead", job)
+	}
+}
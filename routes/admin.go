@@ -3,21 +3,26 @@ package routes
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"server-go/common"
 	"server-go/database/schemas"
 	"server-go/modules"
+	"server-go/modules/translation"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
+type FiltersResponse struct {
+	ProfaneWords      []string `json:"profaneWords"`
+	LightProfaneWords []string `json:"lightProfaneWords"`
+	BanWords          []string `json:"banWords"`
+}
+
 func GetFilters(w http.ResponseWriter, r *http.Request) {
-	response := struct {
-		ProfaneWords      []string `json:"profaneWords"`
-		LightProfaneWords []string `json:"lightProfaneWords"`
-		BanWords          []string `json:"banWords"`
-	}{}
+	response := FiltersResponse{}
 
 	response.ProfaneWords = common.Config.ProfaneWordList
 	response.LightProfaneWords = common.Config.LightProfaneWordList
@@ -54,7 +59,7 @@ func AddFilter(w http.ResponseWriter, r *http.Request) {
 
 	common.SaveConfig()
 	common.LoadConfig()
-	w.WriteHeader(http.StatusOK)
+	common.SendStructResponse(w, Response{Success: true, Message: "Added filter"})
 }
 
 func DeleteFilter(w http.ResponseWriter, r *http.Request) {
@@ -86,16 +91,20 @@ func DeleteFilter(w http.ResponseWriter, r *http.Request) {
 	}
 	common.SaveConfig()
 	common.LoadConfig()
+	common.SendStructResponse(w, Response{Success: true, Message: "Removed filter"})
 }
 
 func GetReports(w http.ResponseWriter, r *http.Request) {
 	limit := common.GetIntQueryOrDefault(r, "limit", 50)
 	offset := common.GetIntQueryOrDefault(r, "offset", 0)
+	if limit <= 0 || limit > 100 || offset < 0 {
+		Error(w, modules.ValidationError("invalid_limit_or_offset", "Invalid limit or offset"))
+		return
+	}
 
 	reports, err := modules.GetReports(offset, limit)
 	if err != nil {
-		fmt.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 
@@ -104,19 +113,24 @@ func GetReports(w http.ResponseWriter, r *http.Request) {
 
 func ReloadConfig(w http.ResponseWriter, r *http.Request) {
 	common.LoadConfig()
+	translation.Reload()
+	common.SendStructResponse(w, Response{Success: true, Message: "Reloaded config"})
 }
 
 func GetUsersAdmin(w http.ResponseWriter, r *http.Request) {
 	limit := common.GetIntQueryOrDefault(r, "limit", 50)
 	offset := common.GetIntQueryOrDefault(r, "offset", 0)
+	if limit <= 0 || limit > 100 || offset < 0 {
+		Error(w, modules.ValidationError("invalid_limit_or_offset", "Invalid limit or offset"))
+		return
+	}
 	query := r.URL.Query().Get("query")
 	ip_hash := common.GetQueryOrDefault(r, "ip_hash", "")
 
 	err, users := modules.GetUsersAdmin(query, limit, offset, ip_hash)
 
 	if err != nil {
-		fmt.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 
@@ -129,11 +143,11 @@ func PatchUserAdmin(w http.ResponseWriter, r *http.Request) {
 
 	err := modules.PatchUserAdmin(user)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	common.SendStructResponse(w, Response{Success: true, Message: "Updated user"})
 }
 
 func GetUserAdmin(w http.ResponseWriter, r *http.Request) {
@@ -142,42 +156,295 @@ func GetUserAdmin(w http.ResponseWriter, r *http.Request) {
 
 	user, err := modules.GetUserAdmin(id)
 	if err != nil {
-		if err == sql.ErrNoRows{ 
-			w.WriteHeader(http.StatusNotFound)
+		if err == sql.ErrNoRows {
+			Error(w, modules.NotFoundError("user_not_found", "User not found"))
 			return
 		}
-		fmt.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 	common.SendStructResponse(w, user)
 }
 
-func AddBadge(w http.ResponseWriter, r *http.Request) {
-	var badge schemas.UserBadge
-	json.NewDecoder(r.Body).Decode(&badge)
+func parseBadgeID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	badgeID, err := strconv.ParseInt(chi.URLParam(r, "badgeid"), 10, 32)
+	if err != nil || badgeID <= 0 {
+		Error(w, modules.ValidationError("invalid_badge_id", "Invalid badge ID"))
+		return 0, false
+	}
+	return int32(badgeID), true
+}
+
+func GetBadgeDefinitions(w http.ResponseWriter, r *http.Request) {
+	definitions, err := modules.GetBadgeDefinitions()
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, definitions)
+}
+
+func CreateBadgeDefinition(w http.ResponseWriter, r *http.Request) {
+	var definition schemas.BadgeDefinition
+	if err := json.NewDecoder(r.Body).Decode(&definition); err != nil {
+		Error(w, modules.ErrInvalidRequest)
+		return
+	}
+
+	if err := modules.CreateBadgeDefinition(&definition); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, definition)
+}
+
+func UpdateBadgeDefinition(w http.ResponseWriter, r *http.Request) {
+	badgeID, ok := parseBadgeID(w, r)
+	if !ok {
+		return
+	}
+
+	definition, err := modules.GetBadgeDefinition(badgeID)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	// fields missing from the body keep their current value
+	if err := json.NewDecoder(r.Body).Decode(definition); err != nil {
+		Error(w, modules.ErrInvalidRequest)
+		return
+	}
+	definition.ID = badgeID
+
+	if err := modules.UpdateBadgeDefinition(definition); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, definition)
+}
+
+func DeleteBadgeDefinition(w http.ResponseWriter, r *http.Request) {
+	badgeID, ok := parseBadgeID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := modules.DeleteBadgeDefinition(badgeID); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Badge deleted"})
+}
+
+func GetBadgeAssignments(w http.ResponseWriter, r *http.Request) {
+	badgeID, ok := parseBadgeID(w, r)
+	if !ok {
+		return
+	}
+
+	assignments, err := modules.GetBadgeAssignments(badgeID)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, assignments)
+}
+
+type AssignBadgeRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func AssignBadge(w http.ResponseWriter, r *http.Request) {
+	badgeID, ok := parseBadgeID(w, r)
+	if !ok {
+		return
+	}
+
+	discordID := chi.URLParam(r, "discordid")
+	if _, err := strconv.ParseUint(discordID, 10, 64); err != nil {
+		Error(w, modules.ValidationError("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	var body AssignBadgeRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			Error(w, modules.ErrInvalidRequest)
+			return
+		}
+	}
+
+	if err := modules.AssignBadge(badgeID, discordID, body.ExpiresAt); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Badge assigned"})
+}
+
+func UnassignBadge(w http.ResponseWriter, r *http.Request) {
+	badgeID, ok := parseBadgeID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := modules.UnassignBadge(badgeID, chi.URLParam(r, "discordid")); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Badge removed"})
+}
+
+type ReviewRevisionsResponse struct {
+	Review    schemas.UserReview       `json:"review"`
+	Revisions []schemas.ReviewRevision `json:"revisions"`
+}
+
+func GetReviewRevisions(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := strconv.ParseInt(chi.URLParam(r, "reviewid"), 10, 32)
+	if err != nil || reviewID <= 0 {
+		Error(w, modules.ValidationError("invalid_review_id", "Invalid review ID"))
+		return
+	}
+
+	review, err := modules.GetReview(int32(reviewID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			Error(w, modules.NotFoundError("review_not_found", "Review not found"))
+			return
+		}
+		Error(w, err)
+		return
+	}
+
+	revisions, err := modules.GetReviewRevisions(review.ID)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, ReviewRevisionsResponse{review, revisions})
+}
+
+func RestoreReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := strconv.ParseInt(chi.URLParam(r, "reviewid"), 10, 32)
+	if err != nil || reviewID <= 0 {
+		Error(w, modules.ValidationError("invalid_review_id", "Invalid review ID"))
+		return
+	}
+
+	// the configured admin token has no user behind it
+	var actorID int32
+	if user, err := Authorize(r); err == nil {
+		actorID = user.ID
+	}
 
-	err := modules.AddBadge(badge)
+	err = modules.RestoreReview(int32(reviewID), actorID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	common.SendStructResponse(w, Response{Success: true, Message: "Successfully restored review"})
 }
 
-func DeleteBadge(w http.ResponseWriter, r *http.Request) {
-	id := common.GetQueryOrDefault(r, "id", "")
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+func GetVoteAbuseReport(w http.ResponseWriter, r *http.Request) {
+	minVotes := common.GetIntQueryOrDefault(r, "min_votes", 3)
+	if minVotes <= 0 {
+		Error(w, modules.ValidationError("invalid_min_votes", "Invalid min_votes"))
 		return
 	}
 
-	err := modules.DeleteBadge(id)
+	report, err := modules.GetVoteAbuseReport(minVotes)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-}
\ No newline at end of file
+	common.SendStructResponse(w, report)
+}
+
+type BanRequest struct {
+	Duration int32 `json:"duration"` // in days
+	ReviewID int32 `json:"reviewId"` // optional, the review the user is banned for
+}
+
+// BanPlatformUser bans an account of any identity provider by its id on that platform
+func BanPlatformUser(w http.ResponseWriter, r *http.Request) {
+	platform := chi.URLParam(r, "platform")
+	if platform != schemas.PlatformDiscord && platform != schemas.PlatformTwitter {
+		Error(w, modules.ValidationError("invalid_platform", "Invalid platform"))
+		return
+	}
+
+	var body BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Duration <= 0 {
+		Error(w, modules.ErrInvalidRequest)
+		return
+	}
+
+	var review schemas.UserReview
+	if body.ReviewID != 0 {
+		var err error
+		review, err = modules.GetReview(body.ReviewID)
+		if err != nil {
+			Error(w, modules.ValidationError("invalid_review_id", "Invalid review ID"))
+			return
+		}
+	}
+
+	err := modules.BanUserOnPlatform(platform, chi.URLParam(r, "platformid"), r.Header.Get("Authorization"), body.Duration, review)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Successfully banned user"})
+}
+
+func GetWebhookJobs(w http.ResponseWriter, r *http.Request) {
+	limit := common.GetIntQueryOrDefault(r, "limit", 50)
+	offset := common.GetIntQueryOrDefault(r, "offset", 0)
+	if limit <= 0 || limit > 100 || offset < 0 {
+		Error(w, modules.ValidationError("invalid_limit_or_offset", "Invalid limit or offset"))
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", schemas.WebhookJobPending, schemas.WebhookJobSent, schemas.WebhookJobDead:
+	default:
+		Error(w, modules.ValidationError("invalid_status", "Invalid status"))
+		return
+	}
+
+	jobs, err := modules.GetWebhookJobs(status, offset, limit)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, jobs)
+}
+
+func RetryWebhookJob(w http.ResponseWriter, r *http.Request) {
+	jobID, err := strconv.ParseInt(chi.URLParam(r, "jobid"), 10, 64)
+	if err != nil || jobID <= 0 {
+		Error(w, modules.ValidationError("invalid_job_id", "Invalid job ID"))
+		return
+	}
+
+	if err = modules.RetryWebhookJob(jobID); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Queued webhook job again"})
+}
@@ -0,0 +1,478 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server-go/common"
+	"server-go/database/schemas"
+	"server-go/modules"
+	discord_utils "server-go/modules/discord"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/api/cmdroute"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// reviews listed by /reviews, each gets a delete button and a message can have 5 rows of 5 buttons
+const reviewsPerPage = 10
+
+// embeds can only have 6000 characters in total
+const maxListedCommentLength = 500
+
+var errNotAllowed = errors.New("You are not allowed to do this")
+
+// Commands are the slash commands served by /interactions, they are registered with
+// `go run ./cmd/admin register-commands`. The moderation commands are backed by the same modules functions as
+// the admin api and check the capabilities of the staff member running them.
+var Commands = []api.CreateCommandData{
+	{
+		Name:        "resettoken",
+		Description: "Reset a user's token",
+		Options: []discord.CommandOption{
+			&discord.UserOption{OptionName: "user", Description: "User to reset token for, leave blank for self", Required: false},
+		},
+	},
+	{
+		Name:        "reviews",
+		Description: "List the reviews on a user's profile",
+		Options: []discord.CommandOption{
+			&discord.UserOption{OptionName: "user", Description: "User whose profile to list", Required: true},
+		},
+	},
+	{
+		Name:        "ban",
+		Description: "Ban a user from ReviewDB",
+		Options: []discord.CommandOption{
+			&discord.UserOption{OptionName: "user", Description: "User to ban", Required: true},
+			&discord.IntegerOption{OptionName: "days", Description: "Ban duration in days", Required: true, Min: option.NewInt(1), Max: option.NewInt(365)},
+			&discord.StringOption{OptionName: "reason", Description: "Reason shown to the user", Required: false, MaxLength: option.NewInt(500)},
+		},
+	},
+	{
+		Name:        "unban",
+		Description: "Lift the ban of a user",
+		Options: []discord.CommandOption{
+			&discord.UserOption{OptionName: "user", Description: "User to unban", Required: true},
+		},
+	},
+	{
+		Name:        "warn",
+		Description: "Warn a user with a notification",
+		Options: []discord.CommandOption{
+			&discord.UserOption{OptionName: "user", Description: "User to warn", Required: true},
+			&discord.StringOption{OptionName: "reason", Description: "Reason shown to the user", Required: true, MaxLength: option.NewInt(500)},
+		},
+	},
+	{
+		Name:        "optout",
+		Description: "Manage manual opt outs",
+		Options: []discord.CommandOption{
+			&discord.SubcommandOption{
+				OptionName:  "add",
+				Description: "Opt out a user that isn't registered",
+				Options: []discord.CommandOptionValue{
+					&discord.UserOption{OptionName: "user", Description: "User to opt out", Required: true},
+					&discord.StringOption{OptionName: "reason", Description: "Why the user is opted out", Required: false},
+				},
+			},
+			&discord.SubcommandOption{
+				OptionName:  "remove",
+				Description: "Remove a manual opt out",
+				Options: []discord.CommandOptionValue{
+					&discord.UserOption{OptionName: "user", Description: "User to opt in again", Required: true},
+				},
+			},
+		},
+	},
+	{
+		Name:        "badge",
+		Description: "Manage badge assignments",
+		Options: []discord.CommandOption{
+			&discord.SubcommandOption{
+				OptionName:  "grant",
+				Description: "Give a badge to a user",
+				Options: []discord.CommandOptionValue{
+					&discord.UserOption{OptionName: "user", Description: "User to give the badge to", Required: true},
+					&discord.IntegerOption{OptionName: "badge", Description: "Badge id", Required: true, Min: option.NewInt(1)},
+					&discord.IntegerOption{OptionName: "days", Description: "Days until the badge expires, leave blank for never", Required: false, Min: option.NewInt(1)},
+				},
+			},
+			&discord.SubcommandOption{
+				OptionName:  "revoke",
+				Description: "Take a badge from a user",
+				Options: []discord.CommandOptionValue{
+					&discord.UserOption{OptionName: "user", Description: "User to take the badge from", Required: true},
+					&discord.IntegerOption{OptionName: "badge", Description: "Badge id", Required: true, Min: option.NewInt(1)},
+				},
+			},
+		},
+	},
+	{
+		Name:        "lookup",
+		Description: "Show a user's ReviewDB account",
+		Options: []discord.CommandOption{
+			&discord.UserOption{OptionName: "user", Description: "User to look up", Required: true},
+		},
+	},
+}
+
+var commandRouter = newCommandRouter()
+
+func newCommandRouter() *cmdroute.Router {
+	router := cmdroute.NewRouter()
+	// commands that take longer than discord waits for an answer are deferred and followed up
+	router.Use(cmdroute.Deferrable(discord_utils.ArikawaState, cmdroute.DeferOpts{}))
+	router.AddFunc("resettoken", resetTokenCommand)
+	router.AddFunc("reviews", reviewsCommand)
+	router.AddFunc("ban", banCommand)
+	router.AddFunc("unban", unbanCommand)
+	router.AddFunc("warn", warnCommand)
+	router.Sub("optout", func(r *cmdroute.Router) {
+		r.AddFunc("add", addOptOutCommand)
+		r.AddFunc("remove", removeOptOutCommand)
+	})
+	router.Sub("badge", func(r *cmdroute.Router) {
+		r.AddFunc("grant", grantBadgeCommand)
+		r.AddFunc("revoke", revokeBadgeCommand)
+	})
+	router.AddFunc("lookup", lookupCommand)
+	return router
+}
+
+// commandInteraction answers a slash command, body is the interaction as sent by discord
+func commandInteraction(body []byte) (string, error) {
+	var event discord.InteractionEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return "", modules.ValidationError("invalid_interaction", "Invalid interaction")
+	}
+
+	response := commandRouter.HandleInteraction(&event)
+	if response == nil {
+		response = &api.InteractionResponse{
+			Type: api.MessageInteractionWithSource,
+			Data: commandError(errors.New("This command is not supported")),
+		}
+	}
+	return InteractionResponse(response), nil
+}
+
+// commandActor is the ReviewDB account of the user running the command if it has the capability
+func commandActor(event *discord.InteractionEvent, capability schemas.Capability) (*schemas.URUser, error) {
+	actor, err := modules.GetDBUserViaDiscordID(event.SenderID().String())
+	if err != nil {
+		return nil, err
+	}
+	if actor == nil || !actor.Can(capability) {
+		return nil, errNotAllowed
+	}
+	return actor, nil
+}
+
+func commandError(err error) *api.InteractionResponseData {
+	return &api.InteractionResponseData{
+		Content: option.NewNullableString("**Error:** " + err.Error()),
+		Flags:   discord.EphemeralMessage,
+	}
+}
+
+func commandMessage(content string) *api.InteractionResponseData {
+	return &api.InteractionResponseData{
+		Content:         option.NewNullableString(content),
+		AllowedMentions: &api.AllowedMentions{},
+	}
+}
+
+func resetTokenCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User discord.UserID `discord:"user?"`
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	// users can reset their own token, only admins can reset the token of others
+	user := data.Event.SenderID()
+	if options.User.IsValid() && options.User != user {
+		requester, err := modules.GetDBUserViaDiscordID(user.String())
+		if err != nil || requester == nil || !requester.IsAdmin() {
+			return commandError(errors.New("You do not have permission to reset tokens"))
+		}
+		user = options.User
+	}
+
+	if err := modules.ResetToken(user.String()); err != nil {
+		return commandError(errors.New("Error resetting token"))
+	}
+	return &api.InteractionResponseData{
+		Content: option.NewNullableString("Successfully reset token"),
+		Flags:   discord.EphemeralMessage,
+	}
+}
+
+func reviewsCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User discord.UserID
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	actor, err := commandActor(data.Event, schemas.CapabilityModerateReviews)
+	if err != nil {
+		return commandError(err)
+	}
+
+	reviews, count, err := modules.GetReviewsWithOptions(actor, int64(options.User), 0, modules.GetReviewsOptions{Limit: reviewsPerPage})
+	if err != nil {
+		return commandError(err)
+	}
+	if len(reviews) == 0 {
+		return commandMessage(fmt.Sprintf("<@%s> has no reviews", options.User))
+	}
+
+	embed := discord.Embed{
+		Title:       "Reviews",
+		Description: fmt.Sprintf("Showing %d of %d reviews on <@%s>", len(reviews), count, options.User),
+	}
+	components := discord.ContainerComponents{}
+	for i, review := range reviews {
+		embed.Fields = append(embed.Fields, discord.EmbedField{
+			Name:  fmt.Sprintf("#%d by %s", review.ID, review.Sender.Username),
+			Value: truncate(review.Comment, maxListedCommentLength),
+		})
+
+		if i%5 == 0 {
+			components = append(components, &discord.ActionRowComponent{})
+		}
+		row := components[len(components)-1].(*discord.ActionRowComponent)
+		*row = append(*row, &discord.ButtonComponent{
+			CustomID: discord.ComponentID(discord_utils.ActionDeleteReview.CustomID(schemas.PlatformDiscord, review.ID)),
+			Label:    fmt.Sprintf("Delete #%d", review.ID),
+			Style:    discord.DangerButtonStyle(),
+		})
+	}
+
+	return &api.InteractionResponseData{
+		Embeds:     &[]discord.Embed{embed},
+		Components: &components,
+		Flags:      discord.EphemeralMessage,
+	}
+}
+
+func banCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User   discord.UserID
+		Days   int32
+		Reason string `discord:"reason?"`
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	actor, err := commandActor(data.Event, schemas.CapabilityBanUsers)
+	if err != nil {
+		return commandError(err)
+	}
+
+	err = modules.BanUserAs(actor, schemas.PlatformDiscord, options.User.String(), options.Days, schemas.UserReview{}, options.Reason)
+	if err != nil {
+		return commandError(err)
+	}
+	return commandMessage(fmt.Sprintf("Successfully banned <@%s> for %d days", options.User, options.Days))
+}
+
+func unbanCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User discord.UserID
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	actor, err := commandActor(data.Event, schemas.CapabilityBanUsers)
+	if err != nil {
+		return commandError(err)
+	}
+
+	if err := modules.UnbanUserAs(actor, schemas.PlatformDiscord, options.User.String()); err != nil {
+		return commandError(err)
+	}
+	return commandMessage(fmt.Sprintf("Successfully unbanned <@%s>", options.User))
+}
+
+func warnCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User   discord.UserID
+		Reason string
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	actor, err := commandActor(data.Event, schemas.CapabilityBanUsers)
+	if err != nil {
+		return commandError(err)
+	}
+
+	if err := modules.WarnUserAs(actor, schemas.PlatformDiscord, options.User.String(), options.Reason); err != nil {
+		return commandError(err)
+	}
+	return commandMessage(fmt.Sprintf("Successfully warned <@%s>", options.User))
+}
+
+// opt out commands refresh the opted out users right away, the api serves them from memory
+func addOptOutCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User   discord.UserID
+		Reason string `discord:"reason?"`
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	if _, err := commandActor(data.Event, schemas.CapabilityManageOptOuts); err != nil {
+		return commandError(err)
+	}
+
+	if err := modules.AddManualOptOut(options.User.String(), options.Reason); err != nil {
+		return commandError(err)
+	}
+	refreshOptedOut()
+	return commandMessage(fmt.Sprintf("Opted out <@%s>", options.User))
+}
+
+func removeOptOutCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User discord.UserID
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	if _, err := commandActor(data.Event, schemas.CapabilityManageOptOuts); err != nil {
+		return commandError(err)
+	}
+
+	if err := modules.DeleteManualOptOut(options.User.String()); err != nil {
+		return commandError(err)
+	}
+	refreshOptedOut()
+	return commandMessage(fmt.Sprintf("Removed the opt out of <@%s>", options.User))
+}
+
+func grantBadgeCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User  discord.UserID
+		Badge int32
+		Days  *int
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	if _, err := commandActor(data.Event, schemas.CapabilityManageBadges); err != nil {
+		return commandError(err)
+	}
+
+	var expiresAt *time.Time
+	if options.Days != nil {
+		expiry := time.Now().AddDate(0, 0, *options.Days)
+		expiresAt = &expiry
+	}
+
+	if err := modules.AssignBadge(options.Badge, options.User.String(), expiresAt); err != nil {
+		return commandError(err)
+	}
+	return commandMessage(fmt.Sprintf("Gave badge %d to <@%s>", options.Badge, options.User))
+}
+
+func revokeBadgeCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User  discord.UserID
+		Badge int32
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	if _, err := commandActor(data.Event, schemas.CapabilityManageBadges); err != nil {
+		return commandError(err)
+	}
+
+	if err := modules.UnassignBadge(options.Badge, options.User.String()); err != nil {
+		return commandError(err)
+	}
+	return commandMessage(fmt.Sprintf("Took badge %d from <@%s>", options.Badge, options.User))
+}
+
+func lookupCommand(ctx context.Context, data cmdroute.CommandData) *api.InteractionResponseData {
+	var options struct {
+		User discord.UserID
+	}
+	if err := data.Options.Unmarshal(&options); err != nil {
+		return commandError(err)
+	}
+
+	if _, err := commandActor(data.Event, schemas.CapabilityModerateReviews); err != nil {
+		return commandError(err)
+	}
+
+	user, err := modules.GetDBUserViaDiscordID(options.User.String())
+	if err != nil {
+		return commandError(err)
+	}
+	if user == nil {
+		return commandMessage(fmt.Sprintf("<@%s> isn't registered to ReviewDB", options.User))
+	}
+
+	// GetDBUserViaDiscordID doesn't load the ban
+	banned, err := modules.GetDBUserViaID(user.ID)
+	if err != nil {
+		return commandError(err)
+	}
+
+	ban := "Not banned"
+	if banned.Type == -1 {
+		ban = "Permanently banned"
+	} else if banned.BanInfo != nil {
+		ban = fmt.Sprintf("Until <t:%d:f>", banned.BanInfo.BanEndDate.Unix())
+		if banned.BanInfo.Reason != "" {
+			ban += "\n**Reason:** " + banned.BanInfo.Reason
+		}
+	}
+
+	badges := []string{}
+	for _, badge := range modules.GetBadgesOfUser(user.DiscordID) {
+		badges = append(badges, badge.Name)
+	}
+	if len(badges) == 0 {
+		badges = append(badges, "None")
+	}
+
+	return &api.InteractionResponseData{
+		Embeds: &[]discord.Embed{{
+			Title: user.Username,
+			Fields: []discord.EmbedField{
+				{Name: "User", Value: common.FormatUser(user.Username, user.ID, user.DiscordID)},
+				{Name: "Warnings", Value: fmt.Sprint(user.WarningCount), Inline: true},
+				{Name: "Opted Out", Value: fmt.Sprint(user.OptedOut), Inline: true},
+				{Name: "Ban", Value: ban},
+				{Name: "Badges", Value: strings.Join(badges, ", ")},
+			},
+		}},
+		Flags: discord.EphemeralMessage,
+	}
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length-1]) + "…"
+}
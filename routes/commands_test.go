@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+var commandName = regexp.MustCompile(`^[a-z]{1,32}$`)
+
+func TestCommandsAreValid(t *testing.T) {
+	seen := map[string]bool{}
+	for _, command := range Commands {
+		if !commandName.MatchString(command.Name) {
+			t.Errorf("invalid command name %q", command.Name)
+		}
+		if seen[command.Name] {
+			t.Errorf("command %q is defined twice", command.Name)
+		}
+		seen[command.Name] = true
+
+		if command.Description == "" || len(command.Description) > 100 {
+			t.Errorf("command %q needs a description of at most 100 characters", command.Name)
+		}
+
+		for _, option := range command.Options {
+			if !commandName.MatchString(option.Name()) {
+				t.Errorf("invalid option name %q on %q", option.Name(), command.Name)
+			}
+			if subcommand, ok := option.(*discord.SubcommandOption); ok {
+				for _, suboption := range subcommand.Options {
+					if !commandName.MatchString(suboption.Name()) {
+						t.Errorf("invalid option name %q on %q %q", suboption.Name(), command.Name, subcommand.Name())
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestUnknownCommandsAreEphemeral(t *testing.T) {
+	body, err := commandInteraction([]byte(`{"id":"1","application_id":"2","type":2,"token":"t","version":1,"data":{"id":"3","name":"unknown","type":1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response api.InteractionResponse
+	if err = json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Data == nil || response.Data.Flags&discord.EphemeralMessage == 0 {
+		t.Errorf("commandInteraction(unknown) = %s, want an ephemeral reply", body)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate changed a short text to %q", got)
+	}
+
+	got := truncate("ääääääääää", 5)
+	if got != "ääää…" || !utf8.ValidString(got) {
+		t.Errorf("truncate(ääääääääää, 5) = %q", got)
+	}
+}
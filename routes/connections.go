@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-go/common"
+	"server-go/database/schemas"
+	"server-go/modules"
+	modules_twitter "server-go/modules/twitter"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func parseConnectionID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	connectionID, err := strconv.ParseInt(chi.URLParam(r, "connectionid"), 10, 32)
+	if err != nil || connectionID <= 0 {
+		Error(w, modules.ValidationError("invalid_connection_id", "Invalid connection ID"))
+		return 0, false
+	}
+	return int32(connectionID), true
+}
+
+func GetConnections(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, modules.ErrUnauthorized)
+		return
+	}
+
+	connections, err := modules.GetConnections(user)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, connections)
+}
+
+func DeleteConnection(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, modules.ErrUnauthorized)
+		return
+	}
+
+	connectionID, ok := parseConnectionID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := modules.DeleteConnection(user, connectionID); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Connection removed"})
+}
+
+type PatchConnectionRequest struct {
+	Public bool `json:"public"`
+}
+
+func PatchConnection(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, modules.ErrUnauthorized)
+		return
+	}
+
+	connectionID, ok := parseConnectionID(w, r)
+	if !ok {
+		return
+	}
+
+	var body PatchConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, modules.ErrInvalidRequest)
+		return
+	}
+
+	if err := modules.SetConnectionPublic(user, connectionID, body.Public); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Connection updated"})
+}
+
+type LinkTwitterRequest struct {
+	TwitterToken string `json:"twitterToken"`
+}
+
+// LinkTwitterConnection links the ReviewDB Twitter account owning twitterToken to the authorized ReviewDB user.
+// This lives here instead of modules because modules/twitter depends on modules.
+func LinkTwitterConnection(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, modules.ErrUnauthorized)
+		return
+	}
+
+	var body LinkTwitterRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.TwitterToken == "" {
+		Error(w, modules.ErrInvalidRequest)
+		return
+	}
+
+	twitterUser, err := modules_twitter.GetDBUserViaToken(body.TwitterToken)
+	if err != nil {
+		Error(w, modules.UnauthorizedError("invalid_twitter_token", "Invalid Twitter token"))
+		return
+	}
+
+	err = modules.AddConnection(&schemas.Oauth2Token{
+		UserId:     user.ID,
+		Provider:   modules.ConnectionProviderTwitter,
+		ProviderId: twitterUser.DiscordID,
+		Username:   twitterUser.Username,
+		Avatar:     twitterUser.AvatarURL,
+	})
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Twitter account linked"})
+}
@@ -0,0 +1,85 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"server-go/database/schemas"
+	"server-go/modules"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEventProfiles is how many profiles one stream can follow
+const maxEventProfiles = 50
+
+// StreamEvents sends the review activity of the profiles in the profiles query as server-sent events.
+// Authorized users also receive their notifications.
+func StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		Error(w, errors.New("response writer does not support flushing"))
+		return
+	}
+
+	topics := []string{}
+	if profiles := r.URL.Query().Get("profiles"); profiles != "" {
+		for _, profileID := range strings.Split(profiles, ",") {
+			if id, err := strconv.ParseUint(profileID, 10, 64); err != nil || id == 0 {
+				Error(w, modules.ValidationError("invalid_profile_id", "Invalid profile id "+profileID))
+				return
+			}
+			topics = append(topics, modules.ProfileTopic(schemas.PlatformDiscord, profileID))
+		}
+	}
+
+	if len(topics) > maxEventProfiles {
+		Error(w, modules.ValidationError("too_many_profiles", fmt.Sprintf("You can follow at most %d profiles", maxEventProfiles)))
+		return
+	}
+
+	if r.Header.Get("Authorization") != "" {
+		user, err := Authorize(r)
+		if err != nil {
+			Error(w, err)
+			return
+		}
+		topics = append(topics, modules.UserTopic(user.ID))
+	}
+
+	if len(topics) == 0 {
+		Error(w, modules.ValidationError("no_topics", "Follow some profiles or authorize to receive notifications"))
+		return
+	}
+
+	sub := modules.Events.Subscribe(topics...)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // so nginx doesn't hold events back
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		flusher.Flush()
+	}
+}
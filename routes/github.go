@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"server-go/common"
+	"server-go/modules"
+	"server-go/modules/github"
+)
+
+// HandleGithubWebhook receives github webhook deliveries, only sponsorship events are acted on
+func HandleGithubWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		Error(w, modules.ErrInvalidRequest)
+		return
+	}
+
+	if !github.VerifyWebhookSignature(common.Config.GithubWebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		Error(w, modules.UnauthorizedError("invalid_signature", "Invalid signature"))
+		return
+	}
+
+	switch r.Header.Get("X-GitHub-Event") {
+	case "sponsorship":
+		var event github.SponsorshipEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			Error(w, modules.ErrInvalidRequest)
+			return
+		}
+
+		if err := modules.HandleGithubSponsorshipEvent(event); err != nil {
+			fmt.Println("failed to handle sponsorship event:", err)
+			Error(w, err)
+			return
+		}
+	}
+
+	common.SendStructResponse(w, Response{Success: true})
+}
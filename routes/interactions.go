@@ -4,12 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"server-go/common"
 	"server-go/database/schemas"
 	"server-go/modules"
 	discord_utils "server-go/modules/discord"
 	"strconv"
-	"strings"
 
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
@@ -37,15 +35,12 @@ type InteractionsData struct {
 	} `json:"member"`
 }
 
-func BanTimeSelectComponent(userid string) discord.ContainerComponents {
-	return BanTimeSelectComponentWithID(userid, "ban_user")
-}
-
-func BanTimeSelectComponentWithID(userid string, componentID string) discord.ContainerComponents {
+// BanTimeSelectComponent asks for a ban duration, customID is the ban action to run with it
+func BanTimeSelectComponent(customID string) discord.ContainerComponents {
 	return discord.ContainerComponents{
 		&discord.ActionRowComponent{
 			&discord.StringSelectComponent{
-				CustomID:    discord.ComponentID(componentID + ":" + userid),
+				CustomID:    discord.ComponentID(customID),
 				Placeholder: "Select ban time",
 				Options: []discord.SelectOption{
 					{
@@ -70,6 +65,21 @@ func BanTimeSelectComponentWithID(userid string, componentID string) discord.Con
 	}
 }
 
+func UndoDeleteComponent(reviewID int32) discord.ContainerComponents {
+	return discord.ContainerComponents{
+		&discord.ActionRowComponent{
+			&discord.ButtonComponent{
+				CustomID: discord.ComponentID(discord_utils.ActionRestoreReview.CustomID(schemas.PlatformDiscord, reviewID)),
+				Label:    "Undo",
+				Style:    discord.SecondaryButtonStyle(),
+				Emoji: &discord.ComponentEmoji{
+					Name: "↩️",
+				},
+			},
+		},
+	}
+}
+
 func AppealDenyTextComponent(appealID int32) discord.ContainerComponents {
 
 	return discord.ContainerComponents{
@@ -86,161 +96,232 @@ func AppealDenyTextComponent(appealID int32) discord.ContainerComponents {
 	}
 }
 
+// componentHandler handles the interactions of a component action, staff without its capability can't use it
+type componentHandler struct {
+	capability schemas.Capability
+	handle     func(interaction *componentInteraction)
+}
+
+type componentInteraction struct {
+	data     InteractionsData
+	id       discord_utils.CustomID
+	actor    *schemas.URUser
+	response *api.InteractionResponse
+}
+
+func (interaction *componentInteraction) reply(content string) {
+	interaction.response.Data.Content = option.NewNullableString(content)
+}
+
+// updateMessage replaces the message the component is on, removing its components so it can't be used twice
+func (interaction *componentInteraction) updateMessage(content string) {
+	interaction.reply(content)
+	interaction.response.Type = api.UpdateMessage
+	interaction.response.Data.Components = &discord.ContainerComponents{}
+}
+
+// banDuration is the duration picked in a ban time select menu
+func (interaction *componentInteraction) banDuration() (int32, bool) {
+	if len(interaction.data.Data.Values) != 1 {
+		return 0, false
+	}
+	days, err := strconv.ParseInt(interaction.data.Data.Values[0], 10, 32)
+	return int32(days), err == nil && days > 0 && days <= 365
+}
+
+var componentHandlers = map[string]componentHandler{
+	discord_utils.ActionDeleteReview.Name:       {schemas.CapabilityModerateReviews, deleteReviewComponent},
+	discord_utils.ActionRestoreReview.Name:      {schemas.CapabilityModerateReviews, restoreReviewComponent},
+	discord_utils.ActionBanSelect.Name:          {schemas.CapabilityBanUsers, banSelectComponent},
+	discord_utils.ActionBanUser.Name:            {schemas.CapabilityBanUsers, banUserComponent},
+	discord_utils.ActionSelectDeleteAndBan.Name: {schemas.CapabilityBanUsers, selectDeleteAndBanComponent},
+	discord_utils.ActionDeleteAndBan.Name:       {schemas.CapabilityBanUsers, deleteAndBanComponent},
+	discord_utils.ActionAcceptAppeal.Name:       {schemas.CapabilityHandleAppeals, acceptAppealComponent},
+	discord_utils.ActionTextDenyAppeal.Name:     {schemas.CapabilityHandleAppeals, textDenyAppealComponent},
+	discord_utils.ActionDenyAppeal.Name:         {schemas.CapabilityHandleAppeals, denyAppealComponent},
+}
+
 func Interactions(data InteractionsData) (string, error) {
 
 	if data.Type == 1 {
 		return "{\"type\":1}", nil //copilot I hope you die
 	}
-	response := api.InteractionResponse{}
+	// slash commands go through commandInteraction, this handles message components and modals
+	if data.Type != 3 && data.Type != 5 {
+		return "", errors.New("invalid interaction")
+	}
 
-	response.Type = 4
+	response := api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{},
+	}
 
-	response.Data = &api.InteractionResponseData{}
+	id, err := discord_utils.ParseCustomID(data.Data.ID)
+	handler, ok := componentHandlers[id.Action.Name]
+	if err != nil || !ok {
+		response.Data.Content = option.NewNullableString("This component is not supported")
+		response.Data.Flags = discord.EphemeralMessage
+		return InteractionResponse(&response), nil
+	}
 
-	userid, _ := strconv.ParseInt(data.Member.User.ID, 10, 64)
+	actor, err := modules.GetDBUserViaDiscordID(data.Member.User.ID)
+	if err != nil || actor == nil || !actor.Can(handler.capability) {
+		response.Data.Content = option.NewNullableString("You are not allowed to do this")
+		response.Data.Flags = discord.EphemeralMessage
+		return InteractionResponse(&response), nil
+	}
 
-	action := strings.Split(data.Data.ID, ":")
+	response.Data.Embeds = &[]discord.Embed{{
+		Footer: &discord.EmbedFooter{
+			Text: fmt.Sprintf("Admin: %s#%s (%s)", data.Member.User.Username, data.Member.User.Discriminator, data.Member.User.ID),
+		},
+	}}
 
-	if (data.Type == 3 || data.Type == 5) && modules.IsUserAdminDC(userid) {
+	handler.handle(&componentInteraction{data: data, id: id, actor: actor, response: &response})
+	return InteractionResponse(&response), nil
+}
 
-		response.Data.Embeds = &[]discord.Embed{{
-			Footer: &discord.EmbedFooter{
-				Text: fmt.Sprintf("Admin: %s#%s (%s)", data.Member.User.Username, data.Member.User.Discriminator, data.Member.User.ID),
-			},
-		}}
-
-		firstVariable, _ := strconv.ParseInt(action[1], 10, 32) // if action is delete review or delete_and_ban its reviewid otherwise userid
-		if action[0] == "delete_review" {
-			err := modules.DeleteReview(int32(firstVariable), common.Config.AdminToken)
-			if err == nil {
-				response.Data.Content = option.NewNullableString("Successfully Deleted review with id " + action[1])
-			} else {
-				response.Data.Content = option.NewNullableString(err.Error())
-			}
-		} else if action[0] == "ban_select" {
-
-			component := BanTimeSelectComponent(action[1] + ":" + action[2])
-			response.Data.Content = option.NewNullableString("Select ban duration")
-			response.Data.Components = &component
-
-		} else if action[0] == "delete_and_ban" {
-
-			banDuration, _ := strconv.ParseInt(data.Data.Values[0], 10, 32)
-			reviewid, err := strconv.ParseInt(action[2], 10, 32)
-			review := schemas.UserReview{}
-
-			if err == nil {
-				review, _ = modules.GetReview(int32(reviewid))
-			}
-
-			err = modules.BanUser(action[1], common.Config.AdminToken, int32(banDuration), review)
-			err2 := modules.DeleteReview(int32(reviewid), common.Config.AdminToken)
-
-			if err == nil && err2 == nil {
-				response.Data.Content = option.NewNullableString(fmt.Sprintf("Successfully deleted review with id %s and banned user %s for %d days", action[2], action[1], int32(banDuration)))
-			} else if err == nil && err2 != nil {
-				response.Data.Content = option.NewNullableString(fmt.Sprintf("Successfully banned user %s for %d days and failed to delete review with id %s\n Reason: %s", action[1], int32(banDuration), action[2], err2.Error()))
-				fmt.Println(err)
-			} else if err != nil && err2 != nil {
-				response.Data.Content = option.NewNullableString(fmt.Sprintf("Failed to delete review with id %s and failed to ban user %s for %d days\nBan Fail Reason: %s\nReview Delete fail reason:%s", action[2], action[1], int32(banDuration), err.Error(), err2.Error()))
-				fmt.Println(err, err2)
-			} else {
-				response.Data.Content = option.NewNullableString(fmt.Sprintf("Failed to ban user with id %s and successfully deleted review with id %s\nReason: %s", action[1], action[2], err.Error()))
-			}
-
-			response.Type = 7 // update message
-
-			response.Data.Components = &discord.ContainerComponents{} // remove components
-
-		} else if action[0] == "select_delete_and_ban" {
-			component := BanTimeSelectComponentWithID(action[2]+":"+action[1], "delete_and_ban")
-			response.Data.Components = &component
-			response.Data.Content = option.NewNullableString("Select ban duration & delete review")
-
-		} else if action[0] == "ban_user" {
-
-			banDuration, _ := strconv.ParseInt(data.Data.Values[0], 10, 32)
-			reviewid, err := strconv.ParseInt(action[2], 10, 32)
-			review := schemas.UserReview{}
-
-			if err == nil {
-				review, _ = modules.GetReview(int32(reviewid))
-			}
-
-			err = modules.BanUser(action[1], common.Config.AdminToken, int32(banDuration), review)
-			if err == nil {
-				response.Data.Content = option.NewNullableString(fmt.Sprintf("Successfully banned user %s for %d days", action[1], int32(banDuration)))
-			} else {
-				response.Data.Content = option.NewNullableString(err.Error())
-			}
-			response.Type = 7 // update message
-
-			response.Data.Components = &discord.ContainerComponents{} // remove components
-
-		} else if action[0] == "accept_appeal" {
-			appeal, err := modules.GetAppeal(int32(firstVariable))
-			if err != nil {
-				response.Data.Content = option.NewNullableString(err.Error())
-				return InteractionResponse(&response), nil
-			}
-
-			if appeal.ActionTaken {
-				response.Data.Content = option.NewNullableString("Appeal action already taken")
-				return InteractionResponse(&response), nil
-			}
-			err = modules.AcceptAppeal(&appeal, appeal.UserID)
-
-			if err == nil {
-				response.Data.Content = option.NewNullableString(fmt.Sprintf("Successfully unbanned user %d", appeal.UserID))
-			} else {
-				response.Data.Content = option.NewNullableString(err.Error())
-			}
-
-		} else if action[0] == "text_deny_appeal" {
-			appealId := int32(firstVariable)
-			component := AppealDenyTextComponent(appealId)
-			response.Data.Components = &component
-			response.Data.Title = option.NewNullableString("Enter Deny Reason")
-			response.Data.CustomID = option.NewNullableString(fmt.Sprintf("deny_appeal:%d", appealId))
-			response.Type = 9 // modal
-		} else if action[0] == "deny_appeal" {
-			appeal, err := modules.GetAppeal(int32(firstVariable))
-
-			if err != nil {
-				response.Data.Content = option.NewNullableString(err.Error())
-				return InteractionResponse(&response), nil
-			}
-			if appeal.ActionTaken {
-				response.Data.Content = option.NewNullableString("Appeal action already taken")
-				return InteractionResponse(&response), nil
-			}
-
-			// Safety check for modal submission data
-			if len(data.Data.Components) == 0 || len(data.Data.Components[0].Components) == 0 {
-				response.Data.Content = option.NewNullableString("Invalid modal submission data")
-				return InteractionResponse(&response), nil
-			}
-
-			denyReason := data.Data.Components[0].Components[0].Value
-			err = modules.DenyAppeal(&appeal, denyReason)
-			if err != nil {
-				response.Data.Content = option.NewNullableString(err.Error())
-			} else {
-				response.Data.Content = option.NewNullableString("Successfully denied appeal\n\n ```" + denyReason + "```")
-			}
-		}
-	}
-
-	if response.Data.Content != nil || response.Data.Title != nil {
-		b, err := json.Marshal(response)
-		if err != nil {
-			return "", err
-		}
-		fmt.Println(string(b))
-
-		return string(b), nil
-	}
-	return "", errors.New("invalid interaction")
+func deleteReviewComponent(interaction *componentInteraction) {
+	reviewID := interaction.id.ID(0)
+	if err := modules.DeleteReviewAs(interaction.actor, reviewID); err != nil {
+		interaction.reply(err.Error())
+		return
+	}
+
+	interaction.reply(fmt.Sprintf("Successfully Deleted review with id %d", reviewID))
+	component := UndoDeleteComponent(reviewID)
+	interaction.response.Data.Components = &component
+}
+
+func restoreReviewComponent(interaction *componentInteraction) {
+	reviewID := interaction.id.ID(0)
+	if err := modules.RestoreReview(reviewID, interaction.actor.ID); err != nil {
+		interaction.updateMessage(err.Error())
+		return
+	}
+	interaction.updateMessage(fmt.Sprintf("Successfully restored review with id %d", reviewID))
+}
+
+func banSelectComponent(interaction *componentInteraction) {
+	id := interaction.id
+	component := BanTimeSelectComponent(discord_utils.ActionBanUser.CustomID(id.Platform, id.AccountID(0), id.ID(1)))
+	interaction.reply("Select ban duration")
+	interaction.response.Data.Components = &component
+}
+
+func selectDeleteAndBanComponent(interaction *componentInteraction) {
+	id := interaction.id
+	component := BanTimeSelectComponent(discord_utils.ActionDeleteAndBan.CustomID(id.Platform, id.AccountID(1), id.ID(0)))
+	interaction.reply("Select ban duration & delete review")
+	interaction.response.Data.Components = &component
+}
+
+func banUserComponent(interaction *componentInteraction) {
+	userID, reviewID := interaction.id.AccountID(0), interaction.id.ID(1)
+	banDuration, ok := interaction.banDuration()
+	if !ok {
+		interaction.reply("Invalid ban duration")
+		return
+	}
+
+	// bans from the ban reporter button have no review
+	review := schemas.UserReview{}
+	if reviewID != 0 {
+		review, _ = modules.GetReview(reviewID)
+	}
+
+	err := modules.BanUserAs(interaction.actor, interaction.id.Platform, userID, banDuration, review, "")
+	if err != nil {
+		interaction.updateMessage(err.Error())
+		return
+	}
+	interaction.updateMessage(fmt.Sprintf("Successfully banned user %s for %d days", userID, banDuration))
+}
+
+func deleteAndBanComponent(interaction *componentInteraction) {
+	userID, reviewID := interaction.id.AccountID(0), interaction.id.ID(1)
+	banDuration, ok := interaction.banDuration()
+	if !ok {
+		interaction.reply("Invalid ban duration")
+		return
+	}
+
+	review, _ := modules.GetReview(reviewID)
+	err := modules.BanUserAs(interaction.actor, interaction.id.Platform, userID, banDuration, review, "")
+	err2 := modules.DeleteReviewAs(interaction.actor, reviewID)
+
+	if err == nil && err2 == nil {
+		interaction.updateMessage(fmt.Sprintf("Successfully deleted review with id %d and banned user %s for %d days", reviewID, userID, banDuration))
+	} else if err == nil && err2 != nil {
+		interaction.updateMessage(fmt.Sprintf("Successfully banned user %s for %d days and failed to delete review with id %d\n Reason: %s", userID, banDuration, reviewID, err2.Error()))
+	} else if err != nil && err2 != nil {
+		interaction.updateMessage(fmt.Sprintf("Failed to delete review with id %d and failed to ban user %s for %d days\nBan Fail Reason: %s\nReview Delete fail reason:%s", reviewID, userID, banDuration, err.Error(), err2.Error()))
+	} else {
+		interaction.updateMessage(fmt.Sprintf("Failed to ban user with id %s and successfully deleted review with id %d\nReason: %s", userID, reviewID, err.Error()))
+	}
+}
+
+// pendingAppeal loads the appeal of the interaction, replying when no action can be taken on it
+func (interaction *componentInteraction) pendingAppeal() (appeal schemas.ReviewDBAppeal, ok bool) {
+	appeal, err := modules.GetAppeal(interaction.id.ID(0))
+	if err != nil {
+		interaction.reply(err.Error())
+		return appeal, false
+	}
+	if appeal.ID == 0 {
+		interaction.reply("Appeal not found")
+		return appeal, false
+	}
+	if appeal.ActionTaken {
+		interaction.reply("Appeal action already taken")
+		return appeal, false
+	}
+	return appeal, true
+}
+
+func acceptAppealComponent(interaction *componentInteraction) {
+	appeal, ok := interaction.pendingAppeal()
+	if !ok {
+		return
+	}
+
+	if err := modules.AcceptAppeal(&appeal, appeal.UserID); err != nil {
+		interaction.reply(err.Error())
+		return
+	}
+	interaction.reply(fmt.Sprintf("Successfully unbanned user %d", appeal.UserID))
+}
+
+func textDenyAppealComponent(interaction *componentInteraction) {
+	appealID := interaction.id.ID(0)
+	component := AppealDenyTextComponent(appealID)
+	interaction.response.Type = api.ModalResponse
+	interaction.response.Data.Components = &component
+	interaction.response.Data.Title = option.NewNullableString("Enter Deny Reason")
+	interaction.response.Data.CustomID = option.NewNullableString(discord_utils.ActionDenyAppeal.CustomID(schemas.PlatformDiscord, appealID))
+	// modals can't have embeds
+	interaction.response.Data.Embeds = nil
+}
+
+func denyAppealComponent(interaction *componentInteraction) {
+	appeal, ok := interaction.pendingAppeal()
+	if !ok {
+		return
+	}
+
+	// Safety check for modal submission data
+	components := interaction.data.Data.Components
+	if len(components) == 0 || len(components[0].Components) == 0 {
+		interaction.reply("Invalid modal submission data")
+		return
+	}
+
+	denyReason := components[0].Components[0].Value
+	if err := modules.DenyAppeal(&appeal, denyReason); err != nil {
+		interaction.reply(err.Error())
+		return
+	}
+	interaction.reply("Successfully denied appeal\n\n ```" + denyReason + "```")
 }
 
 func InteractionResponse(response *api.InteractionResponse) string {
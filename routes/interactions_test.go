@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server-go/common"
+	discord_utils "server-go/modules/discord"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+func TestEveryComponentActionHasHandler(t *testing.T) {
+	for name := range discord_utils.ComponentActions {
+		if _, ok := componentHandlers[name]; !ok {
+			t.Errorf("no handler for component action %s", name)
+		}
+	}
+	for name := range componentHandlers {
+		if _, ok := discord_utils.ComponentActions[name]; !ok {
+			t.Errorf("handler for unknown component action %s", name)
+		}
+	}
+}
+
+func TestInteractionsRejectsInvalidCustomIDs(t *testing.T) {
+	for _, customID := range []string{"ban_user:123", "delete_review:abc", "unknown:1", ""} {
+		var data InteractionsData
+		data.Type = 3
+		data.Data.ID = customID
+
+		body, err := Interactions(data)
+		if err != nil {
+			t.Fatalf("Interactions(%q) = %v", customID, err)
+		}
+
+		var response api.InteractionResponse
+		if err = json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Data == nil || response.Data.Flags&discord.EphemeralMessage == 0 {
+			t.Errorf("Interactions(%q) = %s, want an ephemeral reply", customID, body)
+		}
+	}
+}
+
+func TestInteractionsAnswersPings(t *testing.T) {
+	body, err := Interactions(InteractionsData{Type: 1})
+	if err != nil || body != `{"type":1}` {
+		t.Errorf("Interactions(ping) = %s, %v", body, err)
+	}
+}
+
+func TestHandleInteractionsVerifiesSignature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := common.Config.DiscordPublicKey
+	common.Config.DiscordPublicKey = hex.EncodeToString(publicKey)
+	defer func() { common.Config.DiscordPublicKey = previous }()
+
+	body := `{"type":1}`
+	for _, test := range []struct {
+		name      string
+		signature []byte
+		status    int
+	}{
+		{"valid", ed25519.Sign(privateKey, []byte("1700000000"+body)), http.StatusOK},
+		{"other timestamp", ed25519.Sign(privateKey, []byte("1600000000"+body)), http.StatusUnauthorized},
+		{"missing", nil, http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/interactions", strings.NewReader(body))
+		r.Header.Set("X-Signature-Ed25519", hex.EncodeToString(test.signature))
+		r.Header.Set("X-Signature-Timestamp", "1700000000")
+		w := httptest.NewRecorder()
+
+		HandleInteractions(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s signature: status %d, want %d", test.name, w.Code, test.status)
+		}
+	}
+}
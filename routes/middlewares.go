@@ -18,7 +18,7 @@ func AdminMiddleware(handler http.Handler) http.Handler {
 		var token = r.Header.Get("Authorization")
 
 		if token == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			Error(w, modules.ErrUnauthorized)
 			return
 		}
 		if common.Config.AdminToken != "" && token == common.Config.AdminToken {
@@ -27,7 +27,7 @@ func AdminMiddleware(handler http.Handler) http.Handler {
 		}
 		user, err := modules.GetDBUserViaToken(token)
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			Error(w, modules.ErrUnauthorized)
 			return
 		}
 
@@ -35,7 +35,7 @@ func AdminMiddleware(handler http.Handler) http.Handler {
 			handler.ServeHTTP(w, r)
 			return
 		}
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		Error(w, modules.ErrUnauthorized)
 	})
 }
 
@@ -60,8 +60,7 @@ func ReviewMiddleware(handler http.Handler) http.Handler {
 		idStr := chi.URLParam(r, "reviewid")
 		id, err := strconv.ParseInt(idStr, 10, 32)
 		if err != nil || id <= 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			common.SendStructResponse(w, Response{Message: "Invalid review ID"})
+			Error(w, modules.ValidationError("invalid_review_id", "Invalid review ID"))
 			return
 		}
 
@@ -70,8 +69,7 @@ func ReviewMiddleware(handler http.Handler) http.Handler {
 			Where("id = ?", int32(id)).
 			Count(context.Background())
 		if err != nil || count == 0 {
-			w.WriteHeader(http.StatusNotFound)
-			common.SendStructResponse(w, Response{Message: "Review not found"})
+			Error(w, modules.NotFoundError("review_not_found", "Review not found"))
 			return
 		}
 
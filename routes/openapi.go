@@ -0,0 +1,419 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"server-go/common"
+	"server-go/database/schemas"
+	"server-go/modules"
+)
+
+type apiAuth int
+
+const (
+	authNone apiAuth = iota
+	// a ReviewDB token in the Authorization header
+	authUser
+	// an admin's ReviewDB token or the configured admin token
+	authAdmin
+	// a ReviewDB Twitter token
+	authTwitter
+)
+
+type apiParam struct {
+	Name        string
+	Description string
+	Type        string // string unless set
+}
+
+// apiOperation documents one method of a route. Body and Response are zero values of the types the handler
+// decodes and encodes, Produces is only set for routes that don't answer with json.
+type apiOperation struct {
+	Method   string
+	Path     string
+	Summary  string
+	Auth     apiAuth
+	Query    []apiParam
+	Body     any
+	Response any
+	Produces string
+}
+
+var (
+	paginationParams = []apiParam{
+		{Name: "offset", Type: "integer"},
+		{Name: "limit", Type: "integer"},
+	}
+	leaderboardParams = append([]apiParam{
+		{Name: "period", Description: "week, month or all"},
+	}, paginationParams...)
+	metricParam = apiParam{Name: "metric", Description: "defaults to stupidity"}
+)
+
+// apiOperations is the source of the OpenAPI document, TestOpenAPICoversRoutes fails when it and NewRouter disagree
+var apiOperations = []apiOperation{
+	// ReviewDB
+	{Method: "GET", Path: "/api/reviewdb/auth", Summary: "Exchange a discord oauth code for a ReviewDB token", Query: []apiParam{{Name: "code"}, {Name: "clientMod"}}, Response: ReviewDBAuthResponse{}},
+	{Method: "GET", Path: "/api/reviewdb/authweb", Summary: "Log in to the website, redirects with the token", Query: []apiParam{{Name: "code"}}, Produces: "redirect"},
+	{Method: "GET", Path: "/api/reviewdb/oauth/github", Summary: "Link a GitHub account", Auth: authUser, Query: []apiParam{{Name: "code"}}, Response: Response{}},
+	{Method: "POST", Path: "/api/reviewdb/webhooks/github", Summary: "GitHub sponsorship webhook, signed with the webhook secret", Response: Response{}},
+
+	{Method: "GET", Path: "/api/reviewdb/users/{discordid}/reviews", Summary: "Reviews of a profile", Query: append([]apiParam{{Name: "flags", Type: "integer"}, {Name: "always_include_reviews_by"}}, paginationParams...), Response: ReviewResponse{}},
+	{Method: "PUT", Path: "/api/reviewdb/users/{discordid}/reviews", Summary: "Add or update your review of a profile", Auth: authUser, Body: modules.UR_RequestData{}, Response: AddReviewResponse{}},
+	{Method: "DELETE", Path: "/api/reviewdb/users/{discordid}/reviews", Summary: "Delete a review", Auth: authUser, Body: modules.UR_RequestData{}, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/users/{discordid}/reviews/votes", Summary: "Your votes on the reviews of a profile", Auth: authUser, Response: ReviewVotesResponse{}},
+	{Method: "GET", Path: "/api/reviewdb/users/{discordid}", Summary: "Public info of a user", Response: PublicUserInfo{}},
+	{Method: "GET", Path: "/api/reviewdb/users/{discordid}/rating", Summary: "Average rating of a user", Response: UserRatingResponse{}},
+	{Method: "GET", Path: "/api/reviewdb/users/{discordid}/reputation", Summary: "Reputation of a user", Response: modules.ReputationStats{}},
+	{Method: "GET", Path: "/api/reviewdb/users/{discordid}/reputation/history", Summary: "Daily reputation of a user", Query: []apiParam{{Name: "days", Type: "integer"}}, Response: []modules.ReputationHistoryPoint{}},
+	{Method: "GET", Path: "/api/reviewdb/users", Summary: "Your own user", Auth: authUser, Response: UserInfoResponse{}},
+	{Method: "POST", Path: "/api/reviewdb/users", Summary: "Your own user, for clients sending the token in the body", Body: modules.UR_RequestData{}, Response: UserInfoResponse{}},
+
+	{Method: "POST", Path: "/api/reviewdb/reviews/{reviewid}/vote", Summary: "Up or downvote a review", Auth: authUser, Body: VoteRequest{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/reviewdb/reviews/{reviewid}/vote", Summary: "Remove your vote on a review", Auth: authUser, Response: Response{}},
+	{Method: "PUT", Path: "/api/reviewdb/reviews/{reviewid}/pin", Summary: "Pin a review on your profile", Auth: authUser, Response: Response{}},
+	{Method: "DELETE", Path: "/api/reviewdb/reviews/{reviewid}/pin", Summary: "Unpin a review on your profile", Auth: authUser, Response: Response{}},
+	{Method: "PUT", Path: "/api/reviewdb/reviews/{reviewid}/hide", Summary: "Hide a review on your profile", Auth: authUser, Body: HideReviewRequest{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/reviewdb/reviews/{reviewid}/hide", Summary: "Unhide a review on your profile", Auth: authUser, Response: Response{}},
+	{Method: "POST", Path: "/api/reviewdb/reviews", Summary: "Search reviews, admins only", Body: SearchRequestData{}, Response: ReviewResponse{}},
+
+	{Method: "PUT", Path: "/api/reviewdb/reports", Summary: "Report a review", Auth: authUser, Body: modules.UR_RequestData{}, Response: Response{}},
+	{Method: "PUT", Path: "/api/reviewdb/appeals", Summary: "Appeal your ban", Auth: authUser, Body: schemas.ReviewDBAppeal{}, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/badges", Summary: "Every badge with its owner", Response: []OwnedBadge{}},
+	{Method: "GET", Path: "/api/reviewdb/badges/map", Summary: "Badges by discord id", Response: map[string][]schemas.UserBadge{}},
+	{Method: "GET", Path: "/api/reviewdb/blocks", Summary: "Users you blocked", Auth: authUser, Query: paginationParams, Response: []schemas.BaseRDBUser{}},
+	{Method: "PATCH", Path: "/api/reviewdb/blocks", Summary: "Block or unblock a user", Auth: authUser, Body: BlockRequest{}, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/settings", Summary: "Your settings", Auth: authUser, Response: modules.Settings{}},
+	{Method: "PATCH", Path: "/api/reviewdb/settings", Summary: "Update your settings", Auth: authUser, Body: modules.Settings{}, Response: Response{}},
+	{Method: "PATCH", Path: "/api/reviewdb/notifications", Summary: "Mark a notification as read", Auth: authUser, Query: []apiParam{{Name: "id", Type: "integer"}}, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/restrictions", Summary: "Who can review your profile", Auth: authUser, Response: modules.ReviewRestrictions{}},
+	{Method: "PATCH", Path: "/api/reviewdb/restrictions", Summary: "Restrict who can review your profile", Auth: authUser, Body: modules.ReviewRestrictions{}, Response: Response{}},
+
+	{Method: "GET", Path: "/api/reviewdb/me/connections", Summary: "Your linked accounts", Auth: authUser, Response: []modules.Connection{}},
+	{Method: "POST", Path: "/api/reviewdb/me/connections/twitter", Summary: "Link your ReviewDB Twitter account", Auth: authUser, Body: LinkTwitterRequest{}, Response: Response{}},
+	{Method: "PATCH", Path: "/api/reviewdb/me/connections/{connectionid}", Summary: "Show or hide a linked account on your profile", Auth: authUser, Body: PatchConnectionRequest{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/reviewdb/me/connections/{connectionid}", Summary: "Unlink an account", Auth: authUser, Response: Response{}},
+
+	{Method: "GET", Path: "/api/reviewdb/leaderboard", Summary: "Users with the most reviews", Query: leaderboardParams, Response: []modules.LeaderboardUser{}},
+	{Method: "GET", Path: "/api/reviewdb/leaderboard/me", Summary: "Your review leaderboard rank", Auth: authUser, Query: leaderboardParams[:1], Response: modules.LeaderboardUser{}},
+	{Method: "GET", Path: "/api/reviewdb/reputation/leaderboard", Summary: "Users with the most reputation", Query: leaderboardParams, Response: []modules.ReputationStats{}},
+	{Method: "GET", Path: "/api/reviewdb/reputation/leaderboard/me", Summary: "Your reputation leaderboard rank", Auth: authUser, Query: leaderboardParams[:1], Response: modules.ReputationStats{}},
+
+	// ReviewDB admin
+	{Method: "GET", Path: "/api/reviewdb/admin/filters", Summary: "Word filters", Auth: authAdmin, Response: FiltersResponse{}},
+	{Method: "PUT", Path: "/api/reviewdb/admin/filters", Summary: "Add a word to a filter", Auth: authAdmin, Body: FilterStruct{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/reviewdb/admin/filters", Summary: "Remove a word from a filter", Auth: authAdmin, Body: FilterStruct{}, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/admin/reload", Summary: "Reload the config", Auth: authAdmin, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/admin/reports", Summary: "Reported reviews", Auth: authAdmin, Query: paginationParams, Response: []schemas.ReviewReport{}},
+	{Method: "GET", Path: "/api/reviewdb/admin/users", Summary: "Search users", Auth: authAdmin, Query: append([]apiParam{{Name: "query"}, {Name: "ip_hash"}}, paginationParams...), Response: []schemas.ReviewDBUserFull{}},
+	{Method: "PATCH", Path: "/api/reviewdb/admin/users", Summary: "Update a user", Auth: authAdmin, Body: schemas.ReviewDBUserFull{}, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/admin/users/{id}", Summary: "A user by discord or ReviewDB id", Auth: authAdmin, Response: schemas.ReviewDBUserFull{}},
+	{Method: "POST", Path: "/api/reviewdb/admin/users/{platform}/{platformid}/ban", Summary: "Ban an account of any platform", Auth: authAdmin, Body: BanRequest{}, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/admin/badges", Summary: "Badge definitions", Auth: authAdmin, Response: []schemas.BadgeDefinition{}},
+	{Method: "POST", Path: "/api/reviewdb/admin/badges", Summary: "Create a badge definition", Auth: authAdmin, Body: schemas.BadgeDefinition{}, Response: schemas.BadgeDefinition{}},
+	{Method: "PATCH", Path: "/api/reviewdb/admin/badges/{badgeid}", Summary: "Update a badge definition", Auth: authAdmin, Body: schemas.BadgeDefinition{}, Response: schemas.BadgeDefinition{}},
+	{Method: "DELETE", Path: "/api/reviewdb/admin/badges/{badgeid}", Summary: "Delete a badge definition", Auth: authAdmin, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/admin/badges/{badgeid}/assignments", Summary: "Users that have a badge", Auth: authAdmin, Response: []schemas.BadgeAssignment{}},
+	{Method: "PUT", Path: "/api/reviewdb/admin/badges/{badgeid}/assignments/{discordid}", Summary: "Give a badge to a user", Auth: authAdmin, Body: AssignBadgeRequest{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/reviewdb/admin/badges/{badgeid}/assignments/{discordid}", Summary: "Take a badge from a user", Auth: authAdmin, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/admin/reviews/{reviewid}/revisions", Summary: "Edit history of a review", Auth: authAdmin, Response: ReviewRevisionsResponse{}},
+	{Method: "POST", Path: "/api/reviewdb/admin/reviews/{reviewid}/restore", Summary: "Restore a deleted review", Auth: authAdmin, Response: Response{}},
+	{Method: "GET", Path: "/api/reviewdb/admin/votes/abuse", Summary: "Users that look like they abuse votes", Auth: authAdmin, Query: []apiParam{{Name: "min_votes", Type: "integer"}}, Response: modules.VoteAbuseReport{}},
+	{Method: "GET", Path: "/api/reviewdb/admin/webhook-jobs", Summary: "Queued discord webhooks", Auth: authAdmin, Query: append([]apiParam{{Name: "status", Description: "pending, sent or dead"}}, paginationParams...), Response: []schemas.WebhookJob{}},
+	{Method: "POST", Path: "/api/reviewdb/admin/webhook-jobs/{jobid}/retry", Summary: "Send a dead discord webhook again", Auth: authAdmin, Response: Response{}},
+
+	// ReviewDB Twitter
+	{Method: "GET", Path: "/api/reviewdb-twitter/auth", Summary: "Exchange a twitter oauth code for a ReviewDB Twitter token", Query: []apiParam{{Name: "code"}}, Produces: "text/html"},
+	{Method: "GET", Path: "/api/reviewdb-twitter/users/{profileid}/reviews", Summary: "Reviews of a twitter profile", Query: paginationParams[:1], Response: ReviewsResponseTwitter{}},
+	{Method: "PUT", Path: "/api/reviewdb-twitter/users/{profileid}/reviews", Summary: "Review a twitter profile", Auth: authTwitter, Body: schemas.TwitterRequestData{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/reviewdb-twitter/users/{profileid}/reviews", Summary: "Delete a review, profileid is the id of the review", Auth: authTwitter, Response: Response{}},
+	{Method: "PUT", Path: "/api/reviewdb-twitter/reports", Summary: "Report a review", Auth: authTwitter, Body: modules.ReportData{}, Response: Response{}},
+	{Method: "POST", Path: "/api/reviewdb-twitter/reviews/{reviewid}/vote", Summary: "Up or downvote a review", Auth: authTwitter, Body: VoteRequest{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/reviewdb-twitter/reviews/{reviewid}/vote", Summary: "Remove your vote on a review", Auth: authTwitter, Response: Response{}},
+
+	// v2
+	{Method: "GET", Path: "/api/v2/badges", Summary: "Badges by discord id", Response: map[string][]BadgeV2{}},
+	{Method: "GET", Path: "/api/v2/events", Summary: "Server-sent events of review activity on profiles, authorized users also receive their notifications", Query: []apiParam{{Name: "profiles", Description: "comma separated discord ids, at most 50"}}, Produces: "text/event-stream"},
+	{Method: "GET", Path: "/api/v2/users/{discordid}", Summary: "Public profile of a discord user", Response: ProfileV2{}},
+	{Method: "GET", Path: "/api/v2/users/{discordid}/reviews", Summary: "Reviews of a profile, reviews of opted out users are only returned to admins", Query: append([]apiParam{{Name: "alwaysIncludeReviewsBy"}}, paginationParams...), Response: ReviewPageV2{}},
+	{Method: "PUT", Path: "/api/v2/users/{discordid}/reviews", Summary: "Add or update your review of a profile", Auth: authUser, Body: ReviewRequestV2{}, Response: AddReviewResponse{}},
+	{Method: "POST", Path: "/api/v2/reviews/search", Summary: "Search reviews, admins only", Auth: authUser, Body: SearchRequestV2{}, Response: []ReviewV2{}},
+	{Method: "DELETE", Path: "/api/v2/reviews/{reviewid}", Summary: "Delete a review", Auth: authUser, Response: Response{}},
+	{Method: "POST", Path: "/api/v2/reviews/{reviewid}/reports", Summary: "Report a review", Auth: authUser, Response: Response{}},
+	{Method: "PUT", Path: "/api/v2/reviews/{reviewid}/vote", Summary: "Up or downvote a review", Auth: authUser, Body: VoteRequest{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/v2/reviews/{reviewid}/vote", Summary: "Remove your vote on a review", Auth: authUser, Response: Response{}},
+	{Method: "PUT", Path: "/api/v2/reviews/{reviewid}/pin", Summary: "Pin a review on your profile", Auth: authUser, Response: Response{}},
+	{Method: "DELETE", Path: "/api/v2/reviews/{reviewid}/pin", Summary: "Unpin a review on your profile", Auth: authUser, Response: Response{}},
+	{Method: "PUT", Path: "/api/v2/reviews/{reviewid}/hide", Summary: "Hide a review on your profile", Auth: authUser, Body: HideReviewRequest{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/v2/reviews/{reviewid}/hide", Summary: "Unhide a review on your profile", Auth: authUser, Response: Response{}},
+	{Method: "GET", Path: "/api/v2/me", Summary: "Your own user", Auth: authUser, Response: MeV2{}},
+	{Method: "GET", Path: "/api/v2/me/settings", Summary: "Your settings", Auth: authUser, Response: SettingsV2{}},
+	{Method: "PATCH", Path: "/api/v2/me/settings", Summary: "Update your settings", Auth: authUser, Body: SettingsRequestV2{}, Response: Response{}},
+	{Method: "GET", Path: "/api/v2/me/blocks", Summary: "Users you blocked", Auth: authUser, Query: paginationParams, Response: []UserV2{}},
+	{Method: "PUT", Path: "/api/v2/me/blocks/{discordid}", Summary: "Block a user", Auth: authUser, Response: Response{}},
+	{Method: "DELETE", Path: "/api/v2/me/blocks/{discordid}", Summary: "Unblock a user", Auth: authUser, Response: Response{}},
+	{Method: "DELETE", Path: "/api/v2/me/notifications/{notificationid}", Summary: "Mark a notification as read", Auth: authUser, Response: Response{}},
+	{Method: "GET", Path: "/api/v2/me/webhooks", Summary: "Webhooks events of your profile are sent to", Auth: authUser, Response: []schemas.UserWebhook{}},
+	{Method: "POST", Path: "/api/v2/me/webhooks", Summary: "Add a webhook, the response contains the secret deliveries are signed with", Auth: authUser, Body: CreateWebhookRequest{}, Response: CreatedWebhookResponse{}},
+	{Method: "DELETE", Path: "/api/v2/me/webhooks/{webhookid}", Summary: "Delete a webhook and its deliveries", Auth: authUser, Response: Response{}},
+	{Method: "POST", Path: "/api/v2/me/webhooks/{webhookid}/ping", Summary: "Send a ping delivery to a webhook", Auth: authUser, Response: Response{}},
+	{Method: "GET", Path: "/api/v2/me/webhooks/{webhookid}/deliveries", Summary: "Delivery log of a webhook, newest first", Auth: authUser, Query: paginationParams, Response: []schemas.WebhookDelivery{}},
+
+	// StupidityDB
+	{Method: "GET", Path: "/api/stupiditydb/metrics", Summary: "Metrics users can be rated on", Response: RatingMetricsResponse{}},
+	{Method: "GET", Path: "/api/stupiditydb/users/{discordid}", Summary: "Rating summary of a user, includes your vote when authorized", Query: []apiParam{metricParam}, Response: RatingResponse{}},
+	{Method: "PUT", Path: "/api/stupiditydb/users/{discordid}/vote", Summary: "Rate a user", Auth: authUser, Body: RatingVoteRequest{}, Response: Response{}},
+	{Method: "DELETE", Path: "/api/stupiditydb/users/{discordid}/vote", Summary: "Remove your rating of a user", Auth: authUser, Query: []apiParam{metricParam}, Response: Response{}},
+	{Method: "PUT", Path: "/api/stupiditydb/me/metrics/{metric}", Summary: "Allow being rated on an opt-in metric", Auth: authUser, Response: Response{}},
+	{Method: "DELETE", Path: "/api/stupiditydb/me/metrics/{metric}", Summary: "Stop being rated on an opt-in metric, removes its votes", Auth: authUser, Response: Response{}},
+	{Method: "GET", Path: "/getuser", Summary: "Legacy average stupidity of a user, None if nobody voted", Query: []apiParam{{Name: "discordid"}}, Produces: "text/plain"},
+	{Method: "POST", Path: "/vote", Summary: "Legacy stupidity vote, the token is a ReviewDB token", Body: modules.SDB_RequestData{}, Produces: "text/plain"},
+	{Method: "GET", Path: "/auth", Summary: "Legacy StupidityDB login, redirects with a ReviewDB token", Query: []apiParam{{Name: "code"}}, Produces: "redirect"},
+	{Method: "GET", Path: "/admins", Summary: "Discord ids of the admins", Response: []string{}},
+}
+
+// undocumentedRoutes are served by NewRouter but aren't part of the API
+var undocumentedRoutes = []string{
+	"/", "/metrics", "/static/*", "/assets/*", "/interactions", "/receiveToken/{token}", "/error", "/api/openapi.json",
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+type openAPIBuilder struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+// schema describes how encoding/json encodes values of t
+func (b *openAPIBuilder) schema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		inner := b.schema(t.Elem())
+		if _, isRef := inner["$ref"]; isRef {
+			return map[string]any{"allOf": []any{inner}, "nullable": true}
+		}
+		inner["nullable"] = true
+		return inner
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	// types like snowflakes encode themselves as strings
+	marshaler := reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	if t.Kind() != reflect.Struct && (t.Implements(marshaler) || reflect.PointerTo(t).Implements(marshaler)) {
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + b.componentName(t)}
+	}
+	return map[string]any{}
+}
+
+// componentName registers the named struct t as a component, prefixing the package when two packages use a name
+func (b *openAPIBuilder) componentName(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := b.components[name]; taken {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+
+	b.names[t] = name
+	b.components[name] = nil // reserved so recursive types refer to it
+	b.components[name] = b.structSchema(t)
+	return name
+}
+
+func (b *openAPIBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	b.addFields(t, properties)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func (b *openAPIBuilder) addFields(t reflect.Type, properties map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// embedded structs without a json name are flattened like encoding/json does
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(embedded, properties)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if strings.Contains(options, "string") {
+			properties[name] = map[string]any{"type": "string"}
+		} else {
+			properties[name] = b.schema(field.Type)
+		}
+	}
+}
+
+func (b *openAPIBuilder) content(value any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(value))},
+	}
+}
+
+func (b *openAPIBuilder) operation(op apiOperation) map[string]any {
+	tag := "reviewdb"
+	switch {
+	case strings.HasPrefix(op.Path, "/api/reviewdb/admin"):
+		tag = "admin"
+	case strings.HasPrefix(op.Path, "/api/reviewdb-twitter"):
+		tag = "twitter"
+	case strings.HasPrefix(op.Path, "/api/v2"):
+		tag = "v2"
+	case !strings.HasPrefix(op.Path, "/api/reviewdb"):
+		tag = "stupiditydb"
+	}
+
+	parameters := []any{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		parameters = append(parameters, map[string]any{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+		})
+	}
+	for _, param := range op.Query {
+		paramType := common.Ternary(param.Type == "", "string", param.Type)
+		parameter := map[string]any{"name": param.Name, "in": "query", "schema": map[string]any{"type": paramType}}
+		if param.Description != "" {
+			parameter["description"] = param.Description
+		}
+		parameters = append(parameters, parameter)
+	}
+
+	responses := map[string]any{
+		"default": map[string]any{"description": "Error", "content": b.content(Response{})},
+	}
+	switch op.Produces {
+	case "":
+		responses["200"] = map[string]any{"description": "OK", "content": b.content(op.Response)}
+	case "redirect":
+		responses["307"] = map[string]any{"description": "Redirect"}
+	default:
+		responses["200"] = map[string]any{
+			"description": "OK",
+			"content":     map[string]any{op.Produces: map[string]any{"schema": map[string]any{"type": "string"}}},
+		}
+	}
+
+	operation := map[string]any{
+		"summary":    op.Summary,
+		"tags":       []string{tag},
+		"parameters": parameters,
+		"responses":  responses,
+	}
+
+	if op.Body != nil {
+		operation["requestBody"] = map[string]any{"content": b.content(op.Body)}
+	}
+
+	switch op.Auth {
+	case authUser:
+		operation["security"] = []any{map[string]any{"token": []string{}}}
+	case authAdmin:
+		operation["security"] = []any{map[string]any{"adminToken": []string{}}}
+	case authTwitter:
+		operation["security"] = []any{map[string]any{"twitterToken": []string{}}}
+	}
+
+	return operation
+}
+
+func tokenScheme(description string) map[string]any {
+	return map[string]any{"type": "apiKey", "in": "header", "name": "Authorization", "description": description}
+}
+
+// BuildOpenAPI generates the OpenAPI 3 document of apiOperations
+func BuildOpenAPI() map[string]any {
+	b := &openAPIBuilder{components: map[string]any{}, names: map[reflect.Type]string{}}
+
+	paths := map[string]map[string]any{}
+	for _, op := range apiOperations {
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]any{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = b.operation(op)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "ReviewDB",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"token":        tokenScheme("ReviewDB token"),
+				"adminToken":   tokenScheme("ReviewDB token of an admin"),
+				"twitterToken": tokenScheme("ReviewDB Twitter token"),
+			},
+		},
+	}
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+)
+
+func ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		openAPIJSON, _ = json.Marshal(BuildOpenAPI())
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIJSON)
+}
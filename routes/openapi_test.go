@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	routed := map[string]bool{}
+	err := chi.Walk(NewRouter(), func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routed[method+" "+route] = true
+
+		if slices.Contains(undocumentedRoutes, route) {
+			return nil
+		}
+
+		documented := slices.ContainsFunc(apiOperations, func(op apiOperation) bool { return op.Path == route })
+		if !documented {
+			t.Errorf("%s %s is not documented in apiOperations", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, op := range apiOperations {
+		if !routed[op.Method+" "+op.Path] {
+			t.Errorf("%s %s is documented but not routed", op.Method, op.Path)
+		}
+	}
+}
+
+func TestOpenAPIReferencesResolve(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ServeOpenAPI(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	var spec struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spec.Paths) == 0 {
+		t.Fatal("spec has no paths")
+	}
+
+	for _, ref := range regexp.MustCompile(`"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(rec.Body.String(), -1) {
+		if _, ok := spec.Components.Schemas[ref[1]]; !ok {
+			t.Errorf("schema %s is referenced but not defined", ref[1])
+		}
+	}
+
+	for _, name := range []string{"UR_RequestData", "BlockRequest", "FilterStruct"} {
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			t.Errorf("request body %s is missing", name)
+		}
+	}
+}
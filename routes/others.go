@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"server-go/common"
+	"server-go/modules"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -17,16 +18,22 @@ var HandleInteractions = func(w http.ResponseWriter, r *http.Request) {
 
 	message := append([]byte(timestamp), body...)
 	if !common.VerifySignature(signature, message) {
-		w.WriteHeader(401)
+		Error(w, modules.UnauthorizedError("invalid_signature", "Invalid signature"))
 		return
 	}
 	var data InteractionsData
 
-	json.Unmarshal(message[len(timestamp):], &data)
-	response, err := Interactions(data)
+	json.Unmarshal(body, &data)
+	var response string
+	var err error
+	// slash commands are routed by arikawa, components and modals by their custom id
+	if data.Type == 2 {
+		response, err = commandInteraction(body)
+	} else {
+		response, err = Interactions(data)
+	}
 	if err != nil {
-		println(err.Error())
-		w.WriteHeader(500)
+		Error(w, err)
 		return
 	}
 
@@ -38,3 +45,16 @@ var ReceiveToken = func(w http.ResponseWriter, r *http.Request) {
 	token := chi.URLParam(r, "token")
 	io.WriteString(w, "You have successfully logged in! Your token is: "+token+"\n\n You can now close this window.")
 }
+
+// ErrorPage is where failed oauth logins are redirected to
+func ErrorPage(w http.ResponseWriter, r *http.Request) {
+	Error(w, modules.ValidationError("login_failed", "An error occurred while logging in, please try again"))
+}
+
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Error(w, modules.NotFoundError("route_not_found", "Route not found"))
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	sendError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+}
@@ -0,0 +1,154 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	chiprometheus "server-go/middlewares/prometheus"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httprate"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewRouter registers every route of the server. Documented routes also have to be described in openapi.go.
+func NewRouter() *chi.Mux {
+	mux := chi.NewRouter()
+	prometheusMiddleware := chiprometheus.NewPatternMiddleware("reviewdb")
+
+	mux.Use(CorsMiddleware)
+	mux.Use(httprate.LimitByRealIP(8, 1*time.Second))
+	mux.Use(prometheusMiddleware)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "artgallery/index.html")
+	})
+
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir("artgallery/static"))))
+
+	mux.Handle("/assets/*", http.StripPrefix("/assets/", http.FileServer(http.Dir("artgallery/assets"))))
+
+	mux.HandleFunc("/interactions", HandleInteractions)
+
+	mux.HandleFunc("/receiveToken/{token}", ReceiveToken)
+
+	//StupidityDB
+
+	mux.HandleFunc("/vote", VoteStupidity)
+
+	mux.HandleFunc("/getuser", GetStupidity)
+
+	mux.HandleFunc("/auth", StupidityDBAuth)
+
+	mux.Route("/api/stupiditydb", func(r chi.Router) {
+		r.Get("/metrics", GetRatingMetrics)
+		r.Get("/users/{discordid}", GetUserRatingSummary)
+		r.Put("/users/{discordid}/vote", PutRatingVote)
+		r.Delete("/users/{discordid}/vote", DeleteRatingVote)
+		r.Put("/me/metrics/{metric}", SetRatingMetricOptIn)
+		r.Delete("/me/metrics/{metric}", SetRatingMetricOptIn)
+	})
+
+	//ReviewDB
+
+	mux.Route("/api/reviewdb", func(r chi.Router) {
+		r.Get("/leaderboard", GetLeaderBoard)
+		r.Get("/leaderboard/me", GetMyLeaderboardRank)
+		r.Get("/reputation/leaderboard", GetReputationLeaderboard)
+		r.Get("/reputation/leaderboard/me", GetMyReputationLeaderboardRank)
+		r.Route("/users/{discordid}/reviews", func(r1 chi.Router) {
+			r1.Get("/", GetReviews)
+			r1.Get("/votes", GetReviewVotes)
+			r1.Put("/", AddReview)
+			r1.Delete("/", DeleteReview)
+		})
+		r.Get("/users/{discordid}", GetUserInfoByID)
+		r.Get("/users/{discordid}/rating", GetUserRating)
+		r.Get("/users/{discordid}/reputation", GetUserReputation)
+		r.Get("/users/{discordid}/reputation/history", GetUserReputationHistory)
+		r.Route("/reviews/{reviewid}", func(rv chi.Router) {
+			rv.Use(ReviewMiddleware)
+			rv.Post("/vote", VoteReview)
+			rv.Delete("/vote", DeleteReviewVote)
+			rv.Put("/pin", PinReview)
+			rv.Delete("/pin", UnpinReview)
+			rv.Put("/hide", HideReview)
+			rv.Delete("/hide", UnhideReview)
+		})
+		r.HandleFunc("/users", GetUserInfo)
+		r.HandleFunc("/reports", ReportReview)
+		r.HandleFunc("/badges", GetAllBadges)
+		r.Get("/badges/map", GetBadgesMap)
+		r.HandleFunc("/reviews", SearchReview)
+		r.HandleFunc("/blocks", Blocks)
+		r.HandleFunc("/settings", Settings)
+		r.HandleFunc("/notifications", Notifications)
+		r.Put("/appeals", AppealReview)
+		r.Get("/me/connections", GetConnections)
+		r.Post("/me/connections/twitter", LinkTwitterConnection)
+		r.Patch("/me/connections/{connectionid}", PatchConnection)
+		r.Delete("/me/connections/{connectionid}", DeleteConnection)
+		r.Get("/restrictions", GetReviewRestrictions)
+		r.Patch("/restrictions", SetReviewRestrictions)
+	})
+
+	mux.HandleFunc("/admins", Admins)
+
+	mux.Group(func(r chi.Router) {
+		r.Use(httprate.LimitByRealIP(10, 1*time.Hour))
+
+		r.HandleFunc("/api/reviewdb/authweb", ReviewDBAuthWeb)
+		r.HandleFunc("/api/reviewdb/auth", ReviewDBAuth)
+	})
+
+	mux.Group(func(r chi.Router) {
+		r.Use(AdminMiddleware)
+
+		r.Route(("/api/reviewdb/admin"), func(r chi.Router) {
+			r.Get("/filters", GetFilters)
+			r.Put("/filters", AddFilter)
+			r.Delete("/filters", DeleteFilter)
+			r.Get("/reload", ReloadConfig)
+			r.Get("/reports", GetReports)
+			r.Get("/users", GetUsersAdmin)
+			r.Get("/users/{id}", GetUserAdmin)
+			r.Patch("/users", PatchUserAdmin)
+			r.Get("/badges", GetBadgeDefinitions)
+			r.Post("/badges", CreateBadgeDefinition)
+			r.Patch("/badges/{badgeid}", UpdateBadgeDefinition)
+			r.Delete("/badges/{badgeid}", DeleteBadgeDefinition)
+			r.Get("/badges/{badgeid}/assignments", GetBadgeAssignments)
+			r.Put("/badges/{badgeid}/assignments/{discordid}", AssignBadge)
+			r.Delete("/badges/{badgeid}/assignments/{discordid}", UnassignBadge)
+			r.Get("/reviews/{reviewid}/revisions", GetReviewRevisions)
+			r.Post("/reviews/{reviewid}/restore", RestoreReview)
+			r.Get("/votes/abuse", GetVoteAbuseReport)
+			r.Post("/users/{platform}/{platformid}/ban", BanPlatformUser)
+			r.Get("/webhook-jobs", GetWebhookJobs)
+			r.Post("/webhook-jobs/{jobid}/retry", RetryWebhookJob)
+		})
+	})
+
+	mux.Route("/api/reviewdb-twitter", func(r chi.Router) {
+		r.HandleFunc("/auth", ReviewDBTwitterAuth)
+		r.HandleFunc("/users/{profileid}/reviews", HandleTwitterRoutes)
+		r.HandleFunc("/reports", ReportTwitterReview)
+		r.Post("/reviews/{reviewid}/vote", VoteTwitterReview)
+		r.Delete("/reviews/{reviewid}/vote", DeleteTwitterReviewVote)
+	})
+
+	mux.Route("/api/v2", v2Routes)
+
+	mux.HandleFunc("/api/reviewdb/oauth/github", LinkGithub)
+	mux.Post("/api/reviewdb/webhooks/github", HandleGithubWebhook)
+
+	mux.Get("/api/openapi.json", ServeOpenAPI)
+
+	mux.HandleFunc("/error", ErrorPage)
+	mux.NotFound(NotFound)
+	mux.MethodNotAllowed(MethodNotAllowed)
+
+	return mux
+}
@@ -4,12 +4,17 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"server-go/common"
 	"server-go/modules"
+	"slices"
 	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
 )
 
 var StupidityDBAuth = func(w http.ResponseWriter, r *http.Request) {
-	token, err := modules.AddStupidityDBUser(r.URL.Query().Get("code"))
+	token, err := modules.AddUserReviewsUser(r.URL.Query().Get("code"), "stupiditydb", "/auth", r.Header.Get("CF-Connecting-IP"))
 
 	if err != nil {
 		http.Redirect(w, r, "/error", http.StatusTemporaryRedirect)
@@ -21,11 +26,10 @@ var StupidityDBAuth = func(w http.ResponseWriter, r *http.Request) {
 var Admins = func(w http.ResponseWriter, r *http.Request) {
 	admins, err := modules.GetAdmins()
 	if err != nil {
-		io.WriteString(w, err.Error()+"\n")
+		Error(w, err)
 		return
 	}
-	jsonAdmins, _ := json.Marshal(admins)
-	io.WriteString(w, string(jsonAdmins))
+	common.SendStructResponse(w, admins)
 }
 
 var GetStupidity = func(w http.ResponseWriter, r *http.Request) {
@@ -33,13 +37,14 @@ var GetStupidity = func(w http.ResponseWriter, r *http.Request) {
 	userID, err := strconv.ParseInt(r.URL.Query().Get("discordid"), 10, 64)
 
 	if err != nil {
-		io.WriteString(w, "An Error occurred\n")
+		Error(w, modules.ValidationError("invalid_discord_id", "Invalid discord id"))
 		return
 	}
 
-	stupidity, error := modules.GetStupidity(userID)
-	if error != nil {
-		io.WriteString(w, "An Error occurred\n")
+	// the stupidity itself stays plain text for old clients, errors are json like everywhere else
+	stupidity, err := modules.GetStupidity(userID)
+	if err != nil {
+		Error(w, err)
 		return
 	}
 	if stupidity == -1 {
@@ -52,9 +57,167 @@ var GetStupidity = func(w http.ResponseWriter, r *http.Request) {
 var VoteStupidity = func(w http.ResponseWriter, r *http.Request) {
 
 	var data modules.SDB_RequestData
-	json.NewDecoder(r.Body).Decode(&data)
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		Error(w, modules.ErrInvalidRequest)
+		return
+	}
 
-	res := modules.VoteStupidity(data.DiscordID, data.Token, data.Stupidity, data.SenderDiscordID)
+	// like GetStupidity only the success message stays plain text for old clients
+	res, err := modules.VoteStupidity(data.DiscordID, data.Token, data.Stupidity, data.SenderDiscordID)
+	if err != nil {
+		Error(w, err)
+		return
+	}
 
 	io.WriteString(w, res)
 }
+
+type RatingResponse struct {
+	Response
+	*modules.RatingSummary
+}
+
+type RatingMetricsResponse struct {
+	Response
+	Metrics []modules.RatingMetric `json:"metrics"`
+}
+
+type RatingVoteRequest struct {
+	Metric string `json:"metric"` // defaults to stupidity
+	Value  *int32 `json:"value"`
+}
+
+func parseRatedUser(w http.ResponseWriter, r *http.Request) (discordID int64, ok bool) {
+	discordID, err := strconv.ParseInt(chi.URLParam(r, "discordid"), 10, 64)
+	if err != nil || discordID <= 0 {
+		Error(w, modules.ValidationError("invalid_discord_id", "Invalid discord id"))
+		return 0, false
+	}
+	return discordID, true
+}
+
+func GetRatingMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics := []modules.RatingMetric{}
+	for _, metric := range modules.RatingMetrics {
+		metrics = append(metrics, metric)
+	}
+	slices.SortFunc(metrics, func(a, b modules.RatingMetric) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	common.SendStructResponse(w, RatingMetricsResponse{Response{Success: true}, metrics})
+}
+
+// GetUserRatingSummary returns the vote summary of a metric, with the caller's own vote when they are authorized
+func GetUserRatingSummary(w http.ResponseWriter, r *http.Request) {
+	discordID, ok := parseRatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	metric, err := modules.GetRatingMetric(r.URL.Query().Get("metric"))
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	voterDiscordID := ""
+	if user, err := Authorize(r); err == nil {
+		voterDiscordID = user.DiscordID
+	}
+
+	summary, err := modules.GetRatingSummary(discordID, metric, voterDiscordID)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, RatingResponse{Response{Success: true}, summary})
+}
+
+func PutRatingVote(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	if user.IsBanned() {
+		Error(w, modules.ErrBanned)
+		return
+	}
+
+	discordID, ok := parseRatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var body RatingVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Value == nil {
+		Error(w, modules.ErrInvalidRequest)
+		return
+	}
+
+	metric, err := modules.GetRatingMetric(body.Metric)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	updated, err := modules.SetRating(user.DiscordID, discordID, metric, *body.Value)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: common.Ternary(updated, "Updated your vote", "Successfully voted")})
+}
+
+func DeleteRatingVote(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	discordID, ok := parseRatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	metric, err := modules.GetRatingMetric(r.URL.Query().Get("metric"))
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	if err = modules.DeleteRating(user.DiscordID, discordID, metric); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Removed your vote"})
+}
+
+// SetRatingMetricOptIn opts the caller in (PUT) or out (DELETE) of being rated on an opt-in metric
+func SetRatingMetricOptIn(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	metric, err := modules.GetRatingMetric(chi.URLParam(r, "metric"))
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	optIn := r.Method == http.MethodPut
+	if err = modules.SetMetricOptIn(user.DiscordID, metric, optIn); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: common.Ternary(optIn, "Opted in to ", "Opted out of ") + metric.Name})
+}
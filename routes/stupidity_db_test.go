@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVoteStupidityAnswersErrorsWithJSON(t *testing.T) {
+	for _, test := range []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"discordid": 1, "stupidity": 50}`, http.StatusUnauthorized, "unauthorized"},
+		{`{"discordid": `, http.StatusBadRequest, "invalid_request"},
+	} {
+		w := httptest.NewRecorder()
+		VoteStupidity(w, httptest.NewRequest(http.MethodPost, "/vote", strings.NewReader(test.body)))
+
+		var response Response
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("%s: body is not json: %v", test.body, err)
+		}
+		if w.Code != test.status || response.Success || response.Error != test.code {
+			t.Errorf("%s: got %d %+v, want %d %s", test.body, w.Code, response, test.status, test.code)
+		}
+	}
+}
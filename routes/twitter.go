@@ -8,28 +8,43 @@ import (
 	"server-go/common"
 	"server-go/database/schemas"
 	"server-go/modules"
+	"server-go/modules/filtering"
 	modules_twitter "server-go/modules/twitter"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
+type TwitterAuthResponse struct {
+	ID          int32                   `json:"id"`
+	TwitterID   string                  `json:"twitterId"`
+	Username    string                  `json:"username"`
+	DisplayName string                  `json:"displayName"`
+	AvatarURL   string                  `json:"avatarURL"`
+	Badges      []schemas.UserBadge     `json:"badges"`
+	BanInfo     *schemas.ReviewDBBanLog `json:"banInfo"`
+	Token       string                  `json:"token"`
+}
+
 func ReviewDBTwitterAuth(w http.ResponseWriter, r *http.Request) {
 
 	user, err := modules_twitter.AddTwitterUser(r.URL.Query().Get("code"), r.Header.Get("CF-Connecting-IP"))
-	
+
 	if err != nil {
-		println(err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 
-	res := struct {
-		schemas.TwitterUser
-		Token string `json:"token"`
-	}{
-		TwitterUser: *user,
+	res := TwitterAuthResponse{
+		ID:          user.ID,
+		TwitterID:   user.DiscordID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+		Badges:      modules_twitter.GetBadgesOfUser(user.DiscordID),
+		BanInfo:     user.BanInfo,
 		Token:       user.Token,
 	}
 
@@ -42,105 +57,114 @@ func ReviewDBTwitterAuth(w http.ResponseWriter, r *http.Request) {
 }
 
 func AddTwitterReview(w http.ResponseWriter, r *http.Request) {
-	response := Response{}
-
 	user, err := AuthorizeTwitter(r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
 	var data schemas.TwitterRequestData
 	err = json.NewDecoder(r.Body).Decode(&data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		Error(w, modules.ErrInvalidRequest)
 		return
 	}
 
-	data.ProfileID = chi.URLParam(r, "profileid")
+	profileID, err := strconv.ParseInt(chi.URLParam(r, "profileid"), 10, 64)
+	if err != nil {
+		Error(w, modules.ValidationError("invalid_profile_id", "Invalid profile id"))
+		return
+	}
 
 	if len(data.Comment) > 1000 {
-		response.Message = "Comment Too Long"
+		Error(w, modules.ValidationError("comment_too_long", "Comment Too Long"))
+		return
 	} else if len(strings.TrimSpace(data.Comment)) == 0 {
-		response.Message = "Write Something Guh"
+		Error(w, modules.ValidationError("empty_comment", "Write Something Guh"))
+		return
 	}
 
-	res, err := modules_twitter.AddReview(user, data)
+	review := schemas.UserReview{
+		ProfileID:    profileID,
+		ReviewerID:   user.ID,
+		Comment:      strings.TrimSpace(data.Comment),
+		RepliesTo:    data.RepliesTo,
+		TimestampStr: time.Now(),
+		Platform:     schemas.PlatformTwitter,
+	}
+
+	for _, filterFunction := range filtering.ReviewDB {
+		if err = filterFunction(user, &review); err != nil {
+			Error(w, err)
+			return
+		}
+	}
+
+	res, err := modules.AddReview(user, &review)
 
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		Error(w, err)
 		return
 	}
 
-	w.Write([]byte(res))
-	w.WriteHeader(http.StatusOK)
+	common.SendStructResponse(w, Response{Success: true, Message: res})
 }
 
 type ReviewsResponseTwitter struct {
-	Message     string                      `json:"message"` // for errors
-	HasNextPage bool                        `json:"hasNextPage"`
-	ReviewCount int                         `json:"reviewCount"`
-	Reviews     []schemas.TwitterUserReview `json:"reviews"`
+	Message     string                  `json:"message"` // for errors
+	HasNextPage bool                    `json:"hasNextPage"`
+	ReviewCount int                     `json:"reviewCount"`
+	Reviews     []schemas.TwitterReview `json:"reviews"`
 }
 
 func GetTwitterReviews(w http.ResponseWriter, r *http.Request) {
-	userid := chi.URLParam(r, "profileid")
-	reviews, count, err := modules_twitter.GetTwitterReviews(userid, 0)
+	requester, _ := AuthorizeTwitter(r)
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	reviews, count, err := modules_twitter.GetTwitterReviews(requester, chi.URLParam(r, "profileid"), offset)
+	if err != nil {
+		Error(w, err)
+		return
+	}
 
 	res := ReviewsResponseTwitter{
 		HasNextPage: len(reviews) > 50,
 		ReviewCount: count,
+		Reviews:     reviews,
 	}
 
 	if len(reviews) > 50 {
 		res.Reviews = reviews[:len(reviews)-1]
-	} else if len(reviews) == 0 {
-		res.Reviews = []schemas.TwitterUserReview{}
-		// we dont wanna send null
-	} else {
-		res.Reviews = reviews
-	}
-
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
 	}
 
 	common.SendStructResponse(w, res)
 }
 
 func DeleteReviewTwitter(w http.ResponseWriter, r *http.Request) {
-	user, err := AuthorizeTwitter(r)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized, please reauthroize"))
+	if _, err := AuthorizeTwitter(r); err != nil {
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 	reviewID, err := strconv.Atoi(chi.URLParam(r, "profileid"))
 
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid review id"))
+		Error(w, modules.ValidationError("invalid_review_id", "Invalid review id"))
 		return
 	}
 
-	err = modules_twitter.DeleteReview(user, int32(reviewID))
+	err = modules.DeleteReview(int32(reviewID), r.Header.Get("Authorization"))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("An error occured while deleting review"))
+		Error(w, err)
 		return
 	}
 
-	w.Write([]byte("Successfully deleted review"))
-	w.WriteHeader(http.StatusOK)
+	common.SendStructResponse(w, Response{Success: true, Message: "Successfully deleted review"})
 }
 
 func ReportTwitterReview(w http.ResponseWriter, r *http.Request) {
 	user, err := AuthorizeTwitter(r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized, please reauthroize"))
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
@@ -148,20 +172,25 @@ func ReportTwitterReview(w http.ResponseWriter, r *http.Request) {
 	json.NewDecoder(r.Body).Decode(&data)
 
 	if data.ReviewID == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid Request"))
+		Error(w, modules.ErrInvalidRequest)
 		return
 	}
 
 	err = modules_twitter.ReportReview(user, data.ReviewID)
 
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		Error(w, err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Successfully reported review"))
+	common.SendStructResponse(w, Response{Success: true, Message: "Successfully reported review"})
+}
+
+func VoteTwitterReview(w http.ResponseWriter, r *http.Request) {
+	voteReview(w, r, AuthorizeTwitter)
+}
+
+func DeleteTwitterReviewVote(w http.ResponseWriter, r *http.Request) {
+	deleteReviewVote(w, r, AuthorizeTwitter)
 }
 
 func HandleTwitterRoutes(w http.ResponseWriter, r *http.Request) {
@@ -51,11 +51,45 @@ type ReviewVoteResponse struct {
 	IsUpvote bool  `json:"isUpvote"`
 }
 
+type AddReviewResponse struct {
+	Response
+	Updated bool `json:"updated"`
+}
+
+type UserInfoResponse struct {
+	schemas.URUser
+	LastReviewID int32 `json:"lastReviewID"`
+	UserType     int   `json:"type"`
+	Reputation   int   `json:"reputation"`
+}
+
+// PublicUserInfo is what anyone can see about a user, it is also returned for discord users without an account
+type PublicUserInfo struct {
+	DiscordID    string                     `json:"discordID"`
+	Username     string                     `json:"username"`
+	ProfilePhoto string                     `json:"profilePhoto"`
+	Badges       []schemas.UserBadge        `json:"badges"`
+	Type         int32                      `json:"type"`
+	OptedOut     bool                       `json:"optedOut"`
+	Reputation   int                        `json:"reputation"`
+	Connections  []modules.PublicConnection `json:"connections"`
+}
+
+type SearchRequestData struct {
+	Query string `json:"query"`
+	Token string `json:"token"`
+}
+
+type HideReviewRequest struct {
+	Reason string `json:"reason"`
+}
+
+type VoteRequest struct {
+	IsUpvote bool `json:"isUpvote"`
+}
+
 func AddReview(w http.ResponseWriter, r *http.Request) {
-	response := struct {
-		Response
-		Updated bool `json:"updated"`
-	}{}
+	response := AddReviewResponse{}
 
 	var data modules.UR_RequestData
 	json.NewDecoder(r.Body).Decode(&data)
@@ -66,16 +100,16 @@ func AddReview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(data.Comment) > 1000 {
-		response.Message = "Comment Too Long"
-		w.WriteHeader(http.StatusBadRequest)
+		Error(w, modules.ValidationError("comment_too_long", "Comment Too Long"))
+		return
 	} else if len(strings.TrimSpace(data.Comment)) == 0 {
-		response.Message = "Write Something Guh"
-		w.WriteHeader(http.StatusBadRequest)
+		Error(w, modules.ValidationError("empty_comment", "Write Something Guh"))
+		return
 	}
 
 	if slices.Contains(common.OptedOut, fmt.Sprint(data.DiscordID)) {
-		response.Message = "This user opted out"
-		w.WriteHeader(http.StatusNotAcceptable) // it probably doesnt make sense but trolley
+		Error(w, modules.ForbiddenError("profile_opted_out", "This user opted out"))
+		return
 	}
 
 	if r.Header.Get("Authorization") != "" {
@@ -83,13 +117,7 @@ func AddReview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if data.Token == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		response.Message = "Invalid Request"
-		return
-	}
-
-	if response.Message != "" {
-		common.SendStructResponse(w, response)
+		Error(w, modules.ErrInvalidRequest)
 		return
 	}
 
@@ -106,6 +134,7 @@ func AddReview(w http.ResponseWriter, r *http.Request) {
 		Comment:      strings.TrimSpace(data.Comment),
 		Type:         int32(data.ReviewType),
 		TimestampStr: time.Now(),
+		Platform:     schemas.PlatformDiscord,
 	}
 
 	if data.RepliesTo != 0 {
@@ -156,23 +185,14 @@ func ReviewDBAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !slices.Contains(ClientMods, clientmod) {
-		w.WriteHeader(http.StatusPaymentRequired) // trolley
-		common.SendStructResponse(w, ReviewDBAuthResponse{
-			Success: false,
-			Message: "Invalid clientMod",
-		})
+		Error(w, modules.ValidationError("invalid_client_mod", "Invalid clientMod"))
 		return
 	}
 
 	token, err := modules.AddUserReviewsUser(r.URL.Query().Get("code"), clientmod, "/api/reviewdb/auth", r.Header.Get("CF-Connecting-IP"))
 
 	if err != nil {
-		println(err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		common.SendStructResponse(w, ReviewDBAuthResponse{
-			Token:   "",
-			Message: err.Error(),
-		})
+		Error(w, err)
 		return
 	}
 
@@ -208,17 +228,13 @@ func ReportReview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if data.Token == "" || data.ReviewID == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		response.Message = "Invalid Request"
-		common.SendStructResponse(w, response)
+		Error(w, modules.ErrInvalidRequest)
 		return
 	}
 
 	err := modules.ReportReview(data)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response.Message = err.Error()
-		common.SendStructResponse(w, response)
+		Error(w, err)
 		return
 	}
 	response.Success = true
@@ -240,20 +256,13 @@ func DeleteReview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if data.Token == "" || data.ReviewID == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		responseData.Message = "Invalid Request"
-		res, _ := json.Marshal(responseData)
-
-		w.Write(res)
+		Error(w, modules.ErrInvalidRequest)
 		return
 	}
 
 	err := modules.DeleteReviewWithData(data)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responseData.Message = err.Error()
-		res, _ := json.Marshal(responseData)
-		w.Write(res)
+		Error(w, err)
 		return
 	}
 	responseData.Success = true
@@ -280,13 +289,7 @@ func GetReviews(w http.ResponseWriter, r *http.Request) {
 		limit, err = strconv.Atoi(limitString)
 
 		if err != nil || limit <= 0 || limit > 50 {
-			w.WriteHeader(http.StatusBadRequest)
-			common.SendStructResponse(w, ReviewResponse{
-				Response: Response{
-					Success: false,
-					Message: "Invalid limit parameter",
-				},
-			})
+			Error(w, modules.ValidationError("invalid_limit", "Invalid limit parameter"))
 			return
 		}
 	}
@@ -348,10 +351,7 @@ func GetReviews(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response.Success = false
-		response.Message = err.Error()
-		common.SendStructResponse(w, response)
+		Error(w, err)
 		return
 	}
 
@@ -418,13 +418,6 @@ func GetReviews(w http.ResponseWriter, r *http.Request) {
 func GetUserInfo(w http.ResponseWriter, r *http.Request) {
 	var data modules.UR_RequestData
 
-	type UserInfo struct {
-		schemas.URUser
-		LastReviewID int32 `json:"lastReviewID"`
-		UserType     int   `json:"type"`
-		Reputation   int   `json:"reputation"`
-	}
-
 	token := r.Header.Get("Authorization")
 	if token == "" {
 		json.NewDecoder(r.Body).Decode(&data)
@@ -436,30 +429,35 @@ func GetUserInfo(w http.ResponseWriter, r *http.Request) {
 
 	user, err := modules.GetDBUserViaToken(data.Token)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
-	response := UserInfo{user, modules.GetLastReviewID(user.DiscordID), int(user.Type), user.Reputation}
+	response := UserInfoResponse{user, modules.GetLastReviewID(user.DiscordID), int(user.Type), user.Reputation}
 	response.Badges = modules.GetBadgesOfUser(user.DiscordID)
 
 	json.NewEncoder(w).Encode(response)
 }
 
-func GetAllBadges(w http.ResponseWriter, r *http.Request) {
-	type UserBadge struct {
-		schemas.UserBadge
-		DiscordID string `json:"discordID"`
-	}
+// OwnedBadge is a badge together with the user that has it
+type OwnedBadge struct {
+	schemas.UserBadge
+	DiscordID string `json:"discordID"`
+}
+
+type UserRatingResponse struct {
+	Rating int `json:"rating"`
+}
 
+func GetAllBadges(w http.ResponseWriter, r *http.Request) {
 	legacyBadges, err := modules.GetAllBadges()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
-	badges := make([]UserBadge, len(legacyBadges))
+	badges := make([]OwnedBadge, len(legacyBadges))
 	for i, b := range legacyBadges {
-		badges[i] = UserBadge{schemas.UserBadge(b), b.TargetDiscordID}
+		badges[i] = OwnedBadge{schemas.UserBadge(b), b.TargetDiscordID}
 	}
 	json.NewEncoder(w).Encode(badges)
 }
@@ -467,7 +465,7 @@ func GetAllBadges(w http.ResponseWriter, r *http.Request) {
 func GetBadgesMap(w http.ResponseWriter, r *http.Request) {
 	badges, err := modules.GetBadgesMap()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 
@@ -475,10 +473,6 @@ func GetBadgesMap(w http.ResponseWriter, r *http.Request) {
 }
 
 func SearchReview(w http.ResponseWriter, r *http.Request) {
-	type SearchRequestData struct {
-		Query string `json:"query"`
-		Token string `json:"token"`
-	}
 	response := ReviewResponse{}
 
 	var data SearchRequestData
@@ -486,10 +480,7 @@ func SearchReview(w http.ResponseWriter, r *http.Request) {
 
 	reviews, err := modules.SearchReviews(data.Query, data.Token)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response.Success = false
-		response.Message = err.Error()
-		common.SendStructResponse(w, response)
+		Error(w, err)
 		return
 	}
 
@@ -505,7 +496,7 @@ func Settings(w http.ResponseWriter, r *http.Request) {
 	token := r.Header.Get("Authorization")
 
 	if token == "" {
-		w.WriteHeader(http.StatusUnauthorized)
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
@@ -518,15 +509,14 @@ func Settings(w http.ResponseWriter, r *http.Request) {
 	settings.DiscordID = user.DiscordID
 
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 	switch r.Method {
 	case "GET":
 		settings, err := modules.GetSettings(user.DiscordID)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Printf("err: %v\n", err)
+			Error(w, err)
 			return
 		}
 		json.NewEncoder(w).Encode(settings)
@@ -534,18 +524,13 @@ func Settings(w http.ResponseWriter, r *http.Request) {
 	case "PATCH":
 		err := modules.SetSettings(settings)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Printf("err: %v\n", err)
+			Error(w, err)
 			return
 		}
-		w.WriteHeader(200)
+		common.SendStructResponse(w, Response{Success: true, Message: "Updated settings"})
 	}
 
-	optedOutUsers, err := modules.GetOptedOutUsers()
-	if err != nil {
-		fmt.Println(err)
-	}
-	common.OptedOut = optedOutUsers
+	refreshOptedOut()
 }
 
 func AppealReview(w http.ResponseWriter, r *http.Request) {
@@ -553,12 +538,12 @@ func AppealReview(w http.ResponseWriter, r *http.Request) {
 
 	user, err := Authorize(r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
 	if !user.IsBanned() {
-		w.WriteHeader(http.StatusInternalServerError)
+		Error(w, modules.ValidationError("not_banned", "You are not banned"))
 		return
 	}
 
@@ -569,12 +554,11 @@ func AppealReview(w http.ResponseWriter, r *http.Request) {
 
 	err = modules.AppealBan(appealRequest, user)
 	if err != nil {
-		fmt.Println(err)
-		w.WriteHeader(http.StatusConflict)
+		Error(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	common.SendStructResponse(w, Response{Success: true, Message: "Submitted appeal"})
 }
 
 type BlockRequest struct {
@@ -586,22 +570,27 @@ func Blocks(w http.ResponseWriter, r *http.Request) {
 	user, err := Authorize(r)
 
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
 	switch r.Method {
 	case "GET":
-		blocks, err := modules.GetBlockedUsers(user)
+		limit := common.GetIntQueryOrDefault(r, "limit", 50)
+		offset := common.GetIntQueryOrDefault(r, "offset", 0)
+		if limit <= 0 || limit > 100 || offset < 0 {
+			Error(w, modules.ValidationError("invalid_limit_or_offset", "Invalid limit or offset"))
+			return
+		}
+
+		blocks, err := modules.GetBlockedUsers(user, offset, limit)
 
 		if err != nil {
-			fmt.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
+			Error(w, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(blocks)
-		w.WriteHeader(http.StatusOK)
+		common.SendStructResponse(w, blocks)
 	case "PATCH":
 		var blockRequest BlockRequest
 		json.NewDecoder(r.Body).Decode(&blockRequest)
@@ -611,31 +600,141 @@ func Blocks(w http.ResponseWriter, r *http.Request) {
 			err = modules.BlockUser(user, blockRequest.DiscordID)
 		case "unblock":
 			err = modules.UnblockUser(user, blockRequest.DiscordID)
+		default:
+			err = modules.ValidationError("invalid_action", "Action must be block or unblock")
+		}
+
+		if err != nil {
+			Error(w, err)
+			return
 		}
-		w.WriteHeader(common.Ternary(err != nil, http.StatusInternalServerError, http.StatusOK))
+		common.SendStructResponse(w, Response{Success: true, Message: common.Ternary(blockRequest.Action == "block", "Blocked user", "Unblocked user")})
 	}
 }
 
+func PinReview(w http.ResponseWriter, r *http.Request) {
+	handleReviewModeration(w, r, func(user *schemas.URUser, reviewID int32) error {
+		return modules.PinReview(user, reviewID)
+	}, "Pinned review")
+}
+
+func UnpinReview(w http.ResponseWriter, r *http.Request) {
+	handleReviewModeration(w, r, func(user *schemas.URUser, reviewID int32) error {
+		return modules.UnpinReview(user, reviewID)
+	}, "Unpinned review")
+}
+
+func HideReview(w http.ResponseWriter, r *http.Request) {
+	var body HideReviewRequest
+	json.NewDecoder(r.Body).Decode(&body)
+
+	handleReviewModeration(w, r, func(user *schemas.URUser, reviewID int32) error {
+		return modules.HideReview(user, reviewID, body.Reason)
+	}, "Hid review")
+}
+
+func UnhideReview(w http.ResponseWriter, r *http.Request) {
+	handleReviewModeration(w, r, func(user *schemas.URUser, reviewID int32) error {
+		return modules.UnhideReview(user, reviewID)
+	}, "Unhid review")
+}
+
+func handleReviewModeration(w http.ResponseWriter, r *http.Request, action func(user *schemas.URUser, reviewID int32) error, successMessage string) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, modules.ErrUnauthorized)
+		return
+	}
+
+	// ReviewMiddleware already validated the id
+	reviewID, _ := strconv.ParseInt(chi.URLParam(r, "reviewid"), 10, 32)
+
+	err = action(user, int32(reviewID))
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: successMessage})
+}
+
+func GetReviewRestrictions(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, modules.ErrUnauthorized)
+		return
+	}
+
+	common.SendStructResponse(w, modules.GetReviewRestrictions(user))
+}
+
+func SetReviewRestrictions(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, modules.ErrUnauthorized)
+		return
+	}
+
+	var restrictions modules.ReviewRestrictions
+	if err = json.NewDecoder(r.Body).Decode(&restrictions); err != nil {
+		Error(w, modules.ErrInvalidRequest)
+		return
+	}
+
+	err = modules.SetReviewRestrictions(user, restrictions)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Updated review restrictions"})
+}
+
 func LinkGithub(w http.ResponseWriter, r *http.Request) {
 
 	user, err := Authorize(r)
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
 	err = modules.LinkGithub(r.URL.Query().Get("code"), user)
 	if err != nil {
-		http.Error(w, "An error occured", http.StatusInternalServerError)
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Linked GitHub account"})
+}
+
+// parseLeaderboardQuery reads the period, offset and limit query parameters shared by the leaderboard endpoints
+func parseLeaderboardQuery(w http.ResponseWriter, r *http.Request) (period modules.LeaderboardPeriod, offset int, limit int, ok bool) {
+	period, err := modules.ParseLeaderboardPeriod(r.URL.Query().Get("period"))
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	offset = common.GetIntQueryOrDefault(r, "offset", 0)
+	limit = common.GetIntQueryOrDefault(r, "limit", 50)
+	if offset < 0 || limit <= 0 || limit > 100 {
+		Error(w, modules.ValidationError("invalid_limit_or_offset", "Invalid offset or limit"))
 		return
 	}
+
+	return period, offset, limit, true
 }
 
 func GetLeaderBoard(w http.ResponseWriter, r *http.Request) {
-	leaderboard, err := modules.GetLeaderboard()
+	period, offset, limit, ok := parseLeaderboardQuery(w, r)
+	if !ok {
+		return
+	}
+
+	leaderboard, err := modules.GetLeaderboard(period, offset, limit)
 	if err != nil {
-		http.Error(w, "An error occured", http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 
@@ -643,40 +742,89 @@ func GetLeaderBoard(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetReputationLeaderboard(w http.ResponseWriter, r *http.Request) {
-	leaderboard, err := modules.GetReputationLeaderboard()
+	period, offset, limit, ok := parseLeaderboardQuery(w, r)
+	if !ok {
+		return
+	}
+
+	leaderboard, err := modules.GetReputationLeaderboard(period, offset, limit)
 	if err != nil {
-		http.Error(w, "An error occured", http.StatusInternalServerError)
+		Error(w, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(leaderboard)
 }
 
-func VoteReview(w http.ResponseWriter, r *http.Request) {
+func GetMyLeaderboardRank(w http.ResponseWriter, r *http.Request) {
+	user, err := Authorize(r)
+	if err != nil {
+		Error(w, modules.ErrUnauthorized)
+		return
+	}
+
+	period, err := modules.ParseLeaderboardPeriod(r.URL.Query().Get("period"))
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	rank, err := modules.GetLeaderboardRank(user, period)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, rank)
+}
+
+func GetMyReputationLeaderboardRank(w http.ResponseWriter, r *http.Request) {
 	user, err := Authorize(r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		common.SendStructResponse(w, Response{Message: "Unauthorized"})
+		Error(w, modules.ErrUnauthorized)
+		return
+	}
+
+	period, err := modules.ParseLeaderboardPeriod(r.URL.Query().Get("period"))
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	rank, err := modules.GetReputationLeaderboardRank(user, period)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, rank)
+}
+
+func VoteReview(w http.ResponseWriter, r *http.Request) {
+	voteReview(w, r, Authorize)
+}
+
+// voteReview handles votes of accounts on any platform, authorize decides which accounts are accepted
+func voteReview(w http.ResponseWriter, r *http.Request, authorize func(r *http.Request) (*schemas.URUser, error)) {
+	user, err := authorize(r)
+	if err != nil {
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
 	reviewIDStr := chi.URLParam(r, "reviewid")
 	reviewID64, err := strconv.ParseInt(reviewIDStr, 10, 32)
 	if err != nil || reviewID64 == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		common.SendStructResponse(w, Response{Message: "Invalid review ID"})
+		Error(w, modules.ValidationError("invalid_review_id", "Invalid review ID"))
 		return
 	}
 
-	var body struct {
-		IsUpvote bool `json:"isUpvote"`
-	}
+	var body VoteRequest
 	json.NewDecoder(r.Body).Decode(&body)
 
 	err = modules.VoteReview(user, int32(reviewID64), body.IsUpvote)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		common.SendStructResponse(w, Response{Message: err.Error()})
+		Error(w, err)
 		return
 	}
 
@@ -684,25 +832,26 @@ func VoteReview(w http.ResponseWriter, r *http.Request) {
 }
 
 func DeleteReviewVote(w http.ResponseWriter, r *http.Request) {
-	user, err := Authorize(r)
+	deleteReviewVote(w, r, Authorize)
+}
+
+func deleteReviewVote(w http.ResponseWriter, r *http.Request, authorize func(r *http.Request) (*schemas.URUser, error)) {
+	user, err := authorize(r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		common.SendStructResponse(w, Response{Message: "Unauthorized"})
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
 	reviewIDStr := chi.URLParam(r, "reviewid")
 	reviewID64, err := strconv.ParseInt(reviewIDStr, 10, 32)
 	if err != nil || reviewID64 == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		common.SendStructResponse(w, Response{Message: "Invalid review ID"})
+		Error(w, modules.ValidationError("invalid_review_id", "Invalid review ID"))
 		return
 	}
 
 	err = modules.DeleteReviewVote(user, int32(reviewID64))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		common.SendStructResponse(w, Response{Message: err.Error()})
+		Error(w, err)
 		return
 	}
 
@@ -712,22 +861,19 @@ func DeleteReviewVote(w http.ResponseWriter, r *http.Request) {
 func GetReviewVotes(w http.ResponseWriter, r *http.Request) {
 	user, err := Authorize(r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		common.SendStructResponse(w, Response{Message: "Unauthorized"})
+		Error(w, modules.ErrUnauthorized)
 		return
 	}
 
 	userID, err := strconv.ParseInt(chi.URLParam(r, "discordid"), 10, 64)
 	if err != nil || userID == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		common.SendStructResponse(w, Response{Message: "Invalid user ID"})
+		Error(w, modules.ValidationError("invalid_user_id", "Invalid user ID"))
 		return
 	}
 
 	votes, err := modules.GetReviewVotesOnUser(user, userID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		common.SendStructResponse(w, Response{Message: err.Error()})
+		Error(w, err)
 		return
 	}
 
@@ -750,55 +896,66 @@ func GetUserRating(w http.ResponseWriter, r *http.Request) {
 
 	rating, err := modules.GetUserRating(discordID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		common.SendStructResponse(w, Response{Message: err.Error()})
+		Error(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(struct {
-		Rating int `json:"rating"`
-	}{Rating: rating})
+	json.NewEncoder(w).Encode(UserRatingResponse{Rating: rating})
 }
 
 func GetUserReputation(w http.ResponseWriter, r *http.Request) {
 	discordID := chi.URLParam(r, "discordid")
 	if discordID == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		common.SendStructResponse(w, Response{Message: "Invalid user ID"})
+		Error(w, modules.ValidationError("invalid_user_id", "Invalid user ID"))
 		return
 	}
 
 	reputation, err := modules.GetUserReputation(discordID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		common.SendStructResponse(w, Response{Message: err.Error()})
+		Error(w, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(reputation)
 }
 
-func GetUserInfoByID(w http.ResponseWriter, r *http.Request) {
+func GetUserReputationHistory(w http.ResponseWriter, r *http.Request) {
 	discordID := chi.URLParam(r, "discordid")
 	if discordID == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		Error(w, modules.ValidationError("invalid_user_id", "Invalid user ID"))
 		return
 	}
 
-	type UserInfo struct {
-		DiscordID    string              `json:"discordID"`
-		Username     string              `json:"username"`
-		ProfilePhoto string              `json:"profilePhoto"`
-		Badges       []schemas.UserBadge `json:"badges"`
-		Type         int32               `json:"type"`
-		OptedOut     bool                `json:"optedOut"`
-		Reputation   int                 `json:"reputation"`
+	days := common.GetIntQueryOrDefault(r, "days", 30)
+	if days <= 0 || days > 365 {
+		Error(w, modules.ValidationError("invalid_days", "Days must be between 1 and 365"))
+		return
+	}
+
+	history, err := modules.GetReputationHistory(discordID, days)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, history)
+}
+
+func GetUserInfoByID(w http.ResponseWriter, r *http.Request) {
+	discordID := chi.URLParam(r, "discordid")
+	if discordID == "" {
+		Error(w, modules.ErrInvalidRequest)
+		return
 	}
 
 	user, err := modules.GetDBUserViaDiscordID(discordID)
 	if err == nil && user != nil {
 		badges := modules.GetBadgesOfUser(user.DiscordID)
-		response := UserInfo{
+		connections, err := modules.GetPublicConnections(user.ID)
+		if err != nil {
+			connections = []modules.PublicConnection{}
+		}
+		response := PublicUserInfo{
 			DiscordID:    user.DiscordID,
 			Username:     user.Username,
 			ProfilePhoto: user.AvatarURL,
@@ -806,6 +963,7 @@ func GetUserInfoByID(w http.ResponseWriter, r *http.Request) {
 			Type:         user.Type,
 			OptedOut:     user.OptedOut,
 			Reputation:   user.Reputation,
+			Connections:  connections,
 		}
 		if slices.Contains(common.OptedOut, discordID) {
 			response.OptedOut = true
@@ -816,13 +974,13 @@ func GetUserInfoByID(w http.ResponseWriter, r *http.Request) {
 
 	userID, err := strconv.ParseInt(discordID, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		Error(w, modules.ErrInvalidRequest)
 		return
 	}
 
 	discordUser, err := discord_utils.ArikawaState.User(discord.UserID(userID))
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		Error(w, modules.NotFoundError("user_not_found", "User not found"))
 		return
 	}
 
@@ -831,7 +989,7 @@ func GetUserInfoByID(w http.ResponseWriter, r *http.Request) {
 		avatarURL = "https://cdn.discordapp.com/embed/avatars/0.png"
 	}
 
-	response := UserInfo{
+	response := PublicUserInfo{
 		DiscordID:    discordUser.ID.String(),
 		Username:     common.Ternary(discordUser.Discriminator == "0", discordUser.Username, discordUser.Username+"#"+discordUser.Discriminator),
 		ProfilePhoto: avatarURL,
@@ -839,6 +997,7 @@ func GetUserInfoByID(w http.ResponseWriter, r *http.Request) {
 		Type:         0,
 		OptedOut:     slices.Contains(common.OptedOut, discordID),
 		Reputation:   0,
+		Connections:  []modules.PublicConnection{},
 	}
 
 	json.NewEncoder(w).Encode(response)
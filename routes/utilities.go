@@ -1,7 +1,7 @@
 package routes
 
 import (
-	"errors"
+	"log"
 	"net/http"
 	"server-go/common"
 	"server-go/database/schemas"
@@ -15,29 +15,30 @@ func Authorize(r *http.Request) (*schemas.URUser, error) {
 	var token = r.Header.Get("Authorization")
 
 	if token == "" {
-		return nil, errors.New("Unauthorized")
+		return nil, modules.ErrUnauthorized
 	}
 	user, err := modules.GetDBUserViaToken(token)
 
-	if err != nil {
-		return nil, errors.New("Unauthorized")
+	// accounts of other platforms have their own routes
+	if err != nil || user.Platform != schemas.PlatformDiscord {
+		return nil, modules.ErrUnauthorized
 	}
 
 	return &user, nil
 }
 
 // maybe using a middleware would be better but it prevents me from adding metrics
-func AuthorizeTwitter(r *http.Request) (*schemas.TwitterUser, error) {
+func AuthorizeTwitter(r *http.Request) (*schemas.URUser, error) {
 
 	var token = r.Header.Get("Authorization")
 
 	if token == "" {
-		return nil, errors.New("Unauthorized")
+		return nil, modules.ErrUnauthorized
 	}
 
 	user, err := twitter_modules.GetDBUserViaToken(token)
 	if err != nil {
-		return nil, errors.New("Unauthorized")
+		return nil, modules.ErrUnauthorized
 	}
 
 	return user, nil
@@ -46,34 +47,54 @@ func AuthorizeTwitter(r *http.Request) (*schemas.TwitterUser, error) {
 func Notifications(w http.ResponseWriter, r *http.Request) {
 	user, err := Authorize(r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		Error(w, err)
 		return
 	}
 
 	if r.Method == "PATCH" {
 		notificationId, err := strconv.Atoi(r.URL.Query().Get("id"))
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
+			Error(w, modules.ValidationError("invalid_notification_id", "Invalid notification id"))
 			return
 		}
 
 		err = modules.ReadNotification(user, int32(notificationId))
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			Error(w, err)
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
+		common.SendStructResponse(w, Response{Success: true})
 		return
 	}
 }
 
+var errorStatuses = map[modules.ErrorKind]int{
+	modules.KindInternal:     http.StatusInternalServerError,
+	modules.KindValidation:   http.StatusBadRequest,
+	modules.KindUnauthorized: http.StatusUnauthorized,
+	modules.KindForbidden:    http.StatusForbidden,
+	modules.KindBanned:       http.StatusForbidden,
+	modules.KindNotFound:     http.StatusNotFound,
+	modules.KindConflict:     http.StatusConflict,
+	modules.KindRateLimited:  http.StatusTooManyRequests,
+}
+
+// Error answers with the status code of the error's kind and its code in the error field of the response.
+// Unexpected errors are logged and answered with a generic message instead of leaking their details.
 func Error(w http.ResponseWriter, err error) {
-	w.WriteHeader(http.StatusInternalServerError)
+	typed := modules.AsError(err)
+	if typed == nil {
+		log.Println(err)
+		sendError(w, http.StatusInternalServerError, "internal_error", common.ERROR)
+		return
+	}
+
+	sendError(w, errorStatuses[typed.Kind], typed.Code, typed.Message)
+}
 
-	response := Response{}
-	response.Success = false
-	response.Message = err.Error()
-	common.SendStructResponse(w, response)
-	return
+func sendError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	common.SendStructResponse(w, Response{Success: false, Message: message, Error: code})
 }
@@ -0,0 +1,546 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"server-go/common"
+	"server-go/database/schemas"
+	"server-go/modules"
+	discord_utils "server-go/modules/discord"
+	"server-go/modules/filtering"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/exp/slices"
+)
+
+// v2 only authorizes with the Authorization header, decodes bodies strictly and uses camelCase json everywhere.
+// The /api/reviewdb routes stay as they are for older clients.
+
+type BadgeV2 struct {
+	Name        string `json:"name"`
+	Icon        string `json:"icon"`
+	RedirectURL string `json:"redirectUrl"`
+	Type        int32  `json:"type"`
+	Description string `json:"description"`
+}
+
+type UserV2 struct {
+	DiscordID    string    `json:"discordId"`
+	Username     string    `json:"username"`
+	ProfilePhoto string    `json:"profilePhoto"`
+	Badges       []BadgeV2 `json:"badges"`
+	Reputation   int       `json:"reputation"`
+}
+
+type ProfileV2 struct {
+	UserV2
+	Type        int32                      `json:"type"`
+	OptedOut    bool                       `json:"optedOut"`
+	Connections []modules.PublicConnection `json:"connections"`
+}
+
+type MeV2 struct {
+	UserV2
+	ID           int32                 `json:"id"`
+	Type         int32                 `json:"type"`
+	ClientMods   []string              `json:"clientMods"`
+	WarningCount int32                 `json:"warningCount"`
+	Flags        int32                 `json:"flags"`
+	BlockedUsers []string              `json:"blockedUsers"`
+	LastReviewID int32                 `json:"lastReviewId"`
+	Notification *schemas.Notification `json:"notification"`
+	BanInfo      *BanInfoV2            `json:"banInfo"`
+}
+
+type BanInfoV2 struct {
+	ReviewID      int32     `json:"reviewId"`
+	ReviewContent string    `json:"reviewContent"`
+	EndDate       time.Time `json:"endDate"`
+}
+
+type ReviewV2 struct {
+	ID           int32      `json:"id"`
+	Sender       UserV2     `json:"sender"`
+	Comment      string     `json:"comment"`
+	Type         int32      `json:"type"`
+	Timestamp    int64      `json:"timestamp"`
+	Score        int        `json:"score"`
+	Edited       bool       `json:"edited"`
+	EditedAt     int64      `json:"editedAt,omitempty"`
+	Pinned       bool       `json:"pinned"`
+	Hidden       bool       `json:"hidden"`
+	HiddenReason string     `json:"hiddenReason,omitempty"`
+	Replies      []ReviewV2 `json:"replies"`
+}
+
+type ReviewPageV2 struct {
+	Reviews     []ReviewV2 `json:"reviews"`
+	ReviewCount int        `json:"reviewCount"`
+	HasNextPage bool       `json:"hasNextPage"`
+	OptedOut    bool       `json:"optedOut"`
+}
+
+type SettingsV2 struct {
+	OptedOut bool `json:"optedOut"`
+}
+
+type ReviewRequestV2 struct {
+	Comment   string `json:"comment"`
+	Type      int32  `json:"type"`
+	RepliesTo int32  `json:"repliesTo"`
+}
+
+func (req *ReviewRequestV2) Validate() error {
+	req.Comment = strings.TrimSpace(req.Comment)
+	if len(req.Comment) > 1000 {
+		return modules.ValidationError("comment_too_long", "Comment Too Long")
+	} else if len(req.Comment) == 0 {
+		return modules.ValidationError("empty_comment", "Write Something Guh")
+	} else if req.Type < 0 || req.RepliesTo < 0 {
+		return modules.ErrInvalidRequest
+	}
+	return nil
+}
+
+type SettingsRequestV2 struct {
+	OptedOut *bool `json:"optedOut"`
+}
+
+func (req *SettingsRequestV2) Validate() error {
+	if req.OptedOut == nil {
+		return modules.ValidationError("missing_field", "optedOut is required")
+	}
+	return nil
+}
+
+type SearchRequestV2 struct {
+	Query string `json:"query"`
+}
+
+func (req *SearchRequestV2) Validate() error {
+	if strings.TrimSpace(req.Query) == "" {
+		return modules.ValidationError("missing_field", "query is required")
+	}
+	return nil
+}
+
+func v2Routes(r chi.Router) {
+	r.Get("/badges", GetBadgesV2)
+	r.Get("/events", StreamEvents)
+	r.With(ValidateIDs("discordid")).Get("/users/{discordid}", GetProfileV2)
+	r.With(ValidateIDs("discordid")).Get("/users/{discordid}/reviews", GetReviewsV2)
+
+	r.Group(func(r chi.Router) {
+		r.Use(RequireUser)
+
+		r.Get("/me", GetMeV2)
+		r.Get("/me/settings", GetSettingsV2)
+		r.With(ValidateBody[SettingsRequestV2]).Patch("/me/settings", PatchSettingsV2)
+		r.Get("/me/blocks", GetBlocksV2)
+		r.With(ValidateIDs("discordid")).Put("/me/blocks/{discordid}", BlockUserV2)
+		r.With(ValidateIDs("discordid")).Delete("/me/blocks/{discordid}", UnblockUserV2)
+		r.With(ValidateIDs("notificationid")).Delete("/me/notifications/{notificationid}", ReadNotificationV2)
+		r.Get("/me/webhooks", GetWebhooks)
+		r.With(ValidateBody[CreateWebhookRequest]).Post("/me/webhooks", CreateWebhook)
+		r.With(ValidateIDs("webhookid")).Delete("/me/webhooks/{webhookid}", DeleteWebhook)
+		r.With(ValidateIDs("webhookid")).Post("/me/webhooks/{webhookid}/ping", PingWebhook)
+		r.With(ValidateIDs("webhookid")).Get("/me/webhooks/{webhookid}/deliveries", GetWebhookDeliveries)
+
+		r.With(ValidateIDs("discordid"), ValidateBody[ReviewRequestV2]).Put("/users/{discordid}/reviews", PutReviewV2)
+		r.With(ValidateBody[SearchRequestV2]).Post("/reviews/search", SearchReviewsV2)
+
+		r.Route("/reviews/{reviewid}", func(rv chi.Router) {
+			rv.Use(ReviewMiddleware)
+			rv.Delete("/", DeleteReviewV2)
+			rv.Post("/reports", ReportReviewV2)
+			rv.With(ValidateBody[VoteRequest]).Put("/vote", VoteReviewV2)
+			rv.Delete("/vote", DeleteReviewVoteV2)
+			rv.Put("/pin", PinReview)
+			rv.Delete("/pin", UnpinReview)
+			rv.With(ValidateBody[HideReviewRequest]).Put("/hide", HideReviewV2)
+			rv.Delete("/hide", UnhideReview)
+		})
+	})
+}
+
+func toBadgesV2(badges []schemas.UserBadge) []BadgeV2 {
+	res := make([]BadgeV2, len(badges))
+	for i, badge := range badges {
+		res[i] = BadgeV2{
+			Name:        badge.Name,
+			Icon:        badge.Icon,
+			RedirectURL: badge.RedirectURL,
+			Type:        badge.Type,
+			Description: badge.Description,
+		}
+	}
+	return res
+}
+
+func toUserV2(user *schemas.URUser) UserV2 {
+	return UserV2{
+		DiscordID:    user.DiscordID,
+		Username:     user.Username,
+		ProfilePhoto: user.AvatarURL,
+		Badges:       toBadgesV2(modules.GetBadgesOfUser(user.DiscordID)),
+		Reputation:   user.Reputation,
+	}
+}
+
+func toReviewV2(review schemas.UserReview) ReviewV2 {
+	res := ReviewV2{
+		ID: review.ID,
+		Sender: UserV2{
+			DiscordID:    review.Sender.DiscordID,
+			Username:     review.Sender.Username,
+			ProfilePhoto: review.Sender.ProfilePhoto,
+			Badges:       toBadgesV2(review.Sender.Badges),
+		},
+		Comment:      review.Comment,
+		Type:         review.Type,
+		Timestamp:    review.Timestamp,
+		Score:        review.Score,
+		Edited:       review.Edited,
+		EditedAt:     review.EditedAt,
+		Pinned:       review.Pinned,
+		Hidden:       review.Hidden,
+		HiddenReason: review.HiddenReason,
+		Replies:      toReviewsV2(review.Replies),
+	}
+	if review.Reputation != nil {
+		res.Sender.Reputation = *review.Reputation
+	}
+	return res
+}
+
+func toReviewsV2(reviews []schemas.UserReview) []ReviewV2 {
+	res := make([]ReviewV2, len(reviews))
+	for i, review := range reviews {
+		res[i] = toReviewV2(review)
+	}
+	return res
+}
+
+// reviewIDParam reads the {reviewid} param, ReviewMiddleware already validated it
+func reviewIDParam(r *http.Request) int32 {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "reviewid"), 10, 32)
+	return int32(id)
+}
+
+func GetBadgesV2(w http.ResponseWriter, r *http.Request) {
+	badges, err := modules.GetBadgesMap()
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	res := make(map[string][]BadgeV2, len(badges))
+	for discordID, userBadges := range badges {
+		res[discordID] = toBadgesV2(userBadges)
+	}
+	common.SendStructResponse(w, res)
+}
+
+func GetProfileV2(w http.ResponseWriter, r *http.Request) {
+	discordID := chi.URLParam(r, "discordid")
+
+	user, err := modules.GetDBUserViaDiscordID(discordID)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+	if user == nil {
+		// discord users without an account can still be reviewed
+		userID, _ := strconv.ParseUint(discordID, 10, 64)
+		discordUser, err := discord_utils.ArikawaState.User(discord.UserID(userID))
+		if err != nil {
+			Error(w, modules.NotFoundError("user_not_found", "User not found"))
+			return
+		}
+
+		common.SendStructResponse(w, ProfileV2{
+			UserV2: UserV2{
+				DiscordID:    discordUser.ID.String(),
+				Username:     common.Ternary(discordUser.Discriminator == "0", discordUser.Username, discordUser.Username+"#"+discordUser.Discriminator),
+				ProfilePhoto: common.Ternary(discordUser.AvatarURL() == "", "https://cdn.discordapp.com/embed/avatars/0.png", discordUser.AvatarURL()),
+				Badges:       []BadgeV2{},
+			},
+			OptedOut:    slices.Contains(common.OptedOut, discordID),
+			Connections: []modules.PublicConnection{},
+		})
+		return
+	}
+
+	connections, err := modules.GetPublicConnections(user.ID)
+	if err != nil {
+		connections = []modules.PublicConnection{}
+	}
+
+	common.SendStructResponse(w, ProfileV2{
+		UserV2:      toUserV2(user),
+		Type:        user.Type,
+		OptedOut:    user.OptedOut || slices.Contains(common.OptedOut, discordID),
+		Connections: connections,
+	})
+}
+
+func GetReviewsV2(w http.ResponseWriter, r *http.Request) {
+	discordID := chi.URLParam(r, "discordid")
+	offset := common.GetIntQueryOrDefault(r, "offset", 0)
+	limit := common.GetIntQueryOrDefault(r, "limit", 50)
+	if offset < 0 || limit <= 0 || limit > 50 {
+		Error(w, modules.ValidationError("invalid_limit_or_offset", "Invalid limit or offset"))
+		return
+	}
+
+	requester, _ := Authorize(r)
+	res := ReviewPageV2{
+		Reviews:  []ReviewV2{},
+		OptedOut: slices.Contains(common.OptedOut, discordID),
+	}
+
+	// reviews of opted out users are only visible to admins
+	if res.OptedOut && (requester == nil || !requester.IsAdmin()) {
+		common.SendStructResponse(w, res)
+		return
+	}
+
+	userID, _ := strconv.ParseInt(discordID, 10, 64)
+	reviews, count, err := modules.GetReviewsWithOptions(requester, userID, offset, modules.GetReviewsOptions{
+		IncludeReviewsById: r.URL.Query().Get("alwaysIncludeReviewsBy"),
+		Limit:              limit,
+	})
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	res.Reviews = toReviewsV2(reviews)
+	res.ReviewCount = count
+	res.HasNextPage = offset+limit < count
+	common.SendStructResponse(w, res)
+}
+
+func PutReviewV2(w http.ResponseWriter, r *http.Request) {
+	reviewer := UserFrom(r)
+	body := BodyFrom[ReviewRequestV2](r)
+	discordID := chi.URLParam(r, "discordid")
+
+	if slices.Contains(common.OptedOut, discordID) {
+		Error(w, modules.ForbiddenError("profile_opted_out", "This user opted out"))
+		return
+	}
+
+	profileID, _ := strconv.ParseInt(discordID, 10, 64)
+	review := schemas.UserReview{
+		ProfileID:    profileID,
+		ReviewerID:   reviewer.ID,
+		Comment:      body.Comment,
+		Type:         body.Type,
+		RepliesTo:    body.RepliesTo,
+		TimestampStr: time.Now(),
+		Platform:     schemas.PlatformDiscord,
+	}
+
+	for _, filterFunction := range filtering.ReviewDB {
+		if err := filterFunction(reviewer, &review); err != nil {
+			Error(w, err)
+			return
+		}
+	}
+
+	res, err := modules.AddReview(reviewer, &review)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, AddReviewResponse{
+		Response: Response{Success: true, Message: res},
+		Updated:  res == common.UPDATED,
+	})
+}
+
+func DeleteReviewV2(w http.ResponseWriter, r *http.Request) {
+	if err := modules.DeleteReview(reviewIDParam(r), r.Header.Get("Authorization")); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Successfully Deleted Review"})
+}
+
+func ReportReviewV2(w http.ResponseWriter, r *http.Request) {
+	err := modules.ReportReview(modules.UR_RequestData{
+		Token:    r.Header.Get("Authorization"),
+		ReviewID: reviewIDParam(r),
+	})
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Successfully Reported Review"})
+}
+
+func VoteReviewV2(w http.ResponseWriter, r *http.Request) {
+	if err := modules.VoteReview(UserFrom(r), reviewIDParam(r), BodyFrom[VoteRequest](r).IsUpvote); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Vote recorded"})
+}
+
+func DeleteReviewVoteV2(w http.ResponseWriter, r *http.Request) {
+	if err := modules.DeleteReviewVote(UserFrom(r), reviewIDParam(r)); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Vote removed"})
+}
+
+func HideReviewV2(w http.ResponseWriter, r *http.Request) {
+	reason := BodyFrom[HideReviewRequest](r).Reason
+
+	handleReviewModeration(w, r, func(user *schemas.URUser, reviewID int32) error {
+		return modules.HideReview(user, reviewID, reason)
+	}, "Hid review")
+}
+
+func SearchReviewsV2(w http.ResponseWriter, r *http.Request) {
+	reviews, err := modules.SearchReviews(BodyFrom[SearchRequestV2](r).Query, r.Header.Get("Authorization"))
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, toReviewsV2(reviews))
+}
+
+func GetMeV2(w http.ResponseWriter, r *http.Request) {
+	user := UserFrom(r)
+
+	res := MeV2{
+		UserV2:       toUserV2(user),
+		ID:           user.ID,
+		Type:         user.Type,
+		ClientMods:   user.ClientMods,
+		WarningCount: user.WarningCount,
+		Flags:        user.Flags,
+		BlockedUsers: user.BlockedUsers,
+		LastReviewID: modules.GetLastReviewID(user.DiscordID),
+		Notification: user.Notification,
+	}
+	if user.BanInfo != nil {
+		res.BanInfo = &BanInfoV2{
+			ReviewID:      user.BanInfo.ReviewID,
+			ReviewContent: user.BanInfo.ReviewContent,
+			EndDate:       user.BanInfo.BanEndDate,
+		}
+	}
+	if res.BlockedUsers == nil {
+		res.BlockedUsers = []string{}
+	}
+
+	common.SendStructResponse(w, res)
+}
+
+func GetSettingsV2(w http.ResponseWriter, r *http.Request) {
+	settings, err := modules.GetSettings(UserFrom(r).DiscordID)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, SettingsV2{OptedOut: settings.Opt})
+}
+
+func PatchSettingsV2(w http.ResponseWriter, r *http.Request) {
+	err := modules.SetSettings(modules.Settings{
+		DiscordID: UserFrom(r).DiscordID,
+		Opt:       *BodyFrom[SettingsRequestV2](r).OptedOut,
+	})
+	if err != nil {
+		Error(w, err)
+		return
+	}
+	refreshOptedOut()
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Updated settings"})
+}
+
+func GetBlocksV2(w http.ResponseWriter, r *http.Request) {
+	limit := common.GetIntQueryOrDefault(r, "limit", 50)
+	offset := common.GetIntQueryOrDefault(r, "offset", 0)
+	if limit <= 0 || limit > 100 || offset < 0 {
+		Error(w, modules.ValidationError("invalid_limit_or_offset", "Invalid limit or offset"))
+		return
+	}
+
+	blocks, err := modules.GetBlockedUsers(UserFrom(r), offset, limit)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	res := make([]UserV2, len(blocks))
+	for i, block := range blocks {
+		res[i] = UserV2{
+			DiscordID:    block.DiscordID,
+			Username:     block.Username,
+			ProfilePhoto: block.AvatarURL,
+			Badges:       toBadgesV2(block.Badges),
+			Reputation:   block.Reputation,
+		}
+	}
+	common.SendStructResponse(w, res)
+}
+
+func BlockUserV2(w http.ResponseWriter, r *http.Request) {
+	if err := modules.BlockUser(UserFrom(r), chi.URLParam(r, "discordid")); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Blocked user"})
+}
+
+func UnblockUserV2(w http.ResponseWriter, r *http.Request) {
+	if err := modules.UnblockUser(UserFrom(r), chi.URLParam(r, "discordid")); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Unblocked user"})
+}
+
+func ReadNotificationV2(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := strconv.ParseInt(chi.URLParam(r, "notificationid"), 10, 32)
+	if err != nil {
+		Error(w, modules.ValidationError("invalid_notificationid", "Invalid notificationid"))
+		return
+	}
+
+	if err = modules.ReadNotification(UserFrom(r), int32(notificationID)); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Marked notification as read"})
+}
+
+// refreshOptedOut reloads the opted out users after someone changed their settings
+func refreshOptedOut() {
+	optedOutUsers, err := modules.GetOptedOutUsers()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	common.OptedOut = optedOutUsers
+}
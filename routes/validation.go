@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"server-go/database/schemas"
+	"server-go/modules"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxBodySize is the largest request body DecodeJSON accepts
+const maxBodySize = 64 << 10
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	bodyContextKey
+)
+
+// Validator is implemented by request bodies that check their own fields after being decoded
+type Validator interface {
+	Validate() error
+}
+
+// DecodeJSON strictly decodes the request body, unknown fields, trailing data and bodies over maxBodySize are rejected.
+// Bodies implementing Validator are validated too.
+func DecodeJSON[T any](w http.ResponseWriter, r *http.Request) (body T, err error) {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err = decoder.Decode(&body); err != nil {
+		return body, decodeError(err)
+	}
+
+	if _, err = decoder.Token(); !errors.Is(err, io.EOF) {
+		return body, modules.ValidationError("invalid_json", "Request body must contain a single JSON value")
+	}
+
+	if validator, ok := any(&body).(Validator); ok {
+		return body, validator.Validate()
+	}
+	return body, nil
+}
+
+func decodeError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	var typeError *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return modules.ValidationError("empty_body", "Request body is empty")
+	case errors.As(err, &maxBytesError):
+		return modules.ValidationError("body_too_large", fmt.Sprintf("Request body must be smaller than %d bytes", maxBytesError.Limit))
+	case errors.As(err, &typeError):
+		return modules.ValidationError("invalid_field", fmt.Sprintf("%s must be of type %s", typeError.Field, typeError.Type))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return modules.ValidationError("unknown_field", "Unknown field "+strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return modules.ValidationError("invalid_json", "Invalid JSON")
+}
+
+// ValidateBody decodes the body with DecodeJSON before the handler runs, the handler reads it with BodyFrom
+func ValidateBody[T any](handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := DecodeJSON[T](w, r)
+		if err != nil {
+			Error(w, err)
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), bodyContextKey, body)))
+	})
+}
+
+// BodyFrom returns the body ValidateBody decoded
+func BodyFrom[T any](r *http.Request) T {
+	body, _ := r.Context().Value(bodyContextKey).(T)
+	return body
+}
+
+// ValidateIDs rejects requests where one of the given URL params is not a positive integer
+func ValidateIDs(params ...string) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, param := range params {
+				id, err := strconv.ParseUint(chi.URLParam(r, param), 10, 64)
+				if err != nil || id == 0 {
+					Error(w, modules.ValidationError("invalid_"+param, "Invalid "+param))
+					return
+				}
+			}
+
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUser authorizes the request with the Authorization header, the handler reads the user with UserFrom
+func RequireUser(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := Authorize(r)
+		if err != nil {
+			Error(w, err)
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	})
+}
+
+// UserFrom returns the user RequireUser authorized, nil on routes without it
+func UserFrom(r *http.Request) *schemas.URUser {
+	user, _ := r.Context().Value(userContextKey).(*schemas.URUser)
+	return user
+}
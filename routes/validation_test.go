@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server-go/modules"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{name: "valid", body: `{"comment": " nice ", "repliesTo": 1}`},
+		{name: "empty body", body: ``, code: "empty_body"},
+		{name: "unknown field", body: `{"comment": "nice", "token": "abc"}`, code: "unknown_field"},
+		{name: "wrong type", body: `{"comment": 1}`, code: "invalid_field"},
+		{name: "trailing data", body: `{"comment": "nice"} {}`, code: "invalid_json"},
+		{name: "malformed", body: `{"comment": `, code: "invalid_json"},
+		{name: "too large", body: `{"comment": "` + strings.Repeat("a", maxBodySize) + `"}`, code: "body_too_large"},
+		{name: "fails validation", body: `{"comment": "   "}`, code: "empty_comment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+			body, err := DecodeJSON[ReviewRequestV2](httptest.NewRecorder(), req)
+
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if body.Comment != "nice" || body.RepliesTo != 1 {
+					t.Fatalf("decoded %+v", body)
+				}
+				return
+			}
+
+			typed := modules.AsError(err)
+			if typed == nil || typed.Kind != modules.KindValidation || typed.Code != tt.code {
+				t.Fatalf("err = %v, want validation error %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestRequireUserRejectsMissingHeader(t *testing.T) {
+	called := false
+	handler := RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/me", nil))
+
+	if called {
+		t.Fatal("handler was called without an Authorization header")
+	}
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestValidateIDs(t *testing.T) {
+	router := chi.NewRouter()
+	router.Route("/api/v2", v2Routes)
+
+	for path, want := range map[string]int{
+		"/api/v2/users/abc/reviews": http.StatusBadRequest,
+		"/api/v2/users/0":           http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
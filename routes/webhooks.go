@@ -0,0 +1,92 @@
+package routes
+
+import (
+	"net/http"
+	"server-go/common"
+	"server-go/database/schemas"
+	"server-go/modules"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+func (req *CreateWebhookRequest) Validate() error {
+	if req.URL == "" {
+		return modules.ValidationError("missing_field", "url is required")
+	}
+	return nil
+}
+
+// CreatedWebhookResponse is the only response that contains the secret deliveries are signed with
+type CreatedWebhookResponse struct {
+	schemas.UserWebhook
+	Secret string `json:"secret"`
+}
+
+// webhookIDParam reads the {webhookid} param, ValidateIDs already validated it
+func webhookIDParam(r *http.Request) int32 {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "webhookid"), 10, 32)
+	return int32(id)
+}
+
+func GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := modules.GetWebhooks(UserFrom(r))
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, webhooks)
+}
+
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	body := BodyFrom[CreateWebhookRequest](r)
+
+	webhook, err := modules.CreateWebhook(UserFrom(r), body.URL, body.Events)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, CreatedWebhookResponse{UserWebhook: webhook, Secret: webhook.Secret})
+}
+
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := modules.DeleteWebhook(UserFrom(r), webhookIDParam(r)); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Deleted webhook"})
+}
+
+func PingWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := modules.PingWebhook(UserFrom(r), webhookIDParam(r)); err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, Response{Success: true, Message: "Queued ping"})
+}
+
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := common.GetIntQueryOrDefault(r, "limit", 50)
+	offset := common.GetIntQueryOrDefault(r, "offset", 0)
+	if limit <= 0 || limit > 100 || offset < 0 {
+		Error(w, modules.ValidationError("invalid_limit_or_offset", "Invalid limit or offset"))
+		return
+	}
+
+	deliveries, err := modules.GetWebhookDeliveries(UserFrom(r), webhookIDParam(r), offset, limit)
+	if err != nil {
+		Error(w, err)
+		return
+	}
+
+	common.SendStructResponse(w, deliveries)
+}
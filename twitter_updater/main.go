@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"server-go/database"
+	modules_twitter "server-go/modules/twitter"
+)
+
+const (
+	batchSize = 500
+	// twitter rate limits per app, so only a few users are synced at once
+	concurrency = 8
+)
+
+func main() {
+	database.InitDB()
+
+	result, err := modules_twitter.SyncAllUsers(modules_twitter.NewAPIClient(), batchSize, concurrency)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Updated %d twitter users, refreshed %d tokens, %d revoked access, %d failed\n", result.Updated, result.Refreshed, result.Revoked, result.Failed)
+}